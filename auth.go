@@ -0,0 +1,82 @@
+package stampede
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Authenticator authenticates requests to the admin endpoints of the package, like
+// Purger, by returning an error for requests that are not allowed, see RequireAuth.
+type Authenticator func(r *http.Request) error
+
+var (
+	// ErrUnauthenticated is returned by authenticators for requests without credentials.
+	ErrUnauthenticated = errors.New("stampede: unauthenticated")
+
+	// ErrForbidden is returned by authenticators for requests with wrong credentials.
+	ErrForbidden = errors.New("stampede: forbidden")
+)
+
+// RequireAuth is a middleware that only passes requests accepted by any of auths, and
+// responds with 401 Unauthorized to all others. Mount admin endpoints like Purger behind
+// it to expose them on shared networks.
+func RequireAuth(auths ...Authenticator) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, auth := range auths {
+				if auth(r) == nil {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		})
+	}
+}
+
+// BearerToken accepts requests with an "Authorization: Bearer <token>" header carrying
+// one of tokens.
+func BearerToken(tokens ...string) Authenticator {
+	return func(r *http.Request) error {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || got == "" {
+			return ErrUnauthenticated
+		}
+		for _, token := range tokens {
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1 {
+				return nil
+			}
+		}
+		return ErrForbidden
+	}
+}
+
+// ClientCert accepts requests over mTLS with a verified client certificate, whose
+// common name or one of whose DNS names is one of names. Without names, any verified
+// client certificate is accepted. The server must request and verify client
+// certificates, e.g. with tls.RequireAndVerifyClientCert.
+func ClientCert(names ...string) Authenticator {
+	return func(r *http.Request) error {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			return ErrUnauthenticated
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		cert := r.TLS.VerifiedChains[0][0]
+		for _, name := range names {
+			if cert.Subject.CommonName == name {
+				return nil
+			}
+			for _, dns := range cert.DNSNames {
+				if dns == name {
+					return nil
+				}
+			}
+		}
+		return ErrForbidden
+	}
+}
@@ -0,0 +1,42 @@
+package stampede_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireAuth(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	h := stampede.RequireAuth(stampede.BearerToken("secret"), stampede.ClientCert("admin"))(ok)
+
+	serve := func(r *http.Request) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	r := httptest.NewRequest("PURGE", "/purge", nil)
+	assert.Equal(t, http.StatusUnauthorized, serve(r))
+
+	r.Header.Set("Authorization", "Bearer wrong")
+	assert.Equal(t, http.StatusUnauthorized, serve(r))
+
+	r.Header.Set("Authorization", "Bearer secret")
+	assert.Equal(t, http.StatusOK, serve(r))
+
+	cert := func(cn string) *tls.ConnectionState {
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}}}
+	}
+	r = httptest.NewRequest("PURGE", "/purge", nil)
+	r.TLS = cert("admin")
+	assert.Equal(t, http.StatusOK, serve(r))
+	r.TLS = cert("intruder")
+	assert.Equal(t, http.StatusUnauthorized, serve(r))
+}
@@ -0,0 +1,115 @@
+package stampede
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned for keys that a loader didn't return a value for.
+var ErrNotFound = errors.New("stampede: not found")
+
+// BatchLoader loads the values of several keys in one origin call, e.g. one sql
+// `IN (...)` query. Keys missing from the returned map fail with ErrNotFound.
+type BatchLoader[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Batcher fetches the keys of a Cache that are due for refresh together: keys missing
+// or expiring within the same window are loaded with a single BatchLoader call.
+type Batcher[K comparable, V any] struct {
+	cache    *Cache[K, V]
+	load     BatchLoader[K, V]
+	window   time.Duration
+	maxBatch int
+
+	mu  sync.Mutex
+	cur *batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys    []K
+	timer   *time.Timer
+	started bool
+	done    chan struct{}
+	vals    map[K]V
+	err     error
+}
+
+// NewBatcher returns a Batcher loading the keys of cache with load. Keys are collected
+// for up to window, or until maxBatch keys are pending when maxBatch is positive.
+func NewBatcher[K comparable, V any](cache *Cache[K, V], load BatchLoader[K, V], window time.Duration, maxBatch int) *Batcher[K, V] {
+	return &Batcher[K, V]{
+		cache:    cache,
+		load:     load,
+		window:   window,
+		maxBatch: maxBatch,
+	}
+}
+
+// Get is like Cache.Get, fetching the key with the batch loader.
+func (b *Batcher[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return b.cache.GetContext(ctx, key, func(ctx context.Context) (V, error) {
+		return b.fetch(ctx, key)
+	})
+}
+
+// GetFresh is like Cache.GetFresh, fetching the key with the batch loader.
+func (b *Batcher[K, V]) GetFresh(ctx context.Context, key K) (V, error) {
+	return b.cache.GetFreshContext(ctx, key, func(ctx context.Context) (V, error) {
+		return b.fetch(ctx, key)
+	})
+}
+
+func (b *Batcher[K, V]) fetch(ctx context.Context, key K) (V, error) {
+	b.mu.Lock()
+	cur := b.cur
+	if cur == nil {
+		cur = &batch[K, V]{done: make(chan struct{})}
+		b.cur = cur
+		cur.timer = time.AfterFunc(b.window, func() { b.flush(cur) })
+	}
+	cur.keys = append(cur.keys, key)
+	full := b.maxBatch > 0 && len(cur.keys) >= b.maxBatch
+	if full {
+		b.cur = nil
+	}
+	b.mu.Unlock()
+
+	if full {
+		cur.timer.Stop()
+		go b.flush(cur)
+	}
+
+	var zero V
+	select {
+	case <-cur.done:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+
+	if cur.err != nil {
+		return zero, cur.err
+	}
+	v, ok := cur.vals[key]
+	if !ok {
+		return zero, ErrNotFound
+	}
+	return v, nil
+}
+
+func (b *Batcher[K, V]) flush(cur *batch[K, V]) {
+	b.mu.Lock()
+	if cur.started {
+		b.mu.Unlock()
+		return
+	}
+	cur.started = true
+	if b.cur == cur {
+		b.cur = nil
+	}
+	b.mu.Unlock()
+
+	// the batch serves several callers, so it is not bound to any of their contexts
+	cur.vals, cur.err = b.load(context.Background(), cur.keys)
+	close(cur.done)
+}
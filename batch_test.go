@@ -0,0 +1,91 @@
+package stampede_test
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatcher(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]int
+
+	cache := stampede.NewCacheKV[int, string](64, time.Minute, time.Minute)
+	b := stampede.NewBatcher(cache, func(ctx context.Context, keys []int) (map[int]string, error) {
+		mu.Lock()
+		batches = append(batches, keys)
+		mu.Unlock()
+
+		vals := map[int]string{}
+		for _, k := range keys {
+			if k != 0 {
+				vals[k] = strconv.Itoa(k)
+			}
+		}
+		return vals, nil
+	}, 50*time.Millisecond, 0)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := b.Get(ctx, i)
+			if i == 0 {
+				assert.Equal(t, stampede.ErrNotFound, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, strconv.Itoa(i), v)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, batches, 1)
+	sort.Ints(batches[0])
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, batches[0])
+
+	// cached keys don't hit the loader again
+	v, err := b.Get(ctx, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, "5", v)
+	assert.Len(t, batches, 1)
+}
+
+func TestBatcherMaxBatch(t *testing.T) {
+	var mu sync.Mutex
+	var sizes []int
+
+	cache := stampede.NewCacheKV[int, int](64, time.Minute, time.Minute)
+	b := stampede.NewBatcher(cache, func(ctx context.Context, keys []int) (map[int]int, error) {
+		mu.Lock()
+		sizes = append(sizes, len(keys))
+		mu.Unlock()
+
+		vals := map[int]int{}
+		for _, k := range keys {
+			vals[k] = k
+		}
+		return vals, nil
+	}, time.Hour, 4)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := b.Get(ctx, i)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, []int{4, 4}, sizes)
+}
@@ -0,0 +1,36 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+)
+
+func BenchmarkGetHit(b *testing.B) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[int, int](1024, time.Minute, time.Minute)
+	fetch := func() (int, error) { return 1, nil }
+	c.Get(ctx, 1, fetch)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(ctx, 1, fetch)
+	}
+}
+
+// BenchmarkGetMiss measures the allocations of fetching and admitting new keys while
+// evicting old ones.
+func BenchmarkGetMiss(b *testing.B) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[int, int](1024, time.Minute, time.Minute)
+	fetch := func() (int, error) { return 1, nil }
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(ctx, i, fetch)
+	}
+}
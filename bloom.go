@@ -0,0 +1,108 @@
+package stampede
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// bloomHashes is the number of hashes of a bloom filter, which gives about 1% false
+// positives with bloomBitsPerKey bits per key.
+const (
+	bloomHashes     = 7
+	bloomBitsPerKey = 10
+)
+
+// bloom is a bloom filter of key hashes.
+type bloom struct {
+	bits []uint64
+}
+
+func newBloom(capacity int) *bloom {
+	return &bloom{bits: make([]uint64, (capacity*bloomBitsPerKey+63)/64)}
+}
+
+func (b *bloom) add(h uint64) {
+	n := uint64(len(b.bits) * 64)
+	h1, h2 := h, h>>32|h<<32
+	for i := uint64(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) % n
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+func (b *bloom) has(h uint64) bool {
+	n := uint64(len(b.bits) * 64)
+	h1, h2 := h, h>>32|h<<32
+	for i := uint64(0); i < bloomHashes; i++ {
+		bit := (h1 + i*h2) % n
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// missingFilter remembers the keys recently confirmed missing at the origin, in two
+// bloom filters: keys are added to the current one, and looked up in both. The current
+// filter replaces the previous one once it is full or older than the rotation interval,
+// so keys are forgotten after one to two intervals. See WithMissingFilter.
+type missingFilter struct {
+	mu        sync.Mutex
+	cur, prev *bloom
+	n         int // keys in cur
+	capacity  int
+	every     time.Duration
+	rotated   time.Time
+}
+
+func newMissingFilter(capacity int, every time.Duration) *missingFilter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &missingFilter{cur: newBloom(capacity), prev: newBloom(capacity), capacity: capacity, every: every, rotated: time.Now()}
+}
+
+// rotate must be called with mu held.
+func (f *missingFilter) rotate() {
+	age := time.Since(f.rotated)
+	if f.n < f.capacity && (f.every <= 0 || age < f.every) {
+		return
+	}
+	f.prev, f.cur = f.cur, newBloom(f.capacity)
+	if f.every > 0 && age >= 2*f.every {
+		// nothing was looked up for a whole interval, cur is outdated too
+		f.prev = newBloom(f.capacity)
+	}
+	f.n, f.rotated = 0, time.Now()
+}
+
+func (f *missingFilter) add(h uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rotate()
+	f.cur.add(h)
+	f.n++
+}
+
+func (f *missingFilter) has(h uint64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rotate()
+	return f.cur.has(h) || f.prev.has(h)
+}
+
+func (f *missingFilter) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cur, f.prev, f.n, f.rotated = newBloom(f.capacity), newBloom(f.capacity), 0, time.Now()
+}
+
+// keyHash returns the hash of ck for the missing filter.
+func keyHash[K comparable](ck cacheKey[K]) uint64 {
+	if ck.digest != "" {
+		return xxhash.Sum64String(ck.digest)
+	}
+	return xxhash.Sum64String(typedKeyString(ck.key))
+}
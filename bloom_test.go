@@ -0,0 +1,57 @@
+package stampede_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissingFilter(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](16, time.Minute, time.Minute, stampede.WithMissingFilter(1000, time.Minute))
+	defer c.Close()
+
+	var calls int
+	missing := func() (int, error) {
+		calls++
+		return 0, fmt.Errorf("lookup: %w", stampede.ErrNotFound)
+	}
+	for i := 0; i < 3; i++ {
+		_, err := c.Get(ctx, "nope", missing)
+		assert.ErrorIs(t, err, stampede.ErrNotFound)
+	}
+	assert.Equal(t, 1, calls)
+
+	// other keys still reach the origin
+	v, err := c.Get(ctx, "yes", func() (int, error) { return 1, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	c.Purge()
+	_, err = c.Get(ctx, "nope", missing)
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+	assert.Equal(t, 2, calls)
+}
+
+func TestMissingFilterRotate(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[int, int](16, time.Minute, time.Minute, stampede.WithMissingFilter(100, 50*time.Millisecond))
+	defer c.Close()
+
+	var calls int
+	missing := func() (int, error) {
+		calls++
+		return 0, stampede.ErrNotFound
+	}
+	c.Get(ctx, 1, missing)
+	c.Get(ctx, 1, missing)
+	assert.Equal(t, 1, calls)
+
+	time.Sleep(120 * time.Millisecond)
+	c.Get(ctx, 1, missing)
+	assert.Equal(t, 2, calls)
+}
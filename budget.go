@@ -0,0 +1,44 @@
+package stampede
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// refreshBudget returns the time a background refresh may take, or 0 for no limit, see
+// WithRefreshBudget.
+func (c *Cache[K, V]) refreshBudget() time.Duration {
+	if c.budgetMultiple <= 0 {
+		return 0
+	}
+	budget := c.budgetFloor
+	if p95, ok := c.latency.quantile(0.95); ok {
+		if d := time.Duration(c.budgetMultiple * float64(p95)); d > budget {
+			budget = d
+		}
+	}
+	return budget
+}
+
+// refresh is do for background refreshes, bounded by the refresh budget.
+func (c *Cache[K, V]) refresh(ctx context.Context, key K, ck cacheKey[K], fn FetchFunc[V]) (V, bool, error) {
+	ctx = inBackground(ctx)
+	budget := c.refreshBudget()
+	if budget <= 0 {
+		return c.do(ctx, key, ck, fn)
+	}
+	ctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+	v, shared, err := c.do(ctx, key, ck, fn)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		atomic.AddInt64(&c.refreshTimeouts, 1)
+	}
+	return v, shared, err
+}
+
+// isContextError reports whether err is the error of a canceled or timed out context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
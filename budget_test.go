@@ -0,0 +1,61 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshBudget(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](16, 10*time.Millisecond, time.Minute, stampede.WithRefreshBudget(2, 50*time.Millisecond))
+	defer c.Close()
+
+	c.Get(ctx, "a", func() (int, error) { return 1, nil })
+	time.Sleep(20 * time.Millisecond)
+
+	// the stale value is served while the refresh runs out of its budget
+	start := time.Now()
+	v, err := c.GetContext(ctx, "a", func(ctx context.Context) (int, error) {
+		select {
+		case <-ctx.Done():
+			assert.WithinDuration(t, start.Add(50*time.Millisecond), time.Now(), 40*time.Millisecond)
+			return 0, ctx.Err()
+		case <-time.After(time.Second):
+			return 2, nil
+		}
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	assert.Eventually(t, func() bool {
+		return c.Stats().RefreshTimeouts == 1
+	}, time.Second, 5*time.Millisecond)
+	v, _ = c.Get(ctx, "a", func() (int, error) { return 3, nil })
+	assert.Equal(t, 1, v)
+}
+
+func TestRefreshBudgetWaiters(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](16, 10*time.Millisecond, time.Minute, stampede.WithRefreshBudget(2, 50*time.Millisecond))
+	defer c.Close()
+
+	c.Get(ctx, "a", func() (int, error) { return 1, nil })
+	time.Sleep(20 * time.Millisecond)
+
+	started := make(chan struct{})
+	c.GetContext(ctx, "a", func(ctx context.Context) (int, error) {
+		close(started)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	<-started
+
+	// waiters of the refresh out of its budget fetch again instead of failing
+	v, err := c.GetFresh(ctx, "a", func() (int, error) { return 2, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
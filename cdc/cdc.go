@@ -0,0 +1,117 @@
+// Package cdc invalidates stampede caches from the change events of a database, e.g.
+// Debezium events read from Kafka, so cached values don't outlive the rows they were
+// built from for the full ttl.
+//
+// The package doesn't depend on a Kafka or database client: changes are decoded from
+// the messages of the client of choice, e.g. with Debezium, and passed to an
+// Invalidator, which maps them to the cache keys to invalidate.
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/dadav/stampede"
+)
+
+// Op is the operation of a change.
+type Op string
+
+const (
+	OpCreate Op = "c"
+	OpUpdate Op = "u"
+	OpDelete Op = "d"
+	OpRead   Op = "r" // rows read by a snapshot
+)
+
+// Change is a change of a row. Before is nil for created rows, After for deleted ones.
+type Change struct {
+	Table  string
+	Op     Op
+	Before map[string]any
+	After  map[string]any
+}
+
+// Row returns the row after the change, or before it for deleted rows.
+func (c Change) Row() map[string]any {
+	if c.After != nil {
+		return c.After
+	}
+	return c.Before
+}
+
+// ErrTombstone is returned by Debezium for the tombstones following deletes.
+var ErrTombstone = errors.New("cdc: tombstone")
+
+// Debezium decodes the JSON value of a Debezium change event, with or without schema.
+func Debezium(value []byte) (Change, error) {
+	if len(value) == 0 {
+		return Change{}, ErrTombstone
+	}
+
+	type payload struct {
+		Op     Op             `json:"op"`
+		Before map[string]any `json:"before"`
+		After  map[string]any `json:"after"`
+		Source struct {
+			Table string `json:"table"`
+		} `json:"source"`
+	}
+	var event struct {
+		payload
+		Payload *payload `json:"payload"`
+	}
+	if err := json.Unmarshal(value, &event); err != nil {
+		return Change{}, err
+	}
+	p := event.payload
+	if event.Payload != nil {
+		p = *event.Payload
+	}
+	return Change{Table: p.Source.Table, Op: p.Op, Before: p.Before, After: p.After}, nil
+}
+
+// Invalidator invalidates the keys of a cache affected by changes.
+type Invalidator[K comparable, V any] struct {
+	cache   *stampede.Cache[K, V]
+	mapping func(Change) []K
+
+	// Delete removes the affected keys from the cache, instead of marking them stale
+	// so they are served while they are refreshed, see Cache.InvalidateMany.
+	Delete bool
+}
+
+// New returns an invalidator for the keys of c affected by changes, as returned by
+// mapping. A change may affect several keys, e.g. a row and the lists containing it,
+// or none.
+func New[K comparable, V any](c *stampede.Cache[K, V], mapping func(Change) []K) *Invalidator[K, V] {
+	return &Invalidator[K, V]{cache: c, mapping: mapping}
+}
+
+// Apply invalidates the keys affected by ch, and returns how many of them were cached.
+func (i *Invalidator[K, V]) Apply(ch Change) int {
+	keys := i.mapping(ch)
+	if len(keys) == 0 {
+		return 0
+	}
+	if i.Delete {
+		return i.cache.DeleteMany(keys)
+	}
+	return i.cache.InvalidateMany(keys)
+}
+
+// Run applies changes until the channel is closed or ctx is done.
+func (i *Invalidator[K, V]) Run(ctx context.Context, changes <-chan Change) error {
+	for {
+		select {
+		case ch, ok := <-changes:
+			if !ok {
+				return nil
+			}
+			i.Apply(ch)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
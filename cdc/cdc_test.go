@@ -0,0 +1,55 @@
+package cdc_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/dadav/stampede/cdc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebezium(t *testing.T) {
+	ch, err := cdc.Debezium([]byte(`{"schema":{},"payload":{"op":"u","before":{"id":1,"name":"a"},"after":{"id":1,"name":"b"},"source":{"table":"users"}}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "users", ch.Table)
+	assert.Equal(t, cdc.OpUpdate, ch.Op)
+	assert.Equal(t, "b", ch.Row()["name"])
+
+	ch, err = cdc.Debezium([]byte(`{"op":"d","before":{"id":2},"after":null,"source":{"table":"users"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, cdc.OpDelete, ch.Op)
+	assert.Equal(t, float64(2), ch.Row()["id"])
+
+	_, err = cdc.Debezium(nil)
+	assert.ErrorIs(t, err, cdc.ErrTombstone)
+}
+
+func TestInvalidator(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, string](8, time.Minute, time.Hour)
+	defer c.Close()
+	c.Get(ctx, "user:1", func() (string, error) { return "a", nil })
+	c.Get(ctx, "user:2", func() (string, error) { return "b", nil })
+
+	inv := cdc.New(c, func(ch cdc.Change) []string {
+		if ch.Table != "users" {
+			return nil
+		}
+		return []string{fmt.Sprintf("user:%v", ch.Row()["id"])}
+	})
+	inv.Delete = true
+
+	changes := make(chan cdc.Change, 2)
+	changes <- cdc.Change{Table: "users", Op: cdc.OpUpdate, After: map[string]any{"id": 1}}
+	changes <- cdc.Change{Table: "orders", Op: cdc.OpUpdate, After: map[string]any{"id": 2}}
+	close(changes)
+	assert.NoError(t, inv.Run(ctx, changes))
+
+	_, ok := c.Peek("user:1")
+	assert.False(t, ok)
+	_, ok = c.Peek("user:2")
+	assert.True(t, ok)
+}
@@ -0,0 +1,90 @@
+package stampede
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Level is a level of a StoreChain.
+type Level struct {
+	Store Store
+
+	// Timeout bounds every call to the store, 0 for none. A level timing out or failing
+	// on Get is skipped.
+	Timeout time.Duration
+
+	// PromoteTTL is the ttl of entries promoted into this level after a hit at a level
+	// below it. 0 disables promotion into this level.
+	PromoteTTL time.Duration
+}
+
+// StoreChain is a Store reading through an ordered chain of stores, e.g. a local store
+// in front of a shared one. It is used with WithStore, and the origin is fetched after
+// a miss at every level.
+type StoreChain struct {
+	levels []Level
+}
+
+var _ Store = (*StoreChain)(nil)
+
+// NewStoreChain returns a chain of levels, from the first tried to the last.
+func NewStoreChain(levels ...Level) *StoreChain {
+	return &StoreChain{levels: levels}
+}
+
+// Get returns the value of key from the first level holding it, and promotes it into
+// the levels above.
+func (s *StoreChain) Get(ctx context.Context, key string) ([]byte, error) {
+	for i, l := range s.levels {
+		var b []byte
+		err := l.call(ctx, func(ctx context.Context) (err error) {
+			b, err = l.Store.Get(ctx, key)
+			return err
+		})
+		if err != nil {
+			continue
+		}
+
+		for _, upper := range s.levels[:i] {
+			if upper.PromoteTTL > 0 {
+				upper.call(ctx, func(ctx context.Context) error {
+					return upper.Store.Set(ctx, key, b, upper.PromoteTTL)
+				})
+			}
+		}
+		return b, nil
+	}
+	return nil, ErrNotFound
+}
+
+// Set writes key to every level.
+func (s *StoreChain) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var errs []error
+	for _, l := range s.levels {
+		if err := l.call(ctx, func(ctx context.Context) error { return l.Store.Set(ctx, key, value, ttl) }); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Delete deletes key from every level.
+func (s *StoreChain) Delete(ctx context.Context, key string) error {
+	var errs []error
+	for _, l := range s.levels {
+		if err := l.call(ctx, func(ctx context.Context) error { return l.Store.Delete(ctx, key) }); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (l Level) call(ctx context.Context, fn func(ctx context.Context) error) error {
+	if l.Timeout <= 0 {
+		return fn(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, l.Timeout)
+	defer cancel()
+	return fn(ctx)
+}
@@ -0,0 +1,44 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+type slowStore struct {
+	stampede.Store
+}
+
+func (s slowStore) Get(ctx context.Context, key string) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestStoreChain(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := stampede.NewMemoryStore(), stampede.NewMemoryStore()
+	chain := stampede.NewStoreChain(
+		stampede.Level{Store: l1, PromoteTTL: time.Minute},
+		stampede.Level{Store: slowStore{stampede.NewMemoryStore()}, Timeout: time.Millisecond},
+		stampede.Level{Store: l2},
+	)
+
+	assert.NoError(t, l2.Set(ctx, "k", []byte(`"v"`), time.Minute))
+
+	// the hit in l2 skips the slow level and is promoted into l1
+	c := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithStore(chain, stampede.JSONCodec{}))
+	val, err := c.Get(ctx, "k", func() (string, error) { return "origin", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "v", val)
+
+	b, err := l1.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, `"v"`, string(b))
+
+	_, err = chain.Get(ctx, "missing")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+}
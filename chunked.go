@@ -0,0 +1,163 @@
+package stampede
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// ChunkedStore splits values larger than a chunk size into chunks written to a Store
+// under their own keys, with a manifest listing them under the key of the value, so
+// huge values fit stores limiting the size of values, like Redis. Chunks are read back
+// and reassembled transparently; values with missing or corrupt chunks are misses.
+//
+// Every write of a chunked value uses fresh chunk keys and writes the manifest last, so
+// readers never mix the chunks of concurrent writes. The chunks of the value replaced by
+// a write, or deleted, are deleted afterwards.
+type ChunkedStore struct {
+	store     Store
+	chunkSize int
+}
+
+var _ Store = (*ChunkedStore)(nil)
+
+// NewChunkedStore returns a ChunkedStore splitting values larger than chunkSize bytes
+// written to s.
+func NewChunkedStore(s Store, chunkSize int) *ChunkedStore {
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+	return &ChunkedStore{store: s, chunkSize: chunkSize}
+}
+
+var chunkMagic = [8]byte{0, 'S', 'T', 'C', 'H', 'U', 'N', 'K'}
+
+const manifestSize = 8 + 8 + 4 + 8 + 8
+
+// manifest lists the chunks of a value.
+type manifest struct {
+	gen   [8]byte // distinguishes the chunks of different writes
+	count uint32
+	size  uint64
+	sum   uint64 // xxhash of the value
+}
+
+func (m manifest) encode() []byte {
+	b := make([]byte, manifestSize)
+	copy(b[0:8], chunkMagic[:])
+	copy(b[8:16], m.gen[:])
+	binary.BigEndian.PutUint32(b[16:20], m.count)
+	binary.BigEndian.PutUint64(b[20:28], m.size)
+	binary.BigEndian.PutUint64(b[28:36], m.sum)
+	return b
+}
+
+func decodeManifest(b []byte) (manifest, bool) {
+	var m manifest
+	if len(b) != manifestSize || !bytes.Equal(b[0:8], chunkMagic[:]) {
+		return m, false
+	}
+	copy(m.gen[:], b[8:16])
+	m.count = binary.BigEndian.Uint32(b[16:20])
+	m.size = binary.BigEndian.Uint64(b[20:28])
+	m.sum = binary.BigEndian.Uint64(b[28:36])
+	return m, true
+}
+
+func chunkKey(key string, m manifest, i int) string {
+	return key + ":chunk:" + hex.EncodeToString(m.gen[:]) + ":" + strconv.Itoa(i)
+}
+
+func (s *ChunkedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := decodeManifest(b)
+	if !ok {
+		return b, nil
+	}
+
+	value := make([]byte, 0, m.size)
+	for i := 0; i < int(m.count); i++ {
+		chunk, err := s.store.Get(ctx, chunkKey(key, m, i))
+		if errors.Is(err, ErrNotFound) {
+			// evicted or replaced since the manifest was read
+			return nil, ErrNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		value = append(value, chunk...)
+	}
+	if uint64(len(value)) != m.size || xxhash.Sum64(value) != m.sum {
+		return nil, ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *ChunkedStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	old, hadOld := s.manifest(ctx, key)
+
+	// values looking like a manifest are chunked, so they aren't mistaken for one
+	if len(value) <= s.chunkSize && !bytes.HasPrefix(value, chunkMagic[:]) {
+		if err := s.store.Set(ctx, key, value, ttl); err != nil {
+			return err
+		}
+	} else {
+		m := manifest{size: uint64(len(value)), sum: xxhash.Sum64(value)}
+		rand.Read(m.gen[:])
+		for i := 0; len(value) > 0; i++ {
+			n := s.chunkSize
+			if n > len(value) {
+				n = len(value)
+			}
+			if err := s.store.Set(ctx, chunkKey(key, m, i), value[:n], ttl); err != nil {
+				return err
+			}
+			value = value[n:]
+			m.count++
+		}
+		if err := s.store.Set(ctx, key, m.encode(), ttl); err != nil {
+			return err
+		}
+	}
+
+	if hadOld {
+		s.deleteChunks(ctx, key, old)
+	}
+	return nil
+}
+
+func (s *ChunkedStore) Delete(ctx context.Context, key string) error {
+	m, ok := s.manifest(ctx, key)
+	if err := s.store.Delete(ctx, key); err != nil {
+		return err
+	}
+	if ok {
+		s.deleteChunks(ctx, key, m)
+	}
+	return nil
+}
+
+// manifest returns the manifest of key, if its value is chunked.
+func (s *ChunkedStore) manifest(ctx context.Context, key string) (manifest, bool) {
+	b, err := s.store.Get(ctx, key)
+	if err != nil {
+		return manifest{}, false
+	}
+	return decodeManifest(b)
+}
+
+func (s *ChunkedStore) deleteChunks(ctx context.Context, key string, m manifest) {
+	for i := 0; i < int(m.count); i++ {
+		s.store.Delete(ctx, chunkKey(key, m, i))
+	}
+}
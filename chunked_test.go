@@ -0,0 +1,78 @@
+package stampede_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+// limitedStore rejects values larger than limit, like Redis with a proxy enforcing a
+// value size limit.
+type limitedStore struct {
+	*stampede.MemoryStore
+	limit int
+	keys  map[string]bool
+}
+
+func (s *limitedStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if len(value) > s.limit {
+		return assert.AnError
+	}
+	s.keys[key] = true
+	return s.MemoryStore.Set(ctx, key, value, ttl)
+}
+
+func (s *limitedStore) Delete(ctx context.Context, key string) error {
+	delete(s.keys, key)
+	return s.MemoryStore.Delete(ctx, key)
+}
+
+func TestChunkedStore(t *testing.T) {
+	ctx := context.Background()
+	backend := &limitedStore{MemoryStore: stampede.NewMemoryStore(), limit: 64, keys: map[string]bool{}}
+	s := stampede.NewChunkedStore(backend, 64)
+
+	assert.NoError(t, s.Set(ctx, "small", []byte("1"), 0))
+	v, err := s.Get(ctx, "small")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), v)
+
+	huge := bytes.Repeat([]byte("0123456789"), 100)
+	assert.NoError(t, s.Set(ctx, "huge", huge, time.Minute))
+	v, err = s.Get(ctx, "huge")
+	assert.NoError(t, err)
+	assert.Equal(t, huge, v)
+	assert.Len(t, backend.keys, 2+16)
+
+	// replacing a chunked value deletes its chunks
+	assert.NoError(t, s.Set(ctx, "huge", huge[:100], time.Minute))
+	v, err = s.Get(ctx, "huge")
+	assert.NoError(t, err)
+	assert.Equal(t, huge[:100], v)
+	assert.Len(t, backend.keys, 2+2)
+
+	assert.NoError(t, s.Delete(ctx, "huge"))
+	_, err = s.Get(ctx, "huge")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+	assert.Len(t, backend.keys, 1)
+}
+
+func TestChunkedStoreMissingChunk(t *testing.T) {
+	ctx := context.Background()
+	backend := &limitedStore{MemoryStore: stampede.NewMemoryStore(), limit: 64, keys: map[string]bool{}}
+	s := stampede.NewChunkedStore(backend, 8)
+	assert.NoError(t, s.Set(ctx, "k", []byte("a value of some length"), 0))
+
+	for key := range backend.keys {
+		if key != "k" {
+			backend.Delete(ctx, key)
+			break
+		}
+	}
+	_, err := s.Get(ctx, "k")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+}
@@ -0,0 +1,63 @@
+package stampede
+
+import (
+	"context"
+	"time"
+)
+
+type collapsed[V any] struct {
+	v   V
+	err error
+	at  time.Time
+}
+
+// miss fetches key for a caller that can't be served from the cache, reusing the result
+// of a fetch completed within the collapse window.
+func (c *Cache[K, V]) miss(ctx context.Context, key K, ck cacheKey[K], fn FetchFunc[V]) (V, error) {
+	if c.collapseWindow > 0 {
+		c.collapsedMu.Lock()
+		r, ok := c.collapsed[ck]
+		c.collapsedMu.Unlock()
+		if ok && time.Since(r.at) < c.collapseWindow {
+			return r.v, r.err
+		}
+	}
+
+	v, _, err := c.do(ctx, key, ck, fn)
+	return v, err
+}
+
+// collapse records the result of an origin fetch of ck with ctx for the collapse window,
+// and returns it. Errors of a canceled fetch are the caller's own and not recorded.
+func (c *Cache[K, V]) collapse(ctx context.Context, ck cacheKey[K], v V, err error) (V, error) {
+	if c.collapseWindow <= 0 || (err != nil && ctx.Err() != nil) {
+		return v, err
+	}
+
+	r := collapsed[V]{v: v, err: err, at: time.Now()}
+	c.collapsedMu.Lock()
+	if c.collapsed == nil {
+		c.collapsed = make(map[cacheKey[K]]collapsed[V])
+	}
+	c.collapsed[ck] = r
+	c.collapsedMu.Unlock()
+
+	time.AfterFunc(c.collapseWindow, func() {
+		c.collapsedMu.Lock()
+		if c.collapsed[ck].at == r.at {
+			delete(c.collapsed, ck)
+		}
+		c.collapsedMu.Unlock()
+	})
+	return v, err
+}
+
+// uncollapse drops the recorded result of ck, once its value was removed or replaced.
+func (c *Cache[K, V]) uncollapse(ck cacheKey[K]) {
+	if c.collapseWindow <= 0 {
+		return
+	}
+	c.collapsedMu.Lock()
+	delete(c.collapsed, ck)
+	c.collapsedMu.Unlock()
+}
@@ -0,0 +1,63 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollapseWindow(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithCollapseWindow(50*time.Millisecond))
+
+	calls := 0
+	fail := func() (int, error) {
+		calls++
+		return 0, errors.New("origin down")
+	}
+
+	// failed fetches aren't cached, but misses right after reuse the error
+	for i := 0; i < 3; i++ {
+		_, err := c.Get(ctx, "k", fail)
+		assert.Error(t, err)
+	}
+	assert.Equal(t, 1, calls)
+
+	time.Sleep(60 * time.Millisecond)
+	_, err := c.Get(ctx, "k", fail)
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCollapseWindowDelete(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithCollapseWindow(time.Minute))
+
+	c.Get(ctx, "k", func() (int, error) { return 1, nil })
+	c.Delete("k")
+	v, err := c.Get(ctx, "k", func() (int, error) { return 2, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+
+	c.Purge()
+	v, _ = c.Get(ctx, "k", func() (int, error) { return 3, nil })
+	assert.Equal(t, 3, v)
+}
+
+func TestCollapseWindowCanceled(t *testing.T) {
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithCollapseWindow(time.Minute))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.GetContext(canceled, "k", func(ctx context.Context) (int, error) { return 0, ctx.Err() })
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// the error of the canceled caller isn't handed to the next one
+	v, err := c.Get(context.Background(), "k", func() (int, error) { return 1, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
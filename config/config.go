@@ -0,0 +1,292 @@
+// Package config builds stampede caches from YAML or JSON documents and environment
+// variables, so caching policy can live in deployment config.
+//
+// A YAML document looks like:
+//
+//	size: 1024
+//	freshFor: 5s
+//	ttl: 1m
+//	keyHashing: sha256
+//	normalizers: [lowercase, sort-query]
+//	name: products
+//	store:
+//	  type: dir
+//	  path: /var/cache/products
+//	  envelope: true
+//
+// Stores needing a client, like Redis, are configured in code with stampede.WithStore,
+// passed to NewCache after the options of the document.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dadav/stampede"
+	"gopkg.in/yaml.v3"
+)
+
+// Config describes a stampede cache.
+type Config struct {
+	// Size is the maximum number of entries of the cache.
+	Size int `json:"size" yaml:"size"`
+
+	// FreshFor and TTL are the freshFor and ttl durations of the cache.
+	FreshFor Duration `json:"freshFor" yaml:"freshFor"`
+	TTL      Duration `json:"ttl" yaml:"ttl"`
+
+	// KeyHashing is one of "", "xxhash" or "sha256", see stampede.WithKeyHashing.
+	KeyHashing string `json:"keyHashing" yaml:"keyHashing"`
+
+	// MinTTL and MaxTTL bound per-value TTLs, see stampede.WithTTLBounds.
+	MinTTL Duration `json:"minTTL" yaml:"minTTL"`
+	MaxTTL Duration `json:"maxTTL" yaml:"maxTTL"`
+
+	// SoftLimit and HardLimit are the size watermarks, see stampede.WithWatermarks.
+	SoftLimit int64 `json:"softLimit" yaml:"softLimit"`
+	HardLimit int64 `json:"hardLimit" yaml:"hardLimit"`
+
+	// Normalizers are the key normalizers, by name: "lowercase", "sort-query",
+	// "strip-query" and "trim-trailing-slash".
+	Normalizers []string `json:"normalizers" yaml:"normalizers"`
+
+	// StripParams are the query parameters removed by the "strip-query" normalizer. It
+	// removes the whole query without any.
+	StripParams []string `json:"stripParams" yaml:"stripParams"`
+
+	// Name names the cache in its stats, traces and errors, and in the Registry of
+	// Register, see stampede.WithName.
+	Name string `json:"name" yaml:"name"`
+
+	// Store is the second tier of the cache, see stampede.WithStore.
+	Store Store `json:"store" yaml:"store"`
+}
+
+// Store describes the store of a cache.
+type Store struct {
+	// Type is one of "" for no store, "memory", "dir" for a stampede.DirStore in Path
+	// or "file" for a stampede.FileStore at Path.
+	Type string `json:"type" yaml:"type"`
+	Path string `json:"path" yaml:"path"`
+
+	// Codec is one of "" or "json" for stampede.JSONCodec, or "gob".
+	Codec string `json:"codec" yaml:"codec"`
+
+	// Envelope keeps the freshness of the values in the store, see
+	// stampede.WithStoreEnvelope.
+	Envelope bool `json:"envelope" yaml:"envelope"`
+}
+
+// Duration is a time.Duration that is written as a string like "1m30s" in documents.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// ParseJSON parses a JSON document.
+func ParseJSON(b []byte) (Config, error) {
+	var c Config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	return c, nil
+}
+
+// ParseYAML parses a YAML document.
+func ParseYAML(b []byte) (Config, error) {
+	var c Config
+	if err := yaml.Unmarshal(b, &c); err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+	return c, nil
+}
+
+// LoadFile parses the JSON (.json) or YAML (.yaml, .yml) document at path.
+func LoadFile(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("config: %w", err)
+	}
+
+	switch ext := filepath.Ext(path); ext {
+	case ".json":
+		return ParseJSON(b)
+	case ".yaml", ".yml":
+		return ParseYAML(b)
+	default:
+		return Config{}, fmt.Errorf("config: unknown file extension %q", ext)
+	}
+}
+
+// LoadEnv overrides c with the environment variables named prefix followed by
+// SIZE, FRESH_FOR, TTL, KEY_HASHING, MIN_TTL, MAX_TTL, SOFT_LIMIT, HARD_LIMIT,
+// NORMALIZERS and STRIP_PARAMS (comma separated), NAME, STORE, STORE_PATH, STORE_CODEC
+// and STORE_ENVELOPE. Unset variables are ignored.
+func (c *Config) LoadEnv(prefix string) error {
+	vars := []struct {
+		name string
+		set  func(string) error
+	}{
+		{"SIZE", func(s string) (err error) { c.Size, err = strconv.Atoi(s); return }},
+		{"FRESH_FOR", c.FreshFor.set},
+		{"TTL", c.TTL.set},
+		{"KEY_HASHING", func(s string) error { c.KeyHashing = s; return nil }},
+		{"MIN_TTL", c.MinTTL.set},
+		{"MAX_TTL", c.MaxTTL.set},
+		{"SOFT_LIMIT", func(s string) (err error) { c.SoftLimit, err = strconv.ParseInt(s, 10, 64); return }},
+		{"HARD_LIMIT", func(s string) (err error) { c.HardLimit, err = strconv.ParseInt(s, 10, 64); return }},
+		{"NORMALIZERS", func(s string) error { c.Normalizers = strings.Split(s, ","); return nil }},
+		{"STRIP_PARAMS", func(s string) error { c.StripParams = strings.Split(s, ","); return nil }},
+		{"NAME", func(s string) error { c.Name = s; return nil }},
+		{"STORE", func(s string) error { c.Store.Type = s; return nil }},
+		{"STORE_PATH", func(s string) error { c.Store.Path = s; return nil }},
+		{"STORE_CODEC", func(s string) error { c.Store.Codec = s; return nil }},
+		{"STORE_ENVELOPE", func(s string) (err error) { c.Store.Envelope, err = strconv.ParseBool(s); return }},
+	}
+
+	for _, v := range vars {
+		s, ok := os.LookupEnv(prefix + v.name)
+		if !ok {
+			continue
+		}
+		if err := v.set(s); err != nil {
+			return fmt.Errorf("config: %s%s: %w", prefix, v.name, err)
+		}
+	}
+	return nil
+}
+
+func (d *Duration) set(s string) error {
+	return d.UnmarshalText([]byte(s))
+}
+
+// Options returns the stampede options described by c.
+func (c Config) Options() ([]stampede.Option, error) {
+	var opts []stampede.Option
+
+	switch c.KeyHashing {
+	case "":
+	case "xxhash":
+		opts = append(opts, stampede.WithKeyHashing(stampede.KeyHashXXHash))
+	case "sha256":
+		opts = append(opts, stampede.WithKeyHashing(stampede.KeyHashSHA256))
+	default:
+		return nil, fmt.Errorf("config: unknown key hashing %q", c.KeyHashing)
+	}
+
+	if c.MinTTL != 0 || c.MaxTTL != 0 {
+		opts = append(opts, stampede.WithTTLBounds(time.Duration(c.MinTTL), time.Duration(c.MaxTTL)))
+	}
+	if c.SoftLimit != 0 || c.HardLimit != 0 {
+		opts = append(opts, stampede.WithWatermarks(c.SoftLimit, c.HardLimit))
+	}
+
+	var normalizers []stampede.Normalizer
+	for _, name := range c.Normalizers {
+		switch strings.TrimSpace(name) {
+		case "lowercase":
+			normalizers = append(normalizers, stampede.LowerCase())
+		case "sort-query":
+			normalizers = append(normalizers, stampede.SortQuery())
+		case "strip-query":
+			normalizers = append(normalizers, stampede.StripQuery(c.StripParams...))
+		case "trim-trailing-slash":
+			normalizers = append(normalizers, stampede.TrimTrailingSlash())
+		default:
+			return nil, fmt.Errorf("config: unknown normalizer %q", name)
+		}
+	}
+	if len(normalizers) > 0 {
+		opts = append(opts, stampede.WithKeyNormalizers(normalizers...))
+	}
+	if c.Name != "" {
+		opts = append(opts, stampede.WithName(c.Name))
+	}
+
+	store, err := c.Store.options()
+	if err != nil {
+		return nil, err
+	}
+	return append(opts, store...), nil
+}
+
+func (s Store) options() ([]stampede.Option, error) {
+	var store stampede.Store
+	var err error
+	switch s.Type {
+	case "":
+		return nil, nil
+	case "memory":
+		store = stampede.NewMemoryStore()
+	case "dir":
+		store, err = stampede.NewDirStore(s.Path)
+	case "file":
+		store, err = stampede.NewFileStore(s.Path)
+	default:
+		return nil, fmt.Errorf("config: unknown store %q", s.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: store: %w", err)
+	}
+
+	var codec stampede.Codec
+	switch s.Codec {
+	case "", "json":
+		codec = stampede.JSONCodec{}
+	case "gob":
+		codec = stampede.GobCodec{}
+	default:
+		return nil, fmt.Errorf("config: unknown codec %q", s.Codec)
+	}
+
+	opts := []stampede.Option{stampede.WithStore(store, codec)}
+	if s.Envelope {
+		opts = append(opts, stampede.WithStoreEnvelope())
+	}
+	return opts, nil
+}
+
+// NewCache returns the cache described by c. Additional options are applied after the
+// ones of c.
+func NewCache[K comparable, V any](c Config, opts ...stampede.Option) (*stampede.Cache[K, V], error) {
+	if c.Size <= 0 {
+		return nil, fmt.Errorf("config: size must be positive, got %d", c.Size)
+	}
+
+	o, err := c.Options()
+	if err != nil {
+		return nil, err
+	}
+	return stampede.NewCacheKV[K, V](c.Size, time.Duration(c.FreshFor), time.Duration(c.TTL), append(o, opts...)...), nil
+}
+
+// Register creates the cache described by c in r, under the name of c, so its stats are
+// reported by r. Additional options are applied after the ones of c.
+func Register[K comparable, V any](r *stampede.Registry, c Config, opts ...stampede.Option) (*stampede.Cache[K, V], error) {
+	if c.Name == "" {
+		return nil, fmt.Errorf("config: registered caches need a name")
+	}
+	if c.Size <= 0 {
+		return nil, fmt.Errorf("config: size must be positive, got %d", c.Size)
+	}
+	o, err := c.Options()
+	if err != nil {
+		return nil, err
+	}
+	return stampede.Register[K, V](r, c.Name, c.Size, time.Duration(c.FreshFor), time.Duration(c.TTL), append(o, opts...)...)
+}
@@ -0,0 +1,132 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/dadav/stampede/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	want := config.Config{
+		Size:        1024,
+		FreshFor:    config.Duration(5 * time.Second),
+		TTL:         config.Duration(time.Minute),
+		KeyHashing:  "sha256",
+		Normalizers: []string{"lowercase", "sort-query"},
+	}
+
+	c, err := config.ParseYAML([]byte(`
+size: 1024
+freshFor: 5s
+ttl: 1m
+keyHashing: sha256
+normalizers: [lowercase, sort-query]
+`))
+	assert.NoError(t, err)
+	assert.Equal(t, want, c)
+
+	c, err = config.ParseJSON([]byte(`{"size": 1024, "freshFor": "5s", "ttl": "1m", "keyHashing": "sha256", "normalizers": ["lowercase", "sort-query"]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, want, c)
+
+	_, err = config.ParseJSON([]byte(`{"ttl": "forever"}`))
+	assert.Error(t, err)
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.yml")
+	assert.NoError(t, os.WriteFile(path, []byte("size: 8\nttl: 1s\n"), 0o600))
+
+	c, err := config.LoadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 8, c.Size)
+
+	_, err = config.LoadFile(filepath.Join(t.TempDir(), "cache.toml"))
+	assert.Error(t, err)
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Setenv("CACHE_SIZE", "64")
+	t.Setenv("CACHE_TTL", "2m")
+	t.Setenv("CACHE_NORMALIZERS", "lowercase,trim-trailing-slash")
+
+	c := config.Config{Size: 8, FreshFor: config.Duration(time.Second)}
+	assert.NoError(t, c.LoadEnv("CACHE_"))
+	assert.Equal(t, 64, c.Size)
+	assert.Equal(t, config.Duration(time.Second), c.FreshFor)
+	assert.Equal(t, config.Duration(2*time.Minute), c.TTL)
+	assert.Equal(t, []string{"lowercase", "trim-trailing-slash"}, c.Normalizers)
+
+	t.Setenv("CACHE_SIZE", "many")
+	assert.Error(t, c.LoadEnv("CACHE_"))
+}
+
+func TestNewCache(t *testing.T) {
+	cache, err := config.NewCache[string, string](config.Config{
+		Size:        8,
+		FreshFor:    config.Duration(time.Second),
+		TTL:         config.Duration(time.Minute),
+		Normalizers: []string{"lowercase"},
+	})
+	assert.NoError(t, err)
+	freshFor, ttl := cache.TTL()
+	assert.Equal(t, time.Second, freshFor)
+	assert.Equal(t, time.Minute, ttl)
+
+	_, err = config.NewCache[string, string](config.Config{})
+	assert.Error(t, err)
+	_, err = config.NewCache[string, string](config.Config{Size: 8, KeyHashing: "md5"})
+	assert.Error(t, err)
+	_, err = config.NewCache[string, string](config.Config{Size: 8, Normalizers: []string{"uppercase"}})
+	assert.Error(t, err)
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	c, err := config.ParseYAML([]byte("size: 8\nfreshFor: 1m\nttl: 1m\nname: products\nstore:\n  type: dir\n  path: " + dir + "\n  envelope: true\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, config.Store{Type: "dir", Path: dir, Envelope: true}, c.Store)
+
+	cache, err := config.NewCache[string, string](c)
+	assert.NoError(t, err)
+	v, err := cache.Get(context.Background(), "a", func() (string, error) { return "v", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "v", v)
+	cache.Close()
+
+	// a new cache finds the value in the store
+	cache, err = config.NewCache[string, string](c)
+	assert.NoError(t, err)
+	v, err = cache.Get(context.Background(), "a", func() (string, error) { return "other", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "v", v)
+	assert.Equal(t, "products", cache.Name())
+
+	_, err = config.NewCache[string, string](config.Config{Size: 8, Store: config.Store{Type: "redis"}})
+	assert.Error(t, err)
+	_, err = config.NewCache[string, string](config.Config{Size: 8, Store: config.Store{Type: "memory", Codec: "xml"}})
+	assert.Error(t, err)
+}
+
+func TestStripParams(t *testing.T) {
+	cache, err := config.NewCache[string, string](config.Config{Size: 8, TTL: config.Duration(time.Minute), Normalizers: []string{"strip-query"}, StripParams: []string{"utm_source"}})
+	assert.NoError(t, err)
+	cache.Get(context.Background(), "/a?id=1&utm_source=x", func() (string, error) { return "1", nil })
+	assert.Equal(t, []string{"/a?id=1"}, cache.Keys())
+}
+
+func TestRegister(t *testing.T) {
+	r := stampede.NewRegistry()
+	_, err := config.Register[string, string](r, config.Config{Size: 8, Name: "products"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"products"}, r.Names())
+
+	_, err = config.Register[string, string](r, config.Config{Size: 8})
+	assert.Error(t, err)
+}
@@ -0,0 +1,80 @@
+package stampede
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ContextPolicy builds the context of a background refresh from the context of the
+// request that triggered it. Keeping the request context as is keeps its values, but
+// the refresh is canceled with the request; detaching it survives the request, but
+// loses trace ids, auth and locale values unless they are copied over.
+type ContextPolicy func(parent context.Context) context.Context
+
+// KeepContext runs background refreshes with the context of the triggering request,
+// so they are canceled together with the request.
+func KeepContext() ContextPolicy {
+	return func(parent context.Context) context.Context {
+		return parent
+	}
+}
+
+// DetachContext runs background refreshes with a context that is never canceled and
+// has no deadline. The given context value keys are copied over from the request
+// context, or all values when no keys are given. DetachContext() is the default policy.
+func DetachContext(keys ...any) ContextPolicy {
+	return func(parent context.Context) context.Context {
+		return detachedContext{parent: parent, keys: keys}
+	}
+}
+
+// detachedContext keeps the values of its parent, but not its cancellation.
+type detachedContext struct {
+	parent context.Context
+	keys   []any
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+func (d detachedContext) Value(key any) any {
+	if len(d.keys) == 0 {
+		return d.parent.Value(key)
+	}
+	for _, k := range d.keys {
+		if k == key {
+			return d.parent.Value(key)
+		}
+	}
+	return nil
+}
+
+func (c *Cache[K, V]) refreshContext(parent context.Context) context.Context {
+	if c.contextPolicy == nil {
+		return DetachContext()(parent)
+	}
+	return c.contextPolicy(parent)
+}
+
+// ErrLowPriorityMiss is returned to low priority callers for keys that are not cached,
+// see WithLowPriority.
+var ErrLowPriorityMiss = errors.New("stampede: low priority miss")
+
+type lowPriorityKey struct{}
+
+// WithLowPriority marks the gets made with the returned context as low priority, e.g.
+// for background jobs, so they never compete with interactive traffic for the origin.
+// Low priority gets are served whatever is cached, even stale or expired values, which
+// are refreshed in the background. They fail with ErrLowPriorityMiss for keys that are
+// not cached.
+func WithLowPriority(ctx context.Context) context.Context {
+	return context.WithValue(ctx, lowPriorityKey{}, true)
+}
+
+// LowPriority reports whether ctx is marked low priority, see WithLowPriority.
+func LowPriority(ctx context.Context) bool {
+	low, _ := ctx.Value(lowPriorityKey{}).(bool)
+	return low
+}
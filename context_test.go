@@ -0,0 +1,69 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey string
+
+func TestContextPolicy(t *testing.T) {
+	refreshCtx := func(opts ...stampede.Option) context.Context {
+		cache := stampede.NewCacheKV[string, string](8, 0, time.Minute, opts...)
+
+		ctx := context.WithValue(context.Background(), ctxKey("trace"), "t1")
+		ctx = context.WithValue(ctx, ctxKey("auth"), "secret")
+		ctx, cancel := context.WithCancel(ctx)
+
+		_, err := cache.GetContext(ctx, "a", func(ctx context.Context) (string, error) { return "v", nil })
+		assert.NoError(t, err)
+
+		got := make(chan context.Context, 1)
+		_, err = cache.GetContext(ctx, "a", func(ctx context.Context) (string, error) {
+			got <- ctx
+			return "v", nil
+		})
+		assert.NoError(t, err)
+		cancel()
+		return <-got
+	}
+
+	ctx := refreshCtx()
+	assert.NoError(t, ctx.Err())
+	assert.Equal(t, "t1", ctx.Value(ctxKey("trace")))
+	assert.Equal(t, "secret", ctx.Value(ctxKey("auth")))
+
+	ctx = refreshCtx(stampede.WithContextPolicy(stampede.DetachContext(ctxKey("trace"))))
+	assert.NoError(t, ctx.Err())
+	assert.Equal(t, "t1", ctx.Value(ctxKey("trace")))
+	assert.Nil(t, ctx.Value(ctxKey("auth")))
+
+	ctx = refreshCtx(stampede.WithContextPolicy(stampede.KeepContext()))
+	<-ctx.Done()
+	assert.Equal(t, "secret", ctx.Value(ctxKey("auth")))
+}
+
+func TestLowPriority(t *testing.T) {
+	ctx := stampede.WithLowPriority(context.Background())
+	c := stampede.NewCacheKV[string, int](8, time.Millisecond, time.Millisecond)
+	defer c.Close()
+
+	_, err := c.Get(ctx, "k", func() (int, error) { return 1, nil })
+	assert.Equal(t, stampede.ErrLowPriorityMiss, err)
+
+	c.Get(context.Background(), "k", func() (int, error) { return 1, nil })
+	time.Sleep(5 * time.Millisecond)
+
+	// expired values are served, and refreshed in the background
+	val, err := c.GetFresh(ctx, "k", func() (int, error) { return 2, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+	assert.Eventually(t, func() bool {
+		val, _ := c.Peek("k")
+		return val == 2
+	}, time.Second, time.Millisecond)
+}
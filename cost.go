@@ -0,0 +1,60 @@
+package stampede
+
+import "time"
+
+// Cost is the cost of fetching a value from the origin.
+type Cost struct {
+	Latency time.Duration
+	Bytes   int64
+	Units   float64 // e.g. currency units of a paid API
+}
+
+// Add returns the sum of c and o.
+func (c Cost) Add(o Cost) Cost {
+	c.Latency += o.Latency
+	c.Bytes += o.Bytes
+	c.Units += o.Units
+	return c
+}
+
+// Coster is implemented by values that know what fetching them cost. The latency of
+// values not implementing Coster is the time taken by the fetch.
+type Coster interface {
+	Cost() Cost
+}
+
+func costOf(v any, latency time.Duration) Cost {
+	if c, ok := v.(Coster); ok {
+		return c.Cost()
+	}
+	return Cost{Latency: latency}
+}
+
+func (c *Cache[K, V]) spend(cost Cost) {
+	c.costMu.Lock()
+	c.spent = c.spent.Add(cost)
+	c.costMu.Unlock()
+}
+
+func (c *Cache[K, V]) avoid(cost Cost) {
+	c.costMu.Lock()
+	c.avoided = c.avoided.Add(cost)
+	c.costMu.Unlock()
+}
+
+// avoidFetch counts the cost of the entry of ck as avoided, for a caller that shared the
+// fetch of another one.
+func (c *Cache[K, V]) avoidFetch(ck cacheKey[K]) {
+	c.mu.RLock()
+	val, ok := c.values.Peek(ck)
+	c.mu.RUnlock()
+	if ok {
+		c.avoid(val.cost())
+	}
+}
+
+func (c *Cache[K, V]) costs() (spent, avoided Cost) {
+	c.costMu.Lock()
+	defer c.costMu.Unlock()
+	return c.spent, c.avoided
+}
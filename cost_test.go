@@ -0,0 +1,31 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+type pricedValue int
+
+func (pricedValue) Cost() stampede.Cost {
+	return stampede.Cost{Latency: time.Second, Bytes: 100, Units: 0.5}
+}
+
+func TestCost(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, pricedValue](8, time.Minute, time.Minute)
+	fetch := func() (pricedValue, error) { return 1, nil }
+
+	for i := 0; i < 3; i++ {
+		_, err := c.Get(ctx, "k", fetch)
+		assert.NoError(t, err)
+	}
+
+	stats := c.Stats()
+	assert.Equal(t, stampede.Cost{Latency: time.Second, Bytes: 100, Units: 0.5}, stats.Spent)
+	assert.Equal(t, stampede.Cost{Latency: 2 * time.Second, Bytes: 200, Units: 1}, stats.Avoided)
+}
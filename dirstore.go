@@ -0,0 +1,175 @@
+package stampede
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DirStore is a Store keeping every entry in its own file in a directory, named by the
+// hash of its key, with a sidecar file holding its metadata. It is meant for large blobs
+// like images or reports, which can be streamed from and to it with Open and Write
+// without holding them in memory.
+type DirStore struct {
+	dir string
+}
+
+// dirMeta is the sidecar of an entry of a DirStore.
+type dirMeta struct {
+	Key    string    `json:"key"`
+	Expiry time.Time `json:"expiry,omitempty"`
+}
+
+const dirMetaSuffix = ".meta"
+
+// NewDirStore returns a DirStore keeping its entries in dir, with the entries already in
+// it. dir is created if it doesn't exist.
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+// path returns the path of the file of key, in a subdirectory by the first byte of its
+// hash, so directories stay small.
+func (s *DirStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(s.dir, name[:2], name)
+}
+
+func (s *DirStore) Get(ctx context.Context, key string) ([]byte, error) {
+	rc, err := s.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// Open returns the value of key for reading. The caller must close it.
+func (s *DirStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path := s.path(key)
+	if !s.valid(path, key) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+// valid reports whether the entry at path holds key and is not expired.
+func (s *DirStore) valid(path, key string) bool {
+	b, err := os.ReadFile(path + dirMetaSuffix)
+	if err != nil {
+		return false
+	}
+	var meta dirMeta
+	if json.Unmarshal(b, &meta) != nil || meta.Key != key {
+		return false
+	}
+	if !meta.Expiry.IsZero() && meta.Expiry.Before(time.Now()) {
+		s.remove(path)
+		return false
+	}
+	return true
+}
+
+func (s *DirStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.Write(ctx, key, bytes.NewReader(value), ttl)
+}
+
+// Write writes the value of key from r, expiring after ttl. A zero ttl doesn't expire.
+// The value is written to a temporary file first, so readers never see a partial value.
+func (s *DirStore) Write(ctx context.Context, key string, r io.Reader, ttl time.Duration) error {
+	meta := dirMeta{Key: key}
+	if ttl > 0 {
+		meta.Expiry = time.Now().Add(ttl)
+	}
+	mb, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := writeFile(path, r); err != nil {
+		return err
+	}
+	return writeFile(path+dirMetaSuffix, bytes.NewReader(mb))
+}
+
+// writeFile atomically replaces the file at path with the contents of r.
+func writeFile(path string, r io.Reader) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	// sync before the rename, so a crash never leaves an empty or partial file behind
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+func (s *DirStore) Delete(ctx context.Context, key string) error {
+	return s.remove(s.path(key))
+}
+
+func (s *DirStore) remove(path string) error {
+	err := os.Remove(path + dirMetaSuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Sweep deletes the files of expired entries, and returns how many entries were
+// deleted. Expired entries are also deleted when they are read.
+func (s *DirStore) Sweep() (int, error) {
+	var n int
+	now := time.Now()
+	err := filepath.WalkDir(s.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, dirMetaSuffix) {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var meta dirMeta
+		if json.Unmarshal(b, &meta) == nil && !meta.Expiry.IsZero() && meta.Expiry.Before(now) {
+			if s.remove(strings.TrimSuffix(path, dirMetaSuffix)) == nil {
+				n++
+			}
+		}
+		return nil
+	})
+	return n, err
+}
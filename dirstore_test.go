@@ -0,0 +1,57 @@
+package stampede_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirStore(t *testing.T) {
+	ctx := context.Background()
+	s, err := stampede.NewDirStore(t.TempDir())
+	assert.NoError(t, err)
+
+	_, err = s.Get(ctx, "a")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+
+	assert.NoError(t, s.Write(ctx, "report", strings.NewReader("large blob"), 0))
+	rc, err := s.Open(ctx, "report")
+	assert.NoError(t, err)
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, "large blob", string(b))
+
+	assert.NoError(t, s.Set(ctx, "a", []byte("1"), time.Millisecond))
+	assert.NoError(t, s.Set(ctx, "b", []byte("2"), time.Millisecond))
+	time.Sleep(2 * time.Millisecond)
+	_, err = s.Get(ctx, "a")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+	n, err := s.Sweep()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	assert.NoError(t, s.Delete(ctx, "report"))
+	_, err = s.Get(ctx, "report")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+}
+
+func TestDirStoreCache(t *testing.T) {
+	ctx := context.Background()
+	s, err := stampede.NewDirStore(t.TempDir())
+	assert.NoError(t, err)
+
+	c := stampede.NewCacheKV[string, []byte](8, time.Minute, time.Hour, stampede.WithStore(s, stampede.GobCodec{}))
+	defer c.Close()
+	c.Get(ctx, "image", func() ([]byte, error) { return []byte("png"), nil })
+
+	other := stampede.NewCacheKV[string, []byte](8, time.Minute, time.Hour, stampede.WithStore(s, stampede.GobCodec{}))
+	defer other.Close()
+	v, err := other.Get(ctx, "image", func() ([]byte, error) { return nil, io.ErrUnexpectedEOF })
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("png"), v)
+}
@@ -0,0 +1,133 @@
+// Package dynamostore is a stampede.Store backed by a DynamoDB table, for serverless
+// deployments without Redis.
+//
+// The package doesn't depend on the AWS SDK: Client is satisfied by a small adapter
+// around dynamodb.Client, converting items with the attributevalue package and mapping
+// ConditionalCheckFailedException to ErrConditionFailed.
+//
+// The table needs a string partition key, and should have time to live enabled on the
+// TTL attribute. DynamoDB deletes expired items lazily, hours after they expired, so
+// the store also treats items past their TTL as missing.
+package dynamostore
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dadav/stampede"
+)
+
+// ErrConditionFailed is returned by Client.PutItem if its condition isn't met.
+var ErrConditionFailed = errors.New("dynamostore: condition failed")
+
+// Item is a DynamoDB item. Values are string, []byte or int64, for the attribute types
+// S, B and N.
+type Item map[string]any
+
+// Client is the subset of the DynamoDB API used by Store.
+type Client interface {
+	// GetItem returns the item with key in table, or nil if there is none.
+	GetItem(ctx context.Context, table string, key Item) (Item, error)
+	// PutItem writes item to table if condition, a condition expression using values,
+	// holds. An empty condition always holds.
+	PutItem(ctx context.Context, table string, item Item, condition string, values Item) error
+	DeleteItem(ctx context.Context, table string, key Item) error
+}
+
+// Attributes names the attributes of the items written by Store.
+type Attributes struct {
+	Key     string // partition key, "pk" by default
+	Value   string // "val" by default
+	TTL     string // expiry in unix seconds, "expires_at" by default
+	Version string // version for CompareAndSwap, "ver" by default
+}
+
+// Store stores entries in a DynamoDB table.
+type Store struct {
+	client Client
+	table  string
+	attrs  Attributes
+}
+
+var _ stampede.Store = (*Store)(nil)
+
+// New returns a Store for table. Zero attributes get their default names.
+func New(client Client, table string, attrs Attributes) *Store {
+	if attrs.Key == "" {
+		attrs.Key = "pk"
+	}
+	if attrs.Value == "" {
+		attrs.Value = "val"
+	}
+	if attrs.TTL == "" {
+		attrs.TTL = "expires_at"
+	}
+	if attrs.Version == "" {
+		attrs.Version = "ver"
+	}
+	return &Store{client: client, table: table, attrs: attrs}
+}
+
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	b, _, err := s.GetVersion(ctx, key)
+	return b, err
+}
+
+// GetVersion is like Get, but also returns the version of the entry for
+// CompareAndSwap.
+func (s *Store) GetVersion(ctx context.Context, key string) ([]byte, int64, error) {
+	item, err := s.client.GetItem(ctx, s.table, Item{s.attrs.Key: key})
+	if err != nil {
+		return nil, 0, err
+	}
+	if item == nil {
+		return nil, 0, stampede.ErrNotFound
+	}
+	if exp, ok := item[s.attrs.TTL].(int64); ok && exp <= time.Now().Unix() {
+		return nil, 0, stampede.ErrNotFound
+	}
+	b, ok := item[s.attrs.Value].([]byte)
+	if !ok {
+		return nil, 0, stampede.ErrNotFound
+	}
+	ver, _ := item[s.attrs.Version].(int64)
+	return b, ver, nil
+}
+
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return s.client.PutItem(ctx, s.table, s.item(key, value, ttl, time.Now().UnixNano()), "", nil)
+}
+
+// CompareAndSwap sets key only if its version is still version, as returned by
+// GetVersion, or if there is no entry and version is 0. It returns ErrConditionFailed
+// if another writer got there first.
+func (s *Store) CompareAndSwap(ctx context.Context, key string, version int64, value []byte, ttl time.Duration) error {
+	next := time.Now().UnixNano()
+	if next <= version {
+		next = version + 1
+	}
+
+	item := s.item(key, value, ttl, next)
+	if version == 0 {
+		return s.client.PutItem(ctx, s.table, item, "attribute_not_exists("+s.attrs.Key+")", nil)
+	}
+	return s.client.PutItem(ctx, s.table, item, s.attrs.Version+" = :ver", Item{":ver": version})
+}
+
+func (s *Store) Delete(ctx context.Context, key string) error {
+	return s.client.DeleteItem(ctx, s.table, Item{s.attrs.Key: key})
+}
+
+func (s *Store) item(key string, value []byte, ttl time.Duration, version int64) Item {
+	item := Item{
+		s.attrs.Key:     key,
+		s.attrs.Value:   value,
+		s.attrs.Version: version,
+	}
+	if ttl > 0 {
+		// round up, so items don't expire early at second granularity
+		item[s.attrs.TTL] = time.Now().Add(ttl + time.Second - 1).Unix()
+	}
+	return item
+}
@@ -0,0 +1,108 @@
+package dynamostore_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/dadav/stampede/dynamostore"
+	"github.com/stretchr/testify/assert"
+)
+
+// table fakes DynamoDB for the conditions written by Store
+type table struct {
+	mu    sync.Mutex
+	items map[string]dynamostore.Item
+}
+
+func (t *table) GetItem(ctx context.Context, name string, key dynamostore.Item) (dynamostore.Item, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.items[key["pk"].(string)], nil
+}
+
+func (t *table) PutItem(ctx context.Context, name string, item dynamostore.Item, condition string, values dynamostore.Item) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	old, exists := t.items[item["pk"].(string)]
+	switch {
+	case strings.HasPrefix(condition, "attribute_not_exists"):
+		if exists {
+			return dynamostore.ErrConditionFailed
+		}
+	case condition != "":
+		if !exists || old["ver"] != values[":ver"] {
+			return dynamostore.ErrConditionFailed
+		}
+	}
+	t.items[item["pk"].(string)] = item
+	return nil
+}
+
+func (t *table) DeleteItem(ctx context.Context, name string, key dynamostore.Item) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.items, key["pk"].(string))
+	return nil
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	tbl := &table{items: make(map[string]dynamostore.Item)}
+	s := dynamostore.New(tbl, "cache", dynamostore.Attributes{})
+
+	_, err := s.Get(ctx, "k")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+
+	assert.NoError(t, s.Set(ctx, "k", []byte("v"), time.Minute))
+	b, err := s.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, "v", string(b))
+	assert.Greater(t, tbl.items["k"]["expires_at"], time.Now().Unix())
+
+	// items past their ttl are missing before DynamoDB deletes them
+	tbl.items["k"]["expires_at"] = time.Now().Add(-time.Second).Unix()
+	_, err = s.Get(ctx, "k")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+
+	assert.NoError(t, s.Delete(ctx, "k"))
+	assert.Empty(t, tbl.items)
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	s := dynamostore.New(&table{items: make(map[string]dynamostore.Item)}, "cache", dynamostore.Attributes{})
+
+	assert.NoError(t, s.CompareAndSwap(ctx, "k", 0, []byte("a"), time.Minute))
+	assert.ErrorIs(t, s.CompareAndSwap(ctx, "k", 0, []byte("b"), time.Minute), dynamostore.ErrConditionFailed)
+
+	_, ver, err := s.GetVersion(ctx, "k")
+	assert.NoError(t, err)
+	assert.NoError(t, s.CompareAndSwap(ctx, "k", ver, []byte("c"), time.Minute))
+	assert.ErrorIs(t, s.CompareAndSwap(ctx, "k", ver, []byte("d"), time.Minute), dynamostore.ErrConditionFailed)
+
+	b, err := s.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, "c", string(b))
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	s := dynamostore.New(&table{items: make(map[string]dynamostore.Item)}, "cache", dynamostore.Attributes{})
+
+	calls := 0
+	fetch := func() (string, error) {
+		calls++
+		return "v", nil
+	}
+	for i := 0; i < 2; i++ {
+		c := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithStore(s, stampede.JSONCodec{}))
+		val, err := c.Get(ctx, "k", fetch)
+		assert.NoError(t, err)
+		assert.Equal(t, "v", val)
+	}
+	assert.Equal(t, 1, calls)
+}
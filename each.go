@@ -0,0 +1,51 @@
+package stampede
+
+import (
+	"context"
+	"sync"
+)
+
+// GetEach gets every key of keys like GetContext, fetching missing keys with fn, with
+// at most concurrency gets in flight at once, or all of them for a concurrency below 1.
+// It returns the values of the keys it got, and the errors of the others.
+func (c *Cache[K, V]) GetEach(ctx context.Context, keys []K, fn func(ctx context.Context, key K) (V, error), concurrency int) (map[K]V, map[K]error) {
+	if concurrency < 1 || concurrency > len(keys) {
+		concurrency = len(keys)
+	}
+
+	vals := make(map[K]V, len(keys))
+	var errs map[K]error
+	var mu sync.Mutex
+
+	work := make(chan K)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range work {
+				key := key
+				v, err := c.GetContext(ctx, key, func(ctx context.Context) (V, error) {
+					return fn(ctx, key)
+				})
+
+				mu.Lock()
+				if err != nil {
+					if errs == nil {
+						errs = make(map[K]error)
+					}
+					errs[key] = err
+				} else {
+					vals[key] = v
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, key := range keys {
+		work <- key
+	}
+	close(work)
+	wg.Wait()
+	return vals, errs
+}
@@ -0,0 +1,41 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEach(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[int, int](16, time.Minute, time.Minute)
+	c.Get(ctx, 1, func() (int, error) { return 100, nil })
+
+	errOdd := errors.New("odd")
+	var inFlight, maxInFlight int32
+	fetch := func(ctx context.Context, key int) (int, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		if key%2 == 1 {
+			return 0, errOdd
+		}
+		return key * 10, nil
+	}
+
+	vals, errs := c.GetEach(ctx, []int{1, 2, 3, 4, 5, 6}, fetch, 2)
+	assert.Equal(t, map[int]int{1: 100, 2: 20, 4: 40, 6: 60}, vals)
+	assert.Equal(t, map[int]error{3: errOdd, 5: errOdd}, errs)
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxInFlight), int32(2))
+}
@@ -0,0 +1,134 @@
+package stampede
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorAction is what the cache does with an error returned by the origin, see
+// WithErrorPolicy.
+type ErrorAction int
+
+const (
+	// ErrorPropagate returns the error to the callers, the default.
+	ErrorPropagate ErrorAction = iota
+
+	// ErrorCache caches the error for the negative ttl, so callers get it again without
+	// reaching the origin, e.g. for not found errors. See WithNegativeTTL.
+	ErrorCache
+
+	// ErrorServeStale returns the cached value of the key, even an expired one, instead
+	// of the error. The error is returned if there is none.
+	ErrorServeStale
+
+	// ErrorOpenCircuit stops all fetches of the cache for the circuit cooldown, e.g. when
+	// the origin is overloaded. While the circuit is open, callers get the cached value
+	// of their key, even an expired one, or ErrCircuitOpen. See WithCircuitCooldown.
+	ErrorOpenCircuit
+)
+
+// ErrCircuitOpen is returned instead of fetching while the circuit is open, see
+// ErrorOpenCircuit.
+var ErrCircuitOpen = errors.New("stampede: circuit open")
+
+const defaultCircuitCooldown = 5 * time.Second
+
+type negative struct {
+	err   error
+	until time.Time
+}
+
+// failFast returns the result of a fetch of ck that doesn't reach the origin, because
+// its error is cached, the circuit is open or it is known to be missing.
+func (c *Cache[K, V]) failFast(ck cacheKey[K]) (v V, err error, ok bool) {
+	if c.missing != nil && c.missing.has(keyHash(ck)) {
+		return v, c.named(ErrNotFound), true
+	}
+	if c.errorPolicy == nil {
+		return v, nil, false
+	}
+
+	if until := atomic.LoadInt64(&c.circuitUntil); time.Now().UnixNano() < until {
+		v, err = c.serveStale(ck, c.named(ErrCircuitOpen))
+		return v, err, true
+	}
+
+	c.negativesMu.Lock()
+	n, ok := c.negatives[ck]
+	c.negativesMu.Unlock()
+	if ok && time.Now().Before(n.until) {
+		return v, n.err, true
+	}
+	return v, nil, false
+}
+
+// failed applies the error policy to err, returned by the origin for ck.
+func (c *Cache[K, V]) failed(ck cacheKey[K], v V, err error) (V, error) {
+	if c.missing != nil && errors.Is(err, ErrNotFound) {
+		c.missing.add(keyHash(ck))
+	}
+	if c.errorPolicy == nil {
+		return v, err
+	}
+
+	switch c.errorPolicy(err) {
+	case ErrorCache:
+		c.cacheError(ck, err)
+	case ErrorServeStale:
+		return c.serveStale(ck, err)
+	case ErrorOpenCircuit:
+		cooldown := c.circuitCooldown
+		if cooldown <= 0 {
+			cooldown = defaultCircuitCooldown
+		}
+		atomic.StoreInt64(&c.circuitUntil, time.Now().Add(cooldown).UnixNano())
+		return c.serveStale(ck, err)
+	}
+	return v, err
+}
+
+// serveStale returns the cached value of ck, or err if there is none.
+func (c *Cache[K, V]) serveStale(ck cacheKey[K], err error) (V, error) {
+	if val, ok := c.lookup(ck); ok {
+		return val.Value(), nil
+	}
+	var zero V
+	return zero, err
+}
+
+func (c *Cache[K, V]) cacheError(ck cacheKey[K], err error) {
+	ttl := c.negativeTTL
+	if ttl <= 0 {
+		ttl, _ = c.TTL()
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	n := negative{err: err, until: time.Now().Add(ttl)}
+	c.negativesMu.Lock()
+	if c.negatives == nil {
+		c.negatives = make(map[cacheKey[K]]negative)
+	}
+	c.negatives[ck] = n
+	c.negativesMu.Unlock()
+
+	time.AfterFunc(ttl, func() {
+		c.negativesMu.Lock()
+		if c.negatives[ck].until == n.until {
+			delete(c.negatives, ck)
+		}
+		c.negativesMu.Unlock()
+	})
+}
+
+// forgetError drops the cached error of ck, if any.
+func (c *Cache[K, V]) forgetError(ck cacheKey[K]) {
+	if c.errorPolicy == nil {
+		return
+	}
+	c.negativesMu.Lock()
+	delete(c.negatives, ck)
+	c.negativesMu.Unlock()
+}
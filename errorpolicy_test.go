@@ -0,0 +1,96 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorPolicy(t *testing.T) {
+	ctx := context.Background()
+	errNotFound := errors.New("not found")
+	errOverloaded := errors.New("overloaded")
+	errFlaky := errors.New("flaky")
+
+	policy := func(err error) stampede.ErrorAction {
+		switch err {
+		case errNotFound:
+			return stampede.ErrorCache
+		case errOverloaded:
+			return stampede.ErrorOpenCircuit
+		case errFlaky:
+			return stampede.ErrorServeStale
+		}
+		return stampede.ErrorPropagate
+	}
+
+	t.Run("cache", func(t *testing.T) {
+		c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithErrorPolicy(policy))
+		var calls int
+		fetch := func() (int, error) {
+			calls++
+			return 0, errNotFound
+		}
+
+		for i := 0; i < 3; i++ {
+			_, err := c.Get(ctx, "k", fetch)
+			assert.Equal(t, errNotFound, err)
+		}
+		assert.Equal(t, 1, calls)
+
+		c.SoftDelete("k")
+		_, err := c.Get(ctx, "k", fetch)
+		assert.Equal(t, errNotFound, err)
+		assert.Equal(t, 2, calls)
+	})
+
+	t.Run("serve stale", func(t *testing.T) {
+		c := stampede.NewCacheKV[string, int](8, time.Millisecond, time.Millisecond, stampede.WithErrorPolicy(policy))
+		c.Get(ctx, "k", func() (int, error) { return 1, nil })
+		time.Sleep(5 * time.Millisecond)
+
+		val, err := c.Get(ctx, "k", func() (int, error) { return 0, errFlaky })
+		assert.NoError(t, err)
+		assert.Equal(t, 1, val)
+
+		_, err = c.Get(ctx, "other", func() (int, error) { return 0, errFlaky })
+		assert.Equal(t, errFlaky, err)
+	})
+
+	t.Run("open circuit", func(t *testing.T) {
+		c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute,
+			stampede.WithErrorPolicy(policy), stampede.WithCircuitCooldown(20*time.Millisecond))
+		var calls int
+		fetch := func() (int, error) {
+			calls++
+			return 0, errOverloaded
+		}
+
+		_, err := c.Get(ctx, "a", fetch)
+		assert.Equal(t, errOverloaded, err)
+		_, err = c.Get(ctx, "b", fetch)
+		assert.Equal(t, stampede.ErrCircuitOpen, err)
+		assert.Equal(t, 1, calls)
+
+		time.Sleep(30 * time.Millisecond)
+		val, err := c.Get(ctx, "b", func() (int, error) { return 2, nil })
+		assert.NoError(t, err)
+		assert.Equal(t, 2, val)
+	})
+
+	t.Run("propagate", func(t *testing.T) {
+		c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithErrorPolicy(policy))
+		var calls int
+		fetch := func() (int, error) {
+			calls++
+			return 0, errors.New("boom")
+		}
+		c.Get(ctx, "k", fetch)
+		c.Get(ctx, "k", fetch)
+		assert.Equal(t, 2, calls)
+	})
+}
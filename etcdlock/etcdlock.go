@@ -0,0 +1,92 @@
+// Package etcdlock is a stampede.Locker backed by etcd, for deployments that already run
+// etcd and don't want Redis just for locks.
+//
+// The package doesn't depend on the etcd client: Session is satisfied by a small adapter
+// around a concurrency.Session, whose NewMutex returns concurrency.NewMutex(session,
+// key). Locks are held on the lease of the session, so the locks of crashed holders
+// expire with their lease.
+package etcdlock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/dadav/stampede"
+)
+
+// ErrSessionDone is returned by Lock once the session is done, e.g. because its lease
+// expired.
+var ErrSessionDone = errors.New("etcdlock: session done")
+
+// Mutex is implemented by *concurrency.Mutex.
+type Mutex interface {
+	Lock(ctx context.Context) error
+	Unlock(ctx context.Context) error
+}
+
+// Session is the subset of concurrency.Session used by Locker.
+type Session interface {
+	// NewMutex returns the mutex named key.
+	NewMutex(key string) Mutex
+	// Done is closed once the session is done.
+	Done() <-chan struct{}
+}
+
+// DefaultUnlockTimeout is how long unlocking a key may take.
+const DefaultUnlockTimeout = 5 * time.Second
+
+// Locker locks keys with etcd mutexes.
+type Locker struct {
+	session Session
+	prefix  string
+
+	// UnlockTimeout is how long unlocking a key may take, DefaultUnlockTimeout if 0.
+	// Keys failing to unlock stay locked until the lease of the session expires.
+	UnlockTimeout time.Duration
+}
+
+var _ stampede.Locker = (*Locker)(nil)
+
+// New returns a Locker whose mutexes are named prefix followed by the locked key, e.g.
+// "/stampede/locks/".
+func New(session Session, prefix string) *Locker {
+	return &Locker{session: session, prefix: prefix}
+}
+
+func (l *Locker) Lock(ctx context.Context, key string) (func(), error) {
+	select {
+	case <-l.session.Done():
+		return nil, ErrSessionDone
+	default:
+	}
+
+	m := l.session.NewMutex(l.prefix + key)
+	if err := m.Lock(ctx); err != nil {
+		return nil, err
+	}
+
+	timeout := l.UnlockTimeout
+	if timeout <= 0 {
+		timeout = DefaultUnlockTimeout
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			m.Unlock(ctx)
+		})
+	}, nil
+}
+
+// Ping reports whether the session is still alive, see stampede.Pinger.
+func (l *Locker) Ping(ctx context.Context) error {
+	select {
+	case <-l.session.Done():
+		return ErrSessionDone
+	default:
+		return nil
+	}
+}
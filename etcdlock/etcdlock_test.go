@@ -0,0 +1,109 @@
+package etcdlock_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/dadav/stampede/etcdlock"
+	"github.com/stretchr/testify/assert"
+)
+
+// session fakes etcd mutexes with channels
+type session struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+	names []string
+	done  chan struct{}
+}
+
+func newSession() *session {
+	return &session{locks: map[string]chan struct{}{}, done: make(chan struct{})}
+}
+
+func (s *session) NewMutex(key string) etcdlock.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locks[key] == nil {
+		s.locks[key] = make(chan struct{}, 1)
+	}
+	s.names = append(s.names, key)
+	return mutex(s.locks[key])
+}
+
+func (s *session) Done() <-chan struct{} { return s.done }
+
+type mutex chan struct{}
+
+func (m mutex) Lock(ctx context.Context) error {
+	select {
+	case m <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m mutex) Unlock(ctx context.Context) error {
+	<-m
+	return nil
+}
+
+func TestLocker(t *testing.T) {
+	s := newSession()
+	l := etcdlock.New(s, "/locks/")
+	ctx := context.Background()
+
+	unlock, err := l.Lock(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"/locks/a"}, s.names)
+
+	// the key stays locked until unlocked
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	_, err = l.Lock(short, "a")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	unlock()
+	unlock()
+	unlock, err = l.Lock(ctx, "a")
+	assert.NoError(t, err)
+	unlock()
+
+	assert.NoError(t, l.Ping(ctx))
+	close(s.done)
+	_, err = l.Lock(ctx, "a")
+	assert.ErrorIs(t, err, etcdlock.ErrSessionDone)
+	assert.ErrorIs(t, l.Ping(ctx), etcdlock.ErrSessionDone)
+}
+
+func TestLockerCache(t *testing.T) {
+	store := stampede.NewMemoryStore()
+	l := etcdlock.New(newSession(), "/locks/")
+	ctx := context.Background()
+
+	var calls int64
+	fetch := func() (string, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return "v", nil
+	}
+
+	// two instances sharing the store and the locks fetch once
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		c := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithStore(store, stampede.JSONCodec{}), stampede.WithLocker(l))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := c.Get(ctx, "a", fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, "v", v)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+}
@@ -0,0 +1,132 @@
+package stampede
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventKind is the kind of an Event.
+type EventKind int
+
+const (
+	// EventSet is sent when an entry is added or replaced.
+	EventSet EventKind = iota
+	// EventEvict is sent when an entry is evicted or purged.
+	EventEvict
+	// EventInvalidate is sent once for all entries removed by InvalidateTag, Delete or
+	// DeleteMany, or marked stale by SoftDelete or InvalidateMany, with their keys in
+	// Keys.
+	EventInvalidate
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventSet:
+		return "set"
+	case EventEvict:
+		return "evict"
+	case EventInvalidate:
+		return "invalidate"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Event is a change of the cache. Key is the zero value for entries stored by digest
+// without retaining their key, see WithRetainedKeys. Events of kind EventInvalidate
+// list the keys of all entries removed at once in Keys, their Key, Value and times are
+// those of the first of them.
+type Event[K comparable, V any] struct {
+	Kind       EventKind
+	Key        K
+	Keys       []K
+	Value      V
+	BestBefore time.Time
+	Expiry     time.Time
+	Source     Source
+}
+
+// EventBuffer is the capacity of the channels returned by Events.
+const EventBuffer = 256
+
+// Events subscribes to the changes of the cache, e.g. to mirror it to a standby. Events
+// are not delivered to subscribers lagging more than EventBuffer events behind, see
+// Stats. The channel is closed by Close.
+func (c *Cache[K, V]) Events() <-chan Event[K, V] {
+	ch := make(chan Event[K, V], EventBuffer)
+
+	c.closeMu.RLock()
+	defer c.closeMu.RUnlock()
+	if c.closed {
+		close(ch)
+		return ch
+	}
+
+	c.mu.Lock()
+	c.subscribers = append(c.subscribers, ch)
+	c.mu.Unlock()
+	return ch
+}
+
+// emit sends an event for entry to all subscribers. c.mu must be held.
+func (c *Cache[K, V]) emit(kind EventKind, ck cacheKey[K], entry value[K, V]) {
+	if len(c.subscribers) == 0 {
+		return
+	}
+	c.send(c.event(kind, ck, entry))
+}
+
+// invalidated adds entry to the EventInvalidate sent by emitInvalidated. c.mu must be
+// held.
+func (c *Cache[K, V]) invalidated(ck cacheKey[K], entry value[K, V]) {
+	if len(c.subscribers) == 0 {
+		return
+	}
+	if c.invalidation == nil {
+		ev := c.event(EventInvalidate, ck, entry)
+		c.invalidation = &ev
+	}
+	key := ck.key
+	if entry.hasKey {
+		key = entry.key
+	}
+	c.invalidation.Keys = append(c.invalidation.Keys, key)
+}
+
+// emitInvalidated sends the EventInvalidate of the entries removed since the last call,
+// if any. c.mu must be held.
+func (c *Cache[K, V]) emitInvalidated() {
+	if c.invalidation == nil {
+		return
+	}
+	ev := *c.invalidation
+	c.invalidation = nil
+	c.send(ev)
+}
+
+func (c *Cache[K, V]) event(kind EventKind, ck cacheKey[K], entry value[K, V]) Event[K, V] {
+	key := ck.key
+	if entry.hasKey {
+		key = entry.key
+	}
+	return Event[K, V]{Kind: kind, Key: key, Value: entry.v, BestBefore: time.Unix(0, entry.bestBefore), Expiry: time.Unix(0, entry.expiry), Source: entry.source}
+}
+
+// send sends ev to all subscribers. c.mu must be held.
+func (c *Cache[K, V]) send(ev Event[K, V]) {
+	for _, ch := range c.subscribers {
+		select {
+		case ch <- ev:
+		default:
+			c.dropped++
+		}
+	}
+}
+
+func (c *Cache[K, V]) closeEvents() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ch := range c.subscribers {
+		close(ch)
+	}
+	c.subscribers = nil
+}
@@ -0,0 +1,30 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, page](1, time.Minute, time.Minute)
+	events := c.Events()
+
+	c.Set(ctx, "/a", func() (page, error) { return page{body: "a", products: []string{"p1"}}, nil })
+	c.Set(ctx, "/b", func() (page, error) { return page{body: "b", products: []string{"p1"}}, nil })
+	c.InvalidateTag("p1")
+	assert.NoError(t, c.Close())
+
+	var got []string
+	for ev := range events {
+		got = append(got, ev.Kind.String()+" "+ev.Key)
+	}
+	assert.Equal(t, []string{"set /a", "evict /a", "set /b", "invalidate /b"}, got)
+
+	_, ok := <-c.Events()
+	assert.False(t, ok)
+}
@@ -0,0 +1,208 @@
+package stampede
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrInvalidExport is returned by Import for data not written by Export.
+var ErrInvalidExport = errors.New("stampede: invalid export")
+
+// ErrDynamicKeys is returned by Export and Import for caches whose keys are of an
+// interface type, like a Cache[any, V], unless their keys are hashed and not retained:
+// codecs can't restore the dynamic types of such keys.
+var ErrDynamicKeys = errors.New("stampede: keys of dynamic types can't be exported")
+
+// kinds of exported keys
+const (
+	exportKey    = 0 // key encoded with the codec
+	exportDigest = 1 // digest of a key that isn't retained
+)
+
+// Export writes all unexpired entries to w, to be loaded into another cache with Import,
+// e.g. to avoid a cold start during a blue/green deployment. Every entry is a key
+// followed by an Envelope, both prefixed with their length as uvarint. Keys and values
+// are encoded with the codec of the store, or as JSON without a store. It returns the
+// number of entries written. See ErrDynamicKeys.
+func (c *Cache[K, V]) Export(ctx context.Context, w io.Writer) (int, error) {
+	if c.exportsKeys() && c.anyKeys {
+		return 0, c.errorf("export: %w", ErrDynamicKeys)
+	}
+	codec := c.exportCodec()
+	id := codecID(codec)
+
+	type exported struct {
+		ck  cacheKey[K]
+		val value[K, V]
+	}
+	c.mu.RLock()
+	entries := make([]exported, 0, c.values.Len())
+	for _, ck := range c.values.Keys() {
+		if val, ok := c.values.Peek(ck); ok && !val.IsExpired() {
+			entries = append(entries, exported{ck, val})
+		}
+	}
+	c.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	var n int
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+
+		var key []byte
+		switch {
+		case e.ck.digest == "":
+			b, err := codec.Marshal(e.ck.key)
+			if err != nil {
+				return n, c.errorf("export key %v: %w", e.ck.key, err)
+			}
+			key = append([]byte{exportKey}, b...)
+		case e.val.hasKey:
+			b, err := codec.Marshal(e.val.key)
+			if err != nil {
+				return n, c.errorf("export key %v: %w", e.val.key, err)
+			}
+			key = append([]byte{exportKey}, b...)
+		default:
+			key = append([]byte{exportDigest}, e.ck.digest...)
+		}
+
+		payload, err := codec.Marshal(e.val.v)
+		if err != nil {
+			return n, c.errorf("export value: %w", err)
+		}
+		env := EncodeEnvelope(Envelope{BestBefore: time.Unix(0, e.val.bestBefore), Expiry: time.Unix(0, e.val.expiry), CodecID: id, Payload: payload})
+
+		if err := writeFrame(bw, key); err != nil {
+			return n, err
+		}
+		if err := writeFrame(bw, env); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, bw.Flush()
+}
+
+// Import loads the entries written by Export from r, keeping their freshness. Entries
+// expired in the meantime are skipped. It returns the number of entries loaded. See
+// ErrDynamicKeys.
+func (c *Cache[K, V]) Import(ctx context.Context, r io.Reader) (int, error) {
+	if c.exportsKeys() && c.anyKeys {
+		return 0, c.errorf("import: %w", ErrDynamicKeys)
+	}
+	codec := c.exportCodec()
+	br := bufio.NewReader(r)
+
+	var n int
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+
+		key, err := readFrame(br)
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, c.named(err)
+		}
+		b, err := readFrame(br)
+		if err != nil {
+			if err == io.EOF {
+				err = ErrInvalidExport
+			}
+			return n, c.named(err)
+		}
+
+		env, err := DecodeEnvelope(b)
+		if err != nil {
+			return n, c.named(err)
+		}
+		if env.CodecID != codecID(codec) {
+			return n, c.named(fmt.Errorf("%w: codec %d", ErrInvalidExport, env.CodecID))
+		}
+		if !env.Expiry.After(time.Now()) {
+			continue
+		}
+
+		var v V
+		if err := codec.Unmarshal(env.Payload, &v); err != nil {
+			return n, c.errorf("import value: %w", err)
+		}
+
+		var entry value[K, V]
+		var ck cacheKey[K]
+		switch {
+		case len(key) > 0 && key[0] == exportKey:
+			var k K
+			if err := codec.Unmarshal(key[1:], &k); err != nil {
+				return n, c.errorf("import key: %w", err)
+			}
+			ck = c.cacheKey(k)
+			entry = c.entry(k, ck, v, env.BestBefore, env.Expiry)
+		case len(key) > 0 && key[0] == exportDigest:
+			ck = cacheKey[K]{digest: string(key[1:])}
+			var zero K
+			entry = c.entry(zero, ck, v, env.BestBefore, env.Expiry)
+			entry.key, entry.hasKey = zero, false
+		default:
+			return n, c.named(ErrInvalidExport)
+		}
+
+		c.mu.Lock()
+		c.add(ck, entry)
+		c.mu.Unlock()
+		n++
+	}
+}
+
+// exportsKeys reports whether Export writes keys, rather than only the digests of keys
+// that are hashed and not retained.
+func (c *Cache[K, V]) exportsKeys() bool {
+	return c.keyHash == KeyHashNone || c.retainKeys
+}
+
+func (c *Cache[K, V]) exportCodec() Codec {
+	if c.codec != nil {
+		return c.codec
+	}
+	return JSONCodec{}
+}
+
+// maxFrameSize bounds the allocations of Import for corrupt lengths.
+const maxFrameSize = 1 << 30
+
+func writeFrame(w *bufio.Writer, b []byte) error {
+	var buf [binary.MaxVarintLen64]byte
+	if _, err := w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(b)))]); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if n > maxFrameSize {
+		return nil, ErrInvalidExport
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = ErrInvalidExport
+		}
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,73 @@
+package stampede_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+
+	for _, opts := range [][]stampede.Option{
+		nil,
+		{stampede.WithKeyHashing(stampede.KeyHashSHA256)},
+		{stampede.WithStore(stampede.NewMemoryStore(), stampede.GobCodec{})},
+	} {
+		from := stampede.NewCacheKV[string, int](8, time.Minute, time.Hour, opts...)
+		for i, key := range []string{"a", "b", "c"} {
+			i := i
+			from.Set(ctx, key, func() (int, error) { return i, nil })
+		}
+
+		var buf bytes.Buffer
+		n, err := from.Export(ctx, &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, n)
+
+		to := stampede.NewCacheKV[string, int](8, time.Minute, time.Hour, opts...)
+		n, err = to.Import(ctx, &buf)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, n)
+
+		val, err := to.GetFresh(ctx, "b", func() (int, error) { return -1, nil })
+		assert.NoError(t, err)
+		assert.Equal(t, 1, val)
+	}
+}
+
+func TestImportInvalid(t *testing.T) {
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Hour)
+	_, err := c.Import(context.Background(), bytes.NewReader([]byte{5, 'a'}))
+	assert.ErrorIs(t, err, stampede.ErrInvalidExport)
+}
+
+func TestExportDynamicKeys(t *testing.T) {
+	ctx := context.Background()
+
+	c := stampede.NewCache(8, time.Minute, time.Hour)
+	c.Set(ctx, 1, func() (any, error) { return "v", nil })
+	var buf bytes.Buffer
+	_, err := c.Export(ctx, &buf)
+	assert.ErrorIs(t, err, stampede.ErrDynamicKeys)
+	_, err = c.Import(ctx, &buf)
+	assert.ErrorIs(t, err, stampede.ErrDynamicKeys)
+
+	// hashed keys are exported by their digest, whatever their type
+	from := stampede.NewCache(8, time.Minute, time.Hour, stampede.WithKeyHashing(stampede.KeyHashSHA256))
+	from.Set(ctx, 1, func() (any, error) { return "v", nil })
+	n, err := from.Export(ctx, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	to := stampede.NewCache(8, time.Minute, time.Hour, stampede.WithKeyHashing(stampede.KeyHashSHA256))
+	_, err = to.Import(ctx, &buf)
+	assert.NoError(t, err)
+	v, err := to.GetFresh(ctx, 1, func() (any, error) { return "origin", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "v", v)
+}
@@ -0,0 +1,29 @@
+package stampede
+
+import "context"
+
+// fallback returns the last known good value of key, or else the value of the fallback
+// for key, if err is not nil and there is no stale value of ck, see WithLastKnownGood
+// and WithFallback. Fallback values are not cached.
+func (c *Cache[K, V]) fallback(ctx context.Context, key K, ck cacheKey[K], v V, err error) (V, error) {
+	if err == nil || (c.fallbackFn == nil && c.lkg == nil) {
+		return v, err
+	}
+	if val, ok := c.lookup(ck); ok && !val.IsExpired() {
+		return val.Value(), nil
+	}
+	if lv, ok := c.lastKnownGood(ctx, key, ck); ok {
+		return lv, nil
+	}
+	if c.fallbackFn == nil {
+		return v, err
+	}
+	fv, ferr := c.fallbackFn(ctx, key)
+	if ferr != nil {
+		return v, err
+	}
+	if fv, ok := fv.(V); ok {
+		return fv, nil
+	}
+	return v, err
+}
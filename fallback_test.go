@@ -0,0 +1,42 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallback(t *testing.T) {
+	ctx := context.Background()
+	errOrigin := errors.New("origin down")
+	fallback := func(ctx context.Context, key any) (any, error) {
+		if key == "missing" {
+			return nil, errors.New("no fallback")
+		}
+		return -1, nil
+	}
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithFallback(fallback))
+	fail := func() (int, error) { return 0, errOrigin }
+
+	val, err := c.Get(ctx, "k", fail)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, val)
+
+	// fallback values are not cached
+	val, err = c.Get(ctx, "k", func() (int, error) { return 1, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+
+	// stale values win over the fallback
+	c.SoftDelete("k")
+	val, err = c.GetFresh(ctx, "k", fail)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+
+	_, err = c.Get(ctx, "missing", fail)
+	assert.Equal(t, errOrigin, err)
+}
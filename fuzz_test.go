@@ -0,0 +1,97 @@
+package stampede_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/dadav/stampede"
+)
+
+func FuzzStringToHash(f *testing.F) {
+	f.Add("", "")
+	f.Add("a", "b")
+	f.Add("GET", "/users/1")
+	f.Add("\xff\xfe", "\x00")
+
+	f.Fuzz(func(t *testing.T, a, b string) {
+		h := stampede.StringToHash(a, b)
+		if h != stampede.StringToHash(a, b) {
+			t.Fatalf("hash of (%q, %q) is not deterministic", a, b)
+		}
+		if h != stampede.BytesToHash([]byte(a), []byte(b)) {
+			t.Fatalf("string and bytes hash of (%q, %q) differ", a, b)
+		}
+		if len(b) > 0 && h == stampede.StringToHash(a+b[:1], b[1:]) {
+			t.Fatalf("hash of (%q, %q) collides with shifted boundary", a, b)
+		}
+	})
+}
+
+func FuzzDefaultKeyFunc(f *testing.F) {
+	f.Add("/", []byte(nil))
+	f.Add("/Users/1", []byte(`{"id":1}`))
+	f.Add("/a\xffb", []byte("\r\nX-Injected: 1\r\n"))
+	f.Add(strings.Repeat("/x", 4096), bytes.Repeat([]byte("y"), 1<<16))
+
+	key := func(path string, body []byte) (uint64, []byte) {
+		r := &http.Request{
+			Method: http.MethodPost,
+			URL:    &url.URL{Path: path},
+			Body:   io.NopCloser(bytes.NewReader(body)),
+		}
+		k := stampede.DefaultKeyFunc(r)
+		rest, err := io.ReadAll(r.Body)
+		if err != nil {
+			panic(err)
+		}
+		return k, rest
+	}
+
+	f.Fuzz(func(t *testing.T, path string, body []byte) {
+		k, rest := key(path, body)
+		if !bytes.Equal(rest, body) {
+			t.Fatalf("request body was not restored for the next handler")
+		}
+		if k2, _ := key(path, body); k != k2 {
+			t.Fatalf("key of %q is not deterministic", path)
+		}
+		if k2, _ := key(strings.ToUpper(path), body); utf8.ValidString(path) && strings.ToLower(strings.ToUpper(path)) == strings.ToLower(path) && k != k2 {
+			t.Fatalf("key of %q is not case-insensitive", path)
+		}
+		if len(body) > 0 {
+			if k2, _ := key(path+string(body[:1]), body[1:]); k == k2 {
+				t.Fatalf("key of (%q, %q) collides with shifted boundary", path, body)
+			}
+		}
+		if !utf8.ValidString(path) {
+			if k2, _ := key(strings.ToValidUTF8(path, string(utf8.RuneError)), body); k == k2 {
+				t.Fatalf("key of invalid path %q collides with its replacement", path)
+			}
+		}
+	})
+}
+
+func FuzzNormalize(f *testing.F) {
+	f.Add("/Users/?b=2&a=1&utm_source=x")
+	f.Add("/?&&=")
+	f.Add("/\xff/A?%zz=1")
+
+	normalizers := []stampede.Normalizer{
+		stampede.LowerCase(),
+		stampede.StripQuery("utm_source"),
+		stampede.TrimTrailingSlash(),
+		stampede.SortQuery(),
+	}
+
+	f.Fuzz(func(t *testing.T, key string) {
+		once := stampede.Normalize(key, normalizers...)
+		if twice := stampede.Normalize(once, normalizers...); once != twice {
+			t.Fatalf("normalizing %q is not idempotent: %q != %q", key, once, twice)
+		}
+	})
+}
@@ -0,0 +1,202 @@
+package stampede
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GraphQLHandler is like Handler for POST requests of GraphQL queries, e.g. in front of
+// a GraphQL gateway. Only queries with one of the given operation names are cached and
+// coalesced, keyed on the path and the normalized body; all other requests, including
+// mutations, are passed through.
+func GraphQLHandler(cacheSize int, ttl time.Duration, operations ...string) func(next http.Handler) http.Handler {
+	allowed := make(map[string]struct{}, len(operations))
+	for _, op := range operations {
+		allowed[op] = struct{}{}
+	}
+
+	h := stampede(cacheSize, ttl, func(r *http.Request) uint64 {
+		return r.Context().Value(graphQLKey{}).(uint64)
+	})
+
+	return func(next http.Handler) http.Handler {
+		cached := h(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, ok := GraphQLKey(r, allowed)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cached.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), graphQLKey{}, key)))
+		})
+	}
+}
+
+type graphQLKey struct{}
+
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"` // numbers as json.Number
+}
+
+// GraphQLKey returns the cache key of a POST request of a GraphQL query, and false if
+// the request isn't a query of one of the allowed operations. The key is built from
+// the lowercased path, the operation name, the query with its whitespace outside of
+// string literals collapsed and
+// the variables with sorted keys, so formatting doesn't split the cache. Numbers keep
+// their literal, so large integer ids don't collide.
+func GraphQLKey(r *http.Request, allowed map[string]struct{}) (uint64, bool) {
+	if r.Method != http.MethodPost || r.Body == nil {
+		return 0, false
+	}
+	buf, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewBuffer(buf))
+
+	var req graphQLRequest
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil {
+		return 0, false
+	}
+	if _, ok := allowed[req.OperationName]; !ok {
+		return 0, false
+	}
+	if graphQLOperation(req.Query, req.OperationName) != "query" {
+		return 0, false
+	}
+
+	query := collapseGraphQLSpace(req.Query)
+
+	// maps are marshaled with sorted keys
+	variables, _ := json.Marshal(req.Variables)
+	return StringToHash(toLower(r.URL.Path), req.OperationName, query, string(variables)), true
+}
+
+// collapseGraphQLSpace collapses the runs of whitespace of query into single spaces,
+// but keeps string and block string literals as they are.
+func collapseGraphQLSpace(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	space := false
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			space = true
+			continue
+		case space && b.Len() > 0:
+			b.WriteByte(' ')
+		}
+		space = false
+
+		end := i + 1
+		switch {
+		case strings.HasPrefix(query[i:], `"""`):
+			end = len(query)
+			for j := i + 3; j < len(query); j++ {
+				if query[j] == '\\' && strings.HasPrefix(query[j+1:], `"""`) {
+					j += 3
+				} else if strings.HasPrefix(query[j:], `"""`) {
+					end = j + 3
+					break
+				}
+			}
+		case ch == '"':
+			end = len(query)
+			for j := i + 1; j < len(query); j++ {
+				if query[j] == '\\' {
+					j++
+				} else if query[j] == '"' {
+					end = j + 1
+					break
+				}
+			}
+		}
+		b.WriteString(query[i:end])
+		i = end - 1
+	}
+	return b.String()
+}
+
+// graphQLOperation returns the type of the operation selected by name in the GraphQL
+// document query: "query", "mutation" or "subscription", or "" if there is no such
+// operation. An empty name selects the only operation of the document.
+func graphQLOperation(query, name string) string {
+	type operation struct{ typ, name string }
+	var ops []operation
+	var depth, parens int
+	var inDef, named bool // within a definition, expecting the name of the last operation
+	for i := 0; i < len(query); i++ {
+		ch := query[i]
+		switch {
+		case ch == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case ch == '"' && strings.HasPrefix(query[i:], `"""`):
+			end := strings.Index(query[i+3:], `"""`)
+			if end < 0 {
+				return ""
+			}
+			i += end + 5
+		case ch == '"':
+			for i++; i < len(query) && query[i] != '"'; i++ {
+				if query[i] == '\\' {
+					i++
+				}
+			}
+		case ch == '(':
+			parens++
+			named = false
+		case ch == ')':
+			parens--
+		case ch == '@':
+			named = false
+		case ch == '{':
+			if depth == 0 && parens == 0 && !inDef {
+				ops = append(ops, operation{typ: "query"}) // shorthand query
+				inDef = true
+			}
+			depth++
+			named = false
+		case ch == '}':
+			depth--
+			if depth == 0 {
+				inDef = false
+			}
+		case ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z':
+			j := i + 1
+			for j < len(query) && (query[j] == '_' || query[j] >= 'a' && query[j] <= 'z' || query[j] >= 'A' && query[j] <= 'Z' || query[j] >= '0' && query[j] <= '9') {
+				j++
+			}
+			word := query[i:j]
+			i = j - 1
+			if depth > 0 || parens > 0 {
+				continue
+			}
+			switch {
+			case named:
+				ops[len(ops)-1].name = word
+				named = false
+			case !inDef && (word == "query" || word == "mutation" || word == "subscription"):
+				ops = append(ops, operation{typ: word})
+				inDef, named = true, true
+			case !inDef:
+				inDef = true // fragment or type system definition
+			}
+		}
+	}
+
+	for _, op := range ops {
+		if op.name == name || name == "" && len(ops) == 1 {
+			return op.typ
+		}
+	}
+	return ""
+}
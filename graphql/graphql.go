@@ -0,0 +1,120 @@
+// Package graphql caches and coalesces the fetches of GraphQL resolvers, by field and
+// arguments. Concurrent resolves of the same field and arguments run once, within a
+// request and across requests, and results are cached for the freshness configured
+// per field.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dadav/stampede"
+)
+
+// Loader caches the results of resolvers.
+type Loader struct {
+	size     int
+	freshFor time.Duration
+	ttl      time.Duration
+	opts     []stampede.Option
+
+	mu     sync.Mutex
+	fields map[string]*stampede.Cache[uint64, any]
+}
+
+// New returns a Loader caching up to size results per field. Fields not configured with
+// Field are fresh for freshFor and kept for ttl.
+func New(size int, freshFor, ttl time.Duration, opts ...stampede.Option) *Loader {
+	return &Loader{
+		size:     size,
+		freshFor: freshFor,
+		ttl:      ttl,
+		opts:     opts,
+		fields:   make(map[string]*stampede.Cache[uint64, any]),
+	}
+}
+
+// Field configures the freshness of the results of field, e.g. "Query.product". With a
+// freshFor of 0, results are only shared by concurrent resolves and within a request,
+// see WithRequest.
+func (l *Loader) Field(field string, freshFor, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fields[field] = stampede.NewCacheKV[uint64, any](l.size, freshFor, ttl, l.opts...)
+}
+
+func (l *Loader) cache(field string) *stampede.Cache[uint64, any] {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, ok := l.fields[field]
+	if !ok {
+		c = stampede.NewCacheKV[uint64, any](l.size, l.freshFor, l.ttl, l.opts...)
+		l.fields[field] = c
+	}
+	return c
+}
+
+type requestKey struct{}
+
+type request struct {
+	mu      sync.Mutex
+	results map[uint64]any
+}
+
+// WithRequest returns a context for the resolvers of a request, in which every field
+// and arguments are resolved at most once, regardless of their freshness.
+func WithRequest(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestKey{}, &request{results: make(map[uint64]any)})
+}
+
+// Load returns the result of resolving field with args, which must be encodable as
+// JSON, by calling fn if it isn't cached.
+func Load[T any](ctx context.Context, l *Loader, field string, args any, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	b, err := json.Marshal(args)
+	if err != nil {
+		return zero, fmt.Errorf("graphql: args of %s: %w", field, err)
+	}
+	key := stampede.StringToHash(field, string(b))
+
+	req, _ := ctx.Value(requestKey{}).(*request)
+	if req != nil {
+		req.mu.Lock()
+		v, ok := req.results[key]
+		req.mu.Unlock()
+		if ok {
+			return result[T](field, v)
+		}
+	}
+
+	v, err := l.cache(field).GetContext(ctx, key, func(ctx context.Context) (any, error) {
+		return fn(ctx)
+	})
+	if err != nil {
+		return zero, err
+	}
+
+	if req != nil {
+		req.mu.Lock()
+		req.results[key] = v
+		req.mu.Unlock()
+	}
+	return result[T](field, v)
+}
+
+// result returns v as a T, the zero value if fn returned a nil interface, and an error
+// if field was loaded by a Load of another type.
+func result[T any](field string, v any) (T, error) {
+	if v == nil {
+		var zero T
+		return zero, nil
+	}
+	t, ok := v.(T)
+	if !ok {
+		return t, fmt.Errorf("graphql: result of %s is a %T, not a %T", field, v, t)
+	}
+	return t, nil
+}
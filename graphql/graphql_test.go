@@ -0,0 +1,69 @@
+package graphql_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+type productArgs struct {
+	ID string `json:"id"`
+}
+
+func TestLoad(t *testing.T) {
+	l := graphql.New(16, time.Minute, time.Minute)
+	l.Field("Query.stock", 0, 0)
+
+	calls := map[string]int{}
+	resolve := func(field string, result int) func(context.Context) (int, error) {
+		return func(context.Context) (int, error) {
+			calls[field]++
+			return result, nil
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		ctx := graphql.WithRequest(context.Background())
+		for j := 0; j < 2; j++ {
+			v, err := graphql.Load(ctx, l, "Query.product", productArgs{"1"}, resolve("product", 1))
+			assert.NoError(t, err)
+			assert.Equal(t, 1, v)
+
+			v, err = graphql.Load(ctx, l, "Query.stock", productArgs{"1"}, resolve("stock", 5))
+			assert.NoError(t, err)
+			assert.Equal(t, 5, v)
+		}
+	}
+
+	// products are cached across requests, stock only within a request
+	assert.Equal(t, 1, calls["product"])
+	assert.Equal(t, 2, calls["stock"])
+
+	_, err := graphql.Load(context.Background(), l, "Query.product", productArgs{"2"}, resolve("product", 2))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls["product"])
+}
+
+func TestLoadType(t *testing.T) {
+	l := graphql.New(16, time.Minute, time.Minute)
+	ctx := context.Background()
+
+	v, err := graphql.Load(ctx, l, "Query.node", productArgs{"1"}, func(context.Context) (fmt.Stringer, error) {
+		return nil, nil
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	_, err = graphql.Load(ctx, l, "Query.product", productArgs{"1"}, func(context.Context) (int, error) {
+		return 1, nil
+	})
+	assert.NoError(t, err)
+	_, err = graphql.Load(ctx, l, "Query.product", productArgs{"1"}, func(context.Context) (string, error) {
+		return "1", nil
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package stampede_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphQLHandler(t *testing.T) {
+	calls := 0
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	})
+	h := stampede.GraphQLHandler(16, time.Minute, "Product")(app)
+
+	post := func(body string) string {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(body)))
+		return w.Body.String()
+	}
+
+	query := `{"operationName":"Product","query":"query Product($id: ID!) { product(id: $id) { name } }","variables":{"id":"1","locale":"en"}}`
+	assert.Equal(t, query, post(query))
+	// reformatted queries and reordered variables hit the cache
+	post(`{"operationName":"Product","query":"query Product($id: ID!) {\n  product(id: $id) {\n    name\n  }\n}","variables":{"locale":"en","id":"1"}}`)
+	assert.Equal(t, 1, calls)
+
+	post(`{"operationName":"Product","query":"query Product($id: ID!) { product(id: $id) { name } }","variables":{"id":"2"}}`)
+	assert.Equal(t, 2, calls)
+
+	// other operations and mutations are never cached
+	for i := 0; i < 2; i++ {
+		post(`{"operationName":"Search","query":"query Search { search { name } }"}`)
+		post(`{"operationName":"Product","query":"mutation Product { buy }"}`)
+	}
+	assert.Equal(t, 6, calls)
+}
+
+func TestGraphQLKey(t *testing.T) {
+	allowed := map[string]struct{}{"Product": {}, "": {}}
+	key := func(body string) (uint64, bool) {
+		return stampede.GraphQLKey(httptest.NewRequest("POST", "/graphql", strings.NewReader(body)), allowed)
+	}
+
+	// integer ids beyond float64 precision get different keys
+	a, ok := key(`{"operationName":"Product","query":"query Product { p }","variables":{"id":9007199254740993}}`)
+	assert.True(t, ok)
+	b, _ := key(`{"operationName":"Product","query":"query Product { p }","variables":{"id":9007199254740992}}`)
+	assert.NotEqual(t, a, b)
+
+	// the operation selected by its name decides, not the first one of the document
+	_, ok = key(`{"operationName":"Product","query":"query Other { p } mutation Product { buy }"}`)
+	assert.False(t, ok)
+	_, ok = key(`{"operationName":"Product","query":"mutation Buy { buy } query Product($id: ID! @a) { p(id: \"{\") }"}`)
+	assert.True(t, ok)
+	_, ok = key(`{"operationName":"Product","query":"# query Product\nmutation Product { buy }"}`)
+	assert.False(t, ok)
+	_, ok = key(`{"query":"{ p }"}`)
+	assert.True(t, ok)
+	_, ok = key(`{"query":"fragment F on P { name } subscription { p { ...F } }"}`)
+	assert.False(t, ok)
+	_, ok = key(`{"operationName":"Product","query":"query Other { p }"}`)
+	assert.False(t, ok)
+
+	// whitespace is collapsed, but not within string literals
+	a, _ = key(`{"query":"{ a(s: \"x  y\") }"}`)
+	b, _ = key(`{"query":"{ a(s: \"x y\") }"}`)
+	assert.NotEqual(t, a, b)
+	a, _ = key(`{"query":"{ a(s: \"\"\"x\n  y\"\"\") }"}`)
+	b, _ = key(`{"query":"{ a(s: \"\"\"x\n y\"\"\") }"}`)
+	assert.NotEqual(t, a, b)
+	a, _ = key(`{"query":"{\n  a(s: \"x\\\"  y\")\n}"}`)
+	b, _ = key(`{"query":"{ a(s: \"x\\\"  y\") }"}`)
+	assert.Equal(t, a, b)
+}
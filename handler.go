@@ -0,0 +1,113 @@
+package stampede
+
+import (
+	"net/http"
+	"time"
+)
+
+// HandlerOption configures the middleware of NewHandler.
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	keyFunc func(r *http.Request) uint64
+	paths   []string
+	metrics *RouteMetrics
+	spooler *spooler
+	purger  *Purger
+
+	serveStale bool
+	cb         func(bool, http.ResponseWriter, *http.Request) error // see HandlerWithKeyAndCb
+}
+
+// WithPaths caches only the requests of the given paths, compared case insensitively.
+// All paths are cached without any.
+func WithPaths(paths ...string) HandlerOption {
+	return func(o *handlerOptions) {
+		o.paths = append(o.paths, paths...)
+	}
+}
+
+// WithKeyFunc keys the cached responses with keyFunc, DefaultKeyFunc by default.
+func WithKeyFunc(keyFunc func(r *http.Request) uint64) HandlerOption {
+	return func(o *handlerOptions) {
+		o.keyFunc = keyFunc
+	}
+}
+
+// WithRouteMetrics counts the requests served by the middleware in m.
+func WithRouteMetrics(m *RouteMetrics) HandlerOption {
+	return func(o *handlerOptions) {
+		o.metrics = m
+	}
+}
+
+// WithSpool keeps the bodies of responses larger than threshold bytes in temporary files
+// in dir instead of memory, and streams them to the clients. The files are deleted as
+// soon as they are created and only kept open, so the space is returned to the file
+// system once their responses are evicted, or when the process exits. Responses whose
+// body can't be spooled, e.g. with the disk full, are not cached.
+func WithSpool(dir string, threshold int) HandlerOption {
+	return func(o *handlerOptions) {
+		o.spooler = &spooler{dir: dir, threshold: threshold}
+	}
+}
+
+// WithPurger makes p purge the responses cached by the middleware, see Purger.
+func WithPurger(p *Purger) HandlerOption {
+	return func(o *handlerOptions) {
+		o.purger = p
+	}
+}
+
+// WithServeStale serves stale responses right away, while refreshing them in the
+// background, instead of waiting for the origin. Without it, only fresh responses are
+// served.
+func WithServeStale() HandlerOption {
+	return func(o *handlerOptions) {
+		o.serveStale = true
+	}
+}
+
+// withCallback calls cb before the origin is called, with false, and before cached
+// responses are written, with true.
+func withCallback(cb func(bool, http.ResponseWriter, *http.Request) error) HandlerOption {
+	return func(o *handlerOptions) {
+		o.cb = cb
+	}
+}
+
+// NewHandler returns a middleware caching and coalescing the responses of its next
+// handler for ttl, like Handler, configured by opts.
+func NewHandler(cacheSize int, ttl time.Duration, opts ...HandlerOption) func(next http.Handler) http.Handler {
+	o := handlerOptions{keyFunc: DefaultKeyFunc}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	cache := NewCacheKV[uint64, responseValue](cacheSize, ttl, ttl*2)
+	if o.purger != nil {
+		o.purger.add(cache)
+	}
+	return dispatch(o.paths, stampedeCache(cache, o))
+}
+
+// dispatch returns a middleware passing the requests of paths, or all requests without
+// paths, to the cached handler built by h, and the others to next.
+func dispatch(paths []string, h func(next http.Handler) http.Handler) func(next http.Handler) http.Handler {
+	// mapping of url paths that are cacheable by the stampede handler
+	pathMap := map[string]struct{}{}
+	for _, path := range paths {
+		pathMap[toLower(path)] = struct{}{}
+	}
+
+	return func(next http.Handler) http.Handler {
+		cached := h(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := pathMap[toLower(r.URL.Path)]; ok || len(pathMap) == 0 {
+				cached.ServeHTTP(w, r)
+			} else {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package stampede_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHandler(t *testing.T) {
+	dir := t.TempDir()
+	body := bytes.Repeat([]byte("0123456789"), 1000)
+
+	var calls int32
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Surrogate-Key", "reports")
+		w.Write(body)
+	})
+	metrics := stampede.NewRouteMetrics(func(r *http.Request) string { return r.URL.Path })
+	purger := stampede.NewPurger()
+	h := stampede.NewHandler(16, time.Minute,
+		stampede.WithPaths("/report"),
+		stampede.WithRouteMetrics(metrics),
+		stampede.WithSpool(dir, 1024),
+		stampede.WithPurger(purger),
+	)(app)
+
+	get := func(path string) []byte {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		b, _ := io.ReadAll(w.Result().Body)
+		return b
+	}
+
+	assert.Equal(t, body, get("/report"))
+	assert.Equal(t, body, get("/report"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// paths not listed are passed through
+	get("/other")
+	get("/other")
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	assert.Equal(t, 1, purger.Purge("reports"))
+	assert.Equal(t, body, get("/report"))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&calls))
+
+	stats := metrics.Routes()["/report"]
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(2), stats.Origin)
+	assert.NotContains(t, metrics.Routes(), "/other")
+
+	// spooled files are deleted right away, and only kept open
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
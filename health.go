@@ -0,0 +1,49 @@
+package stampede
+
+import (
+	"context"
+	"errors"
+)
+
+// Pinger is implemented by stores and lockers that can check their connection, see
+// Healthy.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// healthKey is read from stores that don't implement Pinger.
+const healthKey = "stampede:health"
+
+// Healthy returns an error if the cache can't serve its callers well, e.g. for readiness
+// probes: if its store or locker can't be reached, or if more of the recent fetches
+// failed than WithHealthThreshold allows. Stores and lockers implementing Pinger are
+// pinged, other stores are probed with a read, and other lockers are not checked.
+func (c *Cache[K, V]) Healthy(ctx context.Context) error {
+	var errs []error
+	if c.store != nil {
+		if err := ping(ctx, c.store); err != nil {
+			errs = append(errs, c.errorf("store: %w", err))
+		}
+	}
+	if p, ok := c.locker.(Pinger); ok {
+		if err := p.Ping(ctx); err != nil {
+			errs = append(errs, c.errorf("locker: %w", err))
+		}
+	}
+	if c.maxErrorRate > 0 {
+		if rate, ok := c.latency.errorRate(); ok && rate > c.maxErrorRate {
+			errs = append(errs, c.errorf("%.0f%% of recent fetches failed", rate*100))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func ping(ctx context.Context, s Store) error {
+	if p, ok := s.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	if _, err := s.Get(ctx, healthKey); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+type downStore struct{ stampede.MemoryStore }
+
+func (*downStore) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+type pingLocker struct{ err error }
+
+func (l *pingLocker) Lock(ctx context.Context, key string) (func(), error) {
+	return func() {}, nil
+}
+
+func (l *pingLocker) Ping(ctx context.Context) error { return l.err }
+
+func TestHealthy(t *testing.T) {
+	ctx := context.Background()
+
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithStore(stampede.NewMemoryStore(), stampede.JSONCodec{}))
+	assert.NoError(t, c.Healthy(ctx))
+
+	locker := &pingLocker{err: errors.New("lock service unavailable")}
+	c = stampede.NewCacheKV[string, int](8, time.Minute, time.Minute,
+		stampede.WithStore(&downStore{}, stampede.JSONCodec{}), stampede.WithLocker(locker))
+	err := c.Healthy(ctx)
+	assert.ErrorContains(t, err, "store: connection refused")
+	assert.ErrorContains(t, err, "locker: lock service unavailable")
+}
+
+func TestHealthThreshold(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[int, int](64, time.Minute, time.Minute, stampede.WithHealthThreshold(0.5))
+	defer c.Close()
+
+	for i := 0; i < 20; i++ {
+		c.Get(ctx, i, func() (int, error) { return 0, errors.New("origin down") })
+	}
+	assert.ErrorContains(t, c.Healthy(ctx), "100% of recent fetches failed")
+
+	for i := 0; i < 128; i++ {
+		c.Get(ctx, 100+i, func() (int, error) { return 1, nil })
+	}
+	assert.NoError(t, c.Healthy(ctx))
+}
@@ -0,0 +1,63 @@
+package stampede
+
+import (
+	"context"
+	"time"
+)
+
+// hedge runs fn, and runs it a second time, or the secondary loader if there is one,
+// if it didn't return within the hedging delay, returning the first success of either,
+// see WithHedging. Each attempt gets its own entry metadata, and the metadata of the
+// returned attempt is kept.
+func (c *Cache[K, V]) hedge(ctx context.Context, key K, fn FetchFunc[V]) (V, error) {
+	if c.hedgeAfter <= 0 {
+		return c.fetch(ctx, key, fn)
+	}
+
+	type attempt struct {
+		v    V
+		err  error
+		meta *entryMeta
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	parent := metaFrom(ctx)
+	attempts := make(chan attempt, 2)
+	run := func(fn FetchFunc[V]) func() {
+		return func() {
+			ctx, m := withMeta(ctx)
+			if parent != nil {
+				*m = *parent
+			}
+			v, err := c.fetch(ctx, key, fn)
+			attempts <- attempt{v: v, err: err, meta: m}
+		}
+	}
+	hedged := fn
+	if c.secondary != nil {
+		hedged = c.secondaryFunc(key)
+	}
+
+	c.goBackground(run(fn))
+	timer := time.NewTimer(c.hedgeAfter)
+	defer timer.Stop()
+
+	pending := 1
+	var a attempt
+	for pending > 0 {
+		select {
+		case a = <-attempts:
+			pending--
+			if a.err == nil || a.err == Unchanged {
+				pending = 0
+			}
+		case <-timer.C:
+			c.goBackground(run(hedged))
+			pending++
+		}
+	}
+	if parent != nil {
+		*parent = *a.meta
+	}
+	return a.v, a.err
+}
@@ -0,0 +1,40 @@
+package stampede_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedging(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithHedging(10*time.Millisecond))
+	defer c.Close()
+
+	var calls int32
+	fetch := func(ctx context.Context) (int, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			// the first attempt hangs until it is canceled
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return int(n), nil
+	}
+
+	start := time.Now()
+	val, err := c.GetContext(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, val)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// fast fetches are not hedged
+	val, err = c.GetContext(ctx, "other", func(ctx context.Context) (int, error) { return 1, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
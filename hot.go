@@ -0,0 +1,165 @@
+package stampede
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// hotPublishInterval is how often caches merge their hits into the hot index of the
+// store, see WithStoreWarm.
+const hotPublishInterval = time.Minute
+
+// maxHotKeys bounds the number of keys whose hits are counted between two publishes.
+const maxHotKeys = 4096
+
+// hotKeys counts the hits of keys since the last publish, by cache key, as keys may not
+// be usable as map keys, e.g. Keyer keys.
+type hotKeys[K comparable] struct {
+	mu   sync.Mutex
+	hits map[cacheKey[K]]hotKey[K]
+}
+
+func (h *hotKeys[K]) hit(key K, ck cacheKey[K]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.hits == nil {
+		h.hits = make(map[cacheKey[K]]hotKey[K])
+	}
+	if hk, ok := h.hits[ck]; ok || len(h.hits) < maxHotKeys {
+		hk.Key = key
+		hk.Hits++
+		h.hits[ck] = hk
+	}
+}
+
+func (h *hotKeys[K]) take() map[cacheKey[K]]hotKey[K] {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hits := h.hits
+	h.hits = nil
+	return hits
+}
+
+// hotKey is an entry of the hot index in the store.
+type hotKey[K comparable] struct {
+	Key  K
+	Hits int64
+}
+
+// hotIndexKey returns the key of the hot index in the store.
+func (c *Cache[K, V]) hotIndexKey() string {
+	skey := "stampede:hot"
+	if c.name != "" {
+		skey += ":" + c.name
+	}
+	if c.schema != 0 {
+		skey = "v" + strconv.FormatUint(uint64(c.schema), 10) + ":" + skey
+	}
+	return skey
+}
+
+// hotIndex returns the keys of the hot index, hottest first.
+func (c *Cache[K, V]) hotIndex(ctx context.Context) []hotKey[K] {
+	b, err := c.store.Get(ctx, c.hotIndexKey())
+	if err != nil {
+		return nil
+	}
+	var index []hotKey[K]
+	if c.codec.Unmarshal(b, &index) != nil {
+		return nil
+	}
+	return index
+}
+
+// publishHot merges the hits counted since the last publish into the hot index. Hits
+// already in the index are halved, so the index follows shifts in traffic. Concurrent
+// publishes of several instances may lose each other's hits.
+func (c *Cache[K, V]) publishHot(ctx context.Context) {
+	hits := c.hot.take()
+	if len(hits) == 0 {
+		return
+	}
+	for _, h := range c.hotIndex(ctx) {
+		ck := c.cacheKey(h.Key)
+		hk := hits[ck]
+		hk.Key = h.Key
+		hk.Hits += h.Hits / 2
+		hits[ck] = hk
+	}
+
+	index := make([]hotKey[K], 0, len(hits))
+	for _, hk := range hits {
+		if hk.Hits > 0 {
+			index = append(index, hk)
+		}
+	}
+	sort.Slice(index, func(i, j int) bool { return index[i].Hits > index[j].Hits })
+	if len(index) > 4*c.warmKeys {
+		index = index[:4*c.warmKeys]
+	}
+	if b, err := c.codec.Marshal(index); err == nil {
+		c.store.Set(ctx, c.hotIndexKey(), b, 0)
+	}
+}
+
+// warmFromStore loads the values of the hottest keys of the hot index from the store,
+// without reaching the origin, until ctx is done.
+func (c *Cache[K, V]) warmFromStore(ctx context.Context) {
+	index := c.hotIndex(ctx)
+	if len(index) > c.warmKeys {
+		index = index[:c.warmKeys]
+	}
+	for _, h := range index {
+		if ctx.Err() != nil {
+			return
+		}
+		ck := c.cacheKey(h.Key)
+		skey := c.storeKey(h.Key, ck)
+
+		var v V
+		var bestBefore, expiry time.Time
+		if c.storeEnvelope {
+			sv, env, _ := c.storedEnvelope(ctx, skey)
+			if env == nil {
+				continue
+			}
+			v, bestBefore, expiry = sv, env.BestBefore, env.Expiry
+		} else {
+			sv, ok := c.stored(ctx, skey)
+			if !ok {
+				continue
+			}
+			v = sv
+			bestBefore, expiry = c.expiry(ctx, v)
+		}
+
+		entry := c.entry(h.Key, ck, v, bestBefore, expiry)
+		c.mu.Lock()
+		if _, ok := c.values.Peek(ck); !ok {
+			c.add(ck, entry)
+		}
+		c.mu.Unlock()
+	}
+}
+
+// hotPublisher publishes the hits of the cache every hotPublishInterval, and once more
+// when the cache is closed.
+func (c *Cache[K, V]) hotPublisher() {
+	c.goBackground(func() {
+		ticker := time.NewTicker(hotPublishInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				c.publishHot(c.ctx)
+			case <-c.ctx.Done():
+				c.publishHot(context.Background())
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+// warmed waits for keys to be loaded from the store.
+func warmed[K comparable](t *testing.T, c *stampede.Cache[K, int], keys ...K) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		for _, key := range keys {
+			if _, ok := c.Peek(key); !ok {
+				return false
+			}
+		}
+		return true
+	}, time.Second, time.Millisecond)
+}
+
+func TestStoreWarm(t *testing.T) {
+	ctx := context.Background()
+	store := stampede.NewMemoryStore()
+	opts := []stampede.Option{stampede.WithStore(store, stampede.JSONCodec{}), stampede.WithStoreWarm(2)}
+
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, opts...)
+	for key, n := range map[string]int{"a": 3, "b": 2, "c": 1} {
+		for i := 0; i < n; i++ {
+			c.Get(ctx, key, func() (int, error) { return n, nil })
+		}
+	}
+	// closing publishes the hits to the store
+	c.Close()
+
+	c = stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, opts...)
+	defer c.Close()
+	warmed(t, c, "a", "b")
+	for key, want := range map[string]int{"a": 3, "b": 2} {
+		v, err := c.GetFresh(ctx, key, func() (int, error) { return 0, errors.New("origin called") })
+		assert.NoError(t, err)
+		assert.Equal(t, want, v)
+	}
+	_, ok := c.Peek("c")
+	assert.False(t, ok)
+}
+
+type tenantKey struct {
+	Tenant string
+	ID     *int
+}
+
+func (k tenantKey) CacheKey() string {
+	return fmt.Sprintf("%s/%d", k.Tenant, *k.ID)
+}
+
+func TestStoreWarmKeyer(t *testing.T) {
+	ctx := context.Background()
+	store := stampede.NewMemoryStore()
+	opts := []stampede.Option{stampede.WithStore(store, stampede.JSONCodec{}), stampede.WithStoreWarm(2)}
+	id := func(n int) *int { return &n }
+
+	// distinct pointers with the same CacheKey are hits of the same key
+	c := stampede.NewCacheKV[tenantKey, int](8, time.Minute, time.Minute, opts...)
+	for i := 0; i < 3; i++ {
+		c.Get(ctx, tenantKey{Tenant: "a", ID: id(1)}, func() (int, error) { return 1, nil })
+	}
+	c.Close()
+
+	c = stampede.NewCacheKV[tenantKey, int](8, time.Minute, time.Minute, opts...)
+	defer c.Close()
+	warmed(t, c, tenantKey{Tenant: "a", ID: id(1)})
+	v, err := c.GetFresh(ctx, tenantKey{Tenant: "a", ID: id(1)}, func() (int, error) { return 0, errors.New("origin called") })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	// keys that aren't comparable don't panic
+	dynamic := stampede.NewCache(8, time.Minute, time.Minute, opts...)
+	_, err = dynamic.Get(ctx, userQuery{Tenant: "a", IDs: []int{1}}, func() (any, error) { return "v", nil })
+	assert.NoError(t, err)
+	assert.NoError(t, dynamic.Close())
+}
+
+func TestStoreWarmSlow(t *testing.T) {
+	// a store that doesn't answer holds up neither the creation nor the closing of
+	// the cache
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute,
+			stampede.WithStore(slowStore{stampede.NewMemoryStore()}, stampede.JSONCodec{}), stampede.WithStoreWarm(2))
+		c.Close()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("warming from the store blocked")
+	}
+}
@@ -2,9 +2,12 @@ package stampede
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -21,29 +24,25 @@ var stripOutHeaders = []string{
 }
 
 func Handler(cacheSize int, ttl time.Duration, paths ...string) func(next http.Handler) http.Handler {
-	defaultKeyFunc := func(r *http.Request) uint64 {
-		// Read the request payload, and then setup buffer for future reader
-		var buf []byte
-		if r.Body != nil {
-			buf, _ = io.ReadAll(r.Body)
-			r.Body = io.NopCloser(bytes.NewBuffer(buf))
-		}
+	return HandlerWithKey(cacheSize, ttl, DefaultKeyFunc, paths...)
+}
 
-		// Prepare cache key based on request URL path and the request data payload.
-		key := BytesToHash([]byte(strings.ToLower(r.URL.Path)), buf)
-		return key
+// DefaultKeyFunc is the cache key function used by Handler. The key is built from
+// the lowercased request URL path and the request body.
+func DefaultKeyFunc(r *http.Request) uint64 {
+	// Read the request payload, and then setup buffer for future reader
+	var buf []byte
+	if r.Body != nil {
+		buf, _ = io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewBuffer(buf))
 	}
 
-	return HandlerWithKey(cacheSize, ttl, defaultKeyFunc, paths...)
+	// Prepare cache key based on request URL path and the request data payload.
+	key := BytesToHash([]byte(toLower(r.URL.Path)), buf)
+	return key
 }
 
 func HandlerWithKey(cacheSize int, ttl time.Duration, keyFunc func(r *http.Request) uint64, paths ...string) func(next http.Handler) http.Handler {
-	// mapping of url paths that are cacheable by the stampede handler
-	pathMap := map[string]struct{}{}
-	for _, path := range paths {
-		pathMap[strings.ToLower(path)] = struct{}{}
-	}
-
 	// Stampede handler with set ttl for how long content is fresh.
 	// Requests sent to this handler will be coalesced and in scenarios
 	// where there is a "stampede" or parallel requests for the same
@@ -52,34 +51,10 @@ func HandlerWithKey(cacheSize int, ttl time.Duration, keyFunc func(r *http.Reque
 	// the first request. The content thereafter will be cached for up to
 	// ttl time for subsequent requests for further caching.
 	h := stampede(cacheSize, ttl, keyFunc)
-
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Cache all paths, as whitelist has not been provided
-			if len(pathMap) == 0 {
-				h(next).ServeHTTP(w, r)
-				return
-			}
-
-			// Match specific whitelist of paths
-			if _, ok := pathMap[strings.ToLower(r.URL.Path)]; ok {
-				// stampede-cache the matching path
-				h(next).ServeHTTP(w, r)
-			} else {
-				// no caching
-				next.ServeHTTP(w, r)
-			}
-		})
-	}
+	return dispatch(paths, h)
 }
 
 func HandlerWithKeyAndCb(cacheSize int, ttl time.Duration, keyFunc func(r *http.Request) uint64, cbFunc func(bool, http.ResponseWriter, *http.Request) error, paths ...string) func(next http.Handler) http.Handler {
-	// mapping of url paths that are cacheable by the stampede handler
-	pathMap := map[string]struct{}{}
-	for _, path := range paths {
-		pathMap[strings.ToLower(path)] = struct{}{}
-	}
-
 	// Stampede handler with set ttl for how long content is fresh.
 	// Requests sent to this handler will be coalesced and in scenarios
 	// where there is a "stampede" or parallel requests for the same
@@ -87,63 +62,104 @@ func HandlerWithKeyAndCb(cacheSize int, ttl time.Duration, keyFunc func(r *http.
 	// executes, and the remaining handlers will use the response from
 	// the first request. The content thereafter will be cached for up to
 	// ttl time for subsequent requests for further caching.
-	h := stampedeWithCb(cacheSize, ttl, keyFunc, cbFunc)
+	return NewHandler(cacheSize, ttl, WithKeyFunc(keyFunc), WithPaths(paths...), withCallback(cbFunc))
+}
+
+func stampede(cacheSize int, ttl time.Duration, keyFunc func(r *http.Request) uint64) func(next http.Handler) http.Handler {
+	return stampedeCache(NewCacheKV[uint64, responseValue](cacheSize, ttl, ttl*2), handlerOptions{keyFunc: keyFunc})
+}
 
+func stampedeCache(cache *Cache[uint64, responseValue], o handlerOptions) func(next http.Handler) http.Handler {
+	keyFunc, metrics, spooler, cb := o.keyFunc, o.metrics, o.spooler, o.cb
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Cache all paths, as whitelist has not been provided
-			if len(pathMap) == 0 {
-				h(next).ServeHTTP(w, r)
+			// never buffer websockets and event streams
+			if isStreaming(r) {
+				next.ServeHTTP(w, r)
 				return
 			}
 
-			// Match specific whitelist of paths
-			if _, ok := pathMap[strings.ToLower(r.URL.Path)]; ok {
-				// stampede-cache the matching path
-				h(next).ServeHTTP(w, r)
-			} else {
-				// no caching
-				next.ServeHTTP(w, r)
-			}
-		})
-	}
-}
-
-func stampedeWithCb(cacheSize int, ttl time.Duration, keyFunc func(r *http.Request) uint64, cbFunc func(bool, http.ResponseWriter, *http.Request) error) func(next http.Handler) http.Handler {
-	cache := NewCacheKV[uint64, responseValue](cacheSize, ttl, ttl*2)
-
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// cache key for the request
 			key := keyFunc(r)
 
 			// mark the request that actually processes the response
 			first := false
-
-			// process request (single flight)
-			respVal, err := cache.GetFresh(r.Context(), key, func() (responseValue, error) {
-				cbFunc(false, w, r)
-				first = true
-				buf := bytes.NewBuffer(nil)
+			ranged := r.Header.Get("Range") != ""
+			wait := noWait
+			start := time.Now()
+			fetched := false
+
+			// origin serves r with next into w, and returns the response to cache
+			origin := func(w http.ResponseWriter, r *http.Request, discard bool) (responseValue, func(), error) {
+				buf := spooler.spool()
 				ww := &responseWriter{ResponseWriter: w, tee: buf}
-
-				next.ServeHTTP(ww, r)
+				wait, err := serveOrigin(next, ww, r, discard)
+				if err != nil {
+					return responseValue{}, wait, err
+				}
 
 				val := responseValue{
+					created: time.Now(),
 					headers: ww.Header(),
 					status:  ww.Status(),
-					body:    buf.Bytes(),
 
 					// the handler may not write header and body in some logic,
 					// while writing only the body, an attempt is made to write the default header (http.StatusOK)
 					skip: ww.IsHeaderWrong(),
 				}
-				return val, nil
+				if err := buf.body(&val); err != nil || ww.teeErr != nil {
+					return responseValue{}, wait, errSpool
+				}
+				return val, wait, nil
+			}
+
+			// serve stale responses right away, while refreshing them in the background
+			// with a copy of the request, which outlives it
+			if o.serveStale {
+				if respVal, ok := staleResponse(cache, key); ok {
+					metrics.observe(r, routeStale)
+					ctx := DetachContext()(r.Context())
+					refresh := fullRequest(r.WithContext(ctx))
+					cache.SetAsync(ctx, key, func() (responseValue, error) {
+						val, _, err := origin(&discardResponseWriter{header: http.Header{}}, refresh, true)
+						return val, err
+					})
+					writeCached(w, r, respVal, cache, cb)
+					return
+				}
+			}
+
+			// process request (single flight)
+			respVal, err := cache.GetFresh(r.Context(), key, func() (responseValue, error) {
+				metrics.observe(r, routeOrigin)
+				fetched = true
+				if cb != nil {
+					cb(false, w, r)
+				}
+
+				// fetch and cache the full body for range requests, the range is
+				// served from the cached body below
+				if ranged {
+					val, _, err := origin(&discardResponseWriter{header: http.Header{}}, fullRequest(r), true)
+					return val, err
+				}
+				first = true
+				val, fetchWait, err := origin(w, r, false)
+				wait = fetchWait
+				return val, err
 			})
 
 			// the first request to trigger the fetch should return as it's already
-			// responded to the client
+			// responded to the client, or is still streaming to it
 			if first {
+				wait()
+				return
+			}
+
+			// the response turned out to be a stream, or couldn't be spooled, which isn't
+			// shared
+			if err == errStreaming || err == errSpool {
+				next.ServeHTTP(w, r)
 				return
 			}
 
@@ -157,106 +173,176 @@ func stampedeWithCb(cacheSize int, ttl time.Duration, keyFunc func(r *http.Reque
 				return
 			}
 
-			header := w.Header()
-
-		nextHeader:
-			for k := range respVal.headers {
-				for _, match := range stripOutHeaders {
-					// Prevent any header in stripOutHeaders to override the current
-					// value of that header. This is important when you don't want a
-					// header to affect all subsequent requests (for instance, when
-					// working with several CORS domains, you don't want the first domain
-					// to be recorded an to be printed in all responses)
-					if match == k {
-						continue nextHeader
-					}
-				}
-				header[k] = respVal.headers[k]
+			switch freshFor, _ := cache.TTL(); {
+			case fetched:
+				// counted as origin call
+			case respVal.created.After(start):
+				metrics.observe(r, routeCoalesced)
+			case time.Since(respVal.created) > freshFor:
+				// expired responses, served by read-only caches
+				metrics.observe(r, routeStale)
+			default:
+				metrics.observe(r, routeHit)
 			}
-
-			cbFunc(true, w, r)
-			w.WriteHeader(respVal.status)
-			w.Write(respVal.body)
+			writeCached(w, r, respVal, cache, cb)
 		})
 	}
 }
 
-func stampede(cacheSize int, ttl time.Duration, keyFunc func(r *http.Request) uint64) func(next http.Handler) http.Handler {
-	cache := NewCacheKV[uint64, responseValue](cacheSize, ttl, ttl*2)
-
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// cache key for the request
-			key := keyFunc(r)
+// staleResponse returns the cached response of key if it is stale, but not expired yet.
+func staleResponse(cache *Cache[uint64, responseValue], key uint64) (responseValue, bool) {
+	if cache.Disabled() {
+		return responseValue{}, false
+	}
+	key = cache.normalizeKey(key)
+	val, ok := cache.lookup(cache.cacheKey(key))
+	if !ok || val.IsFresh() || val.IsExpired() || val.Value().skip {
+		return responseValue{}, false
+	}
+	cache.record(key, outcomeStale)
+	return cache.read(val.Value()), true
+}
 
-			// mark the request that actually processes the response
-			first := false
+// writeCached writes a response served from cache, with the headers of the origin, and
+// calls cb, if any, before the response is written.
+func writeCached(w http.ResponseWriter, r *http.Request, val responseValue, cache *Cache[uint64, responseValue], cb func(bool, http.ResponseWriter, *http.Request) error) {
+	header := w.Header()
+
+nextHeader:
+	for k := range val.headers {
+		for _, match := range stripOutHeaders {
+			// Prevent any header in stripOutHeaders to override the current
+			// value of that header. This is important when you don't want a
+			// header to affect all subsequent requests (for instance, when
+			// working with several CORS domains, you don't want the first domain
+			// to be recorded an to be printed in all responses)
+			if match == k {
+				continue nextHeader
+			}
+		}
+		header[k] = val.headers[k]
+	}
 
-			// process request (single flight)
-			respVal, err := cache.GetFresh(r.Context(), key, func() (responseValue, error) {
-				first = true
-				buf := bytes.NewBuffer(nil)
-				ww := &responseWriter{ResponseWriter: w, tee: buf}
+	if cb != nil {
+		cb(true, w, r)
+	}
+	freshFor, _ := cache.TTL()
+	writeResponse(w, r, val, freshFor)
+}
 
-				next.ServeHTTP(ww, r)
+// writeResponse writes a cached response, or the requested range of it, with its age
+// and expiry computed from the time it was fetched.
+func writeResponse(w http.ResponseWriter, r *http.Request, val responseValue, freshFor time.Duration) {
+	age := time.Since(val.created)
+	expires := val.created.Add(freshFor)
+	w.Header().Set("Age", strconv.Itoa(int(age/time.Second)))
+	w.Header().Set("Expires", expires.UTC().Format(http.TimeFormat))
+	if age > freshFor {
+		w.Header().Add("Warning", `110 - "Response is Stale"`)
+	}
 
-				val := responseValue{
-					headers: ww.Header(),
-					status:  ww.Status(),
-					body:    buf.Bytes(),
+	if val.status == http.StatusOK && r.Header.Get("Range") != "" {
+		http.ServeContent(w, r, "", time.Time{}, val.reader())
+		return
+	}
+	w.WriteHeader(val.status)
+	if val.spooled != nil {
+		io.Copy(w, val.reader())
+		return
+	}
+	w.Write(val.body)
+}
 
-					// the handler may not write header and body in some logic,
-					// while writing only the body, an attempt is made to write the default header (http.StatusOK)
-					skip: ww.IsHeaderWrong(),
-				}
-				return val, nil
-			})
+// fullRequest returns a copy of r without its range.
+func fullRequest(r *http.Request) *http.Request {
+	r = r.Clone(r.Context())
+	r.Header.Del("Range")
+	r.Header.Del("If-Range")
+	return r
+}
 
-			// the first request to trigger the fetch should return as it's already
-			// responded to the client
-			if first {
-				return
-			}
+// discardResponseWriter is the writer of responses only written to the cache.
+type discardResponseWriter struct {
+	header http.Header
+}
 
-			// handle response for other listeners
-			if err != nil {
-				// TODO: perhaps just log error and execute standard handler..?
-				panic(fmt.Sprintf("stampede: fail to get value, %v", err))
-			}
+func (w *discardResponseWriter) Header() http.Header         { return w.header }
+func (w *discardResponseWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardResponseWriter) WriteHeader(int)             {}
+
+var errStreaming = errors.New("stampede: streaming response")
+
+// serveOrigin serves r with next into ww, for a fetch of the cache. It returns
+// errStreaming as soon as the response turns out to be an event stream, so the callers
+// waiting on the fetch don't wait for the end of the stream. The returned wait function
+// returns once next returned, and repanics its panic. Streams to a discarded writer are
+// canceled instead.
+func serveOrigin(next http.Handler, ww *responseWriter, r *http.Request, discard bool) (wait func(), err error) {
+	ctx, cancel := context.WithCancel(r.Context())
+	ww.streamed = make(chan struct{})
+	done := make(chan struct{})
+	var panicked any
+	go func() {
+		defer close(done)
+		defer func() { panicked = recover() }()
+		next.ServeHTTP(ww, r.WithContext(ctx))
+	}()
+	wait = func() {
+		<-done
+		cancel()
+		if panicked != nil {
+			panic(panicked)
+		}
+	}
 
-			if respVal.skip {
-				return
-			}
+	select {
+	case <-done:
+		wait()
+		if ww.streaming {
+			return noWait, errStreaming
+		}
+		return noWait, nil
+	case <-ww.streamed:
+		if discard {
+			cancel()
+			return noWait, errStreaming
+		}
+		return wait, errStreaming
+	}
+}
 
-			header := w.Header()
-
-		nextHeader:
-			for k := range respVal.headers {
-				for _, match := range stripOutHeaders {
-					// Prevent any header in stripOutHeaders to override the current
-					// value of that header. This is important when you don't want a
-					// header to affect all subsequent requests (for instance, when
-					// working with several CORS domains, you don't want the first domain
-					// to be recorded an to be printed in all responses)
-					if match == k {
-						continue nextHeader
-					}
-				}
-				header[k] = respVal.headers[k]
-			}
+func noWait() {}
 
-			w.WriteHeader(respVal.status)
-			w.Write(respVal.body)
-		})
-	}
+// isStreaming reports whether r is a websocket upgrade or asks for an event stream.
+func isStreaming(r *http.Request) bool {
+	return r.Header.Get("Upgrade") != "" || strings.Contains(r.Header.Get("Accept"), "text/event-stream")
 }
 
 // responseValue is response payload we will be coalescing
 type responseValue struct {
+	created time.Time
 	headers http.Header
 	status  int
 	body    []byte
 	skip    bool
+
+	// spooled holds bodies too large for memory instead of body, see HandlerWithSpool
+	spooled io.ReaderAt
+	size    int64
+}
+
+// Tags returns the tags of the response set by the origin in the Surrogate-Key (space
+// separated) and Cache-Tag (comma separated) headers, see Purger.
+func (v responseValue) Tags() []string {
+	tags := strings.Fields(strings.Join(v.headers.Values("Surrogate-Key"), " "))
+	for _, h := range v.headers.Values("Cache-Tag") {
+		for _, tag := range strings.Split(h, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
 }
 
 type responseWriter struct {
@@ -265,12 +351,23 @@ type responseWriter struct {
 	code        int
 	bytes       int
 	tee         io.Writer
+	teeErr      error // the first error writing to tee, if any
+	streaming   bool
+	streamed    chan struct{} // closed once streaming, see serveOrigin
 }
 
 func (b *responseWriter) WriteHeader(code int) {
 	if !b.wroteHeader {
 		b.code = code
 		b.wroteHeader = true
+		if strings.HasPrefix(b.Header().Get("Content-Type"), "text/event-stream") {
+			// stop buffering, the stream may never end
+			b.streaming = true
+			b.tee = nil
+			if b.streamed != nil {
+				close(b.streamed)
+			}
+		}
 		b.ResponseWriter.WriteHeader(code)
 	}
 }
@@ -283,9 +380,11 @@ func (b *responseWriter) Write(buf []byte) (int, error) {
 	b.maybeWriteHeader()
 	n, err := b.ResponseWriter.Write(buf)
 	if b.tee != nil {
-		_, err2 := b.tee.Write(buf[:n])
-		if err == nil {
-			err = err2
+		// failing to keep the body only keeps the response from being cached, and is
+		// none of the origin handler's business
+		if _, teeErr := b.tee.Write(buf[:n]); teeErr != nil {
+			b.teeErr = teeErr
+			b.tee = nil
 		}
 	}
 	b.bytes += n
@@ -298,6 +397,13 @@ func (b *responseWriter) maybeWriteHeader() {
 	}
 }
 
+// Flush flushes the underlying writer, e.g. for event streams.
+func (b *responseWriter) Flush() {
+	if f, ok := b.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 func (b *responseWriter) Status() int {
 	return b.code
 }
@@ -0,0 +1,25 @@
+package stampede
+
+import (
+	"encoding/binary"
+	"net/http"
+)
+
+// PerClientKeyFunc adds the identity of the caller, e.g. an API key or user id, to the
+// keys of keyFunc, so that authenticated routes coalesce duplicate requests of the same
+// client without serving the response of one client to another.
+func PerClientKeyFunc(identity func(r *http.Request) string, keyFunc func(r *http.Request) uint64) func(r *http.Request) uint64 {
+	return func(r *http.Request) uint64 {
+		var key [8]byte
+		binary.LittleEndian.PutUint64(key[:], keyFunc(r))
+		return BytesToHash([]byte(identity(r)), key[:])
+	}
+}
+
+// HeaderIdentity returns the value of header name as identity for PerClientKeyFunc,
+// e.g. of the Authorization or X-API-Key header.
+func HeaderIdentity(name string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
@@ -0,0 +1,23 @@
+package stampede_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPerClientKeyFunc(t *testing.T) {
+	keyFunc := stampede.PerClientKeyFunc(stampede.HeaderIdentity("X-API-Key"), stampede.DefaultKeyFunc)
+
+	req := func(apiKey string) uint64 {
+		r := httptest.NewRequest("GET", "/account", nil)
+		r.Header.Set("X-API-Key", apiKey)
+		return keyFunc(r)
+	}
+
+	assert.Equal(t, req("alice"), req("alice"))
+	assert.NotEqual(t, req("alice"), req("bob"))
+	assert.NotEqual(t, req(""), req("alice"))
+}
@@ -0,0 +1,23 @@
+package stampede
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// sweepIdle evicts all entries that were not read within the max idle time, except for
+// pinned entries, see WithMaxIdle.
+func (c *Cache[K, V]) sweepIdle() {
+	cutoff := time.Now().Add(-c.maxIdle).UnixNano()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ck := range c.values.Keys() {
+		if _, pinned := c.pinned[ck]; pinned {
+			continue
+		}
+		if val, ok := c.values.Peek(ck); ok && atomic.LoadInt64(&val.ext.access) < cutoff {
+			c.values.Remove(ck)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxIdle(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithMaxIdle(40*time.Millisecond))
+	defer c.Close()
+
+	fetch := func() (int, error) { return 1, nil }
+	c.Get(ctx, "hot", fetch)
+	c.Get(ctx, "cold", fetch)
+	for i := 0; i < 8; i++ {
+		time.Sleep(20 * time.Millisecond)
+		c.Get(ctx, "hot", fetch)
+		// refreshes don't count as reads
+		if i < 3 {
+			c.Set(ctx, "cold", fetch)
+		}
+	}
+
+	_, ok := c.Peek("hot")
+	assert.True(t, ok)
+	_, ok = c.Peek("cold")
+	assert.False(t, ok)
+}
@@ -0,0 +1,59 @@
+package stampede
+
+import (
+	"strings"
+	"sync"
+)
+
+// interner deduplicates strings. It forgets all strings once it holds max of them;
+// strings interned before are still valid, just no longer shared with later ones.
+type interner struct {
+	mu   sync.Mutex
+	strs map[string]string
+	max  int
+}
+
+func newInterner(max int) *interner {
+	return &interner{strs: make(map[string]string), max: max}
+}
+
+// intern returns the canonical copy of s.
+func (in *interner) intern(s string) string {
+	if s == "" {
+		return s
+	}
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if is, ok := in.strs[s]; ok {
+		return is
+	}
+	if len(in.strs) >= in.max {
+		in.strs = make(map[string]string)
+	}
+	s = strings.Clone(s)
+	in.strs[s] = s
+	return s
+}
+
+// internKey interns key if it is a string.
+func internKey[K comparable](in *interner, key K) K {
+	if in == nil {
+		return key
+	}
+	if s, ok := any(key).(string); ok {
+		return any(in.intern(s)).(K)
+	}
+	return key
+}
+
+// internTags returns tags with all tags interned, see WithInterning.
+func (c *Cache[K, V]) internTags(tags []string) []string {
+	if c.strings == nil || tags == nil {
+		return tags
+	}
+	interned := make([]string, len(tags))
+	for i, tag := range tags {
+		interned[i] = c.strings.intern(tag)
+	}
+	return interned
+}
@@ -0,0 +1,33 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterning(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithInterning())
+
+	// keys built per request share one copy, which doesn't alias the request
+	buf := []byte("GET /products?page=1")
+	first := string(buf[4:])
+	c.Get(ctx, first, func() (int, error) { return 1, nil })
+	c.Pin(string(buf[4:]))
+
+	keys := c.Keys()
+	assert.Equal(t, []string{"/products?page=1"}, keys)
+	assert.True(t, unsafe.StringData(first) != unsafe.StringData(keys[0]))
+
+	var ranged string
+	c.RangeByRecency(func(key string, v int) bool {
+		ranged = key
+		return true
+	})
+	assert.True(t, unsafe.StringData(keys[0]) == unsafe.StringData(ranged))
+}
@@ -0,0 +1,96 @@
+package stampede
+
+import (
+	"context"
+	"time"
+)
+
+// SoftDelete marks the entry of key stale instead of removing it, so the next get
+// serves it while refreshing it in the background rather than waiting for the origin.
+// The entry of key in the store is deleted, so the refresh reaches the origin. It
+// reports whether key was cached. A cached error of key is dropped.
+func (c *Cache[K, V]) SoftDelete(key K) bool {
+	return c.InvalidateMany([]K{key}) == 1
+}
+
+// InvalidateMany is SoftDelete for many keys, locking the cache once and sending a single
+// EventInvalidate. It returns how many of the keys were cached.
+func (c *Cache[K, V]) InvalidateMany(keys []K) int {
+	keys, cks := c.cacheKeys(keys)
+
+	var n int
+	now := time.Now().UnixNano()
+	c.mu.Lock()
+	c.softInvalidating = true
+	for _, ck := range cks {
+		val, ok := c.values.Peek(ck)
+		val, ok = c.stashed(ck, val, ok)
+		if ok && !val.IsExpired() {
+			if val.bestBefore > now {
+				val.bestBefore = now
+			}
+			c.add(ck, val)
+		}
+		if ok {
+			n++
+		}
+	}
+	c.softInvalidating = false
+	c.emitInvalidated()
+	c.mu.Unlock()
+
+	c.forget(keys, cks)
+	return n
+}
+
+// Delete removes the entry of key from the cache and the store, and reports whether key
+// was cached.
+func (c *Cache[K, V]) Delete(key K) bool {
+	return c.DeleteMany([]K{key}) == 1
+}
+
+// DeleteMany removes the entries of keys from the cache and the store, locking the cache
+// once, and returns how many of the keys were cached. Removed entries are sent as a
+// single EventInvalidate.
+func (c *Cache[K, V]) DeleteMany(keys []K) int {
+	keys, cks := c.cacheKeys(keys)
+
+	var n int
+	c.mu.Lock()
+	c.invalidating = true
+	for _, ck := range cks {
+		_, stashed := c.stashed(ck, value[K, V]{}, false)
+		if c.values.Remove(ck) || stashed {
+			n++
+		}
+		c.unstash(ck)
+	}
+	c.invalidating = false
+	c.emitInvalidated()
+	c.mu.Unlock()
+
+	c.forget(keys, cks)
+	return n
+}
+
+// cacheKeys returns the normalized keys and the cache keys of keys.
+func (c *Cache[K, V]) cacheKeys(keys []K) ([]K, []cacheKey[K]) {
+	normalized := make([]K, len(keys))
+	cks := make([]cacheKey[K], len(keys))
+	for i, key := range keys {
+		normalized[i] = c.normalizeKey(key)
+		cks[i] = c.cacheKey(normalized[i])
+	}
+	return normalized, cks
+}
+
+// forget drops the cached errors, collapsed results and store entries of keys.
+func (c *Cache[K, V]) forget(keys []K, cks []cacheKey[K]) {
+	for i, ck := range cks {
+		c.forgetError(ck)
+		c.uncollapse(ck)
+		if c.store != nil {
+			c.store.Delete(context.Background(), c.storeKey(keys[i], ck))
+		}
+	}
+}
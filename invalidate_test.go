@@ -0,0 +1,78 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Hour)
+
+	calls := 0
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+	c.Get(ctx, "k", fetch)
+
+	assert.True(t, c.SoftDelete("k"))
+	assert.False(t, c.SoftDelete("missing"))
+
+	// the stale value is served while it is refreshed
+	val, err := c.Get(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+	assert.Eventually(t, func() bool {
+		val, _ := c.Peek("k")
+		return val == 2
+	}, time.Second, time.Millisecond)
+}
+
+func TestInvalidateMany(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Hour)
+	fetch := func() (int, error) { return 1, nil }
+	c.Get(ctx, "a", fetch)
+	c.Get(ctx, "b", fetch)
+	events := c.Events()
+
+	assert.Equal(t, 2, c.InvalidateMany([]string{"a", "b", "missing"}))
+	val, ok := c.Peek("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, val)
+
+	// a single event is sent for all keys
+	ev := <-events
+	assert.Equal(t, stampede.EventInvalidate, ev.Kind)
+	assert.Equal(t, []string{"a", "b"}, ev.Keys)
+	assert.Empty(t, events)
+}
+
+func TestDeleteMany(t *testing.T) {
+	ctx := context.Background()
+	store := stampede.NewMemoryStore()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Hour, stampede.WithStore(store, stampede.JSONCodec{}))
+	fetch := func() (int, error) { return 1, nil }
+	for _, key := range []string{"a", "b", "c"} {
+		c.Get(ctx, key, fetch)
+	}
+	events := c.Events()
+
+	assert.Equal(t, 2, c.DeleteMany([]string{"a", "b", "missing"}))
+	ev := <-events
+	assert.Equal(t, stampede.EventInvalidate, ev.Kind)
+	assert.Equal(t, []string{"a", "b"}, ev.Keys)
+	assert.Empty(t, events)
+	assert.Equal(t, []string{"c"}, c.Keys())
+	_, err := store.Get(ctx, "a")
+	assert.Equal(t, stampede.ErrNotFound, err)
+
+	assert.True(t, c.Delete("c"))
+	assert.False(t, c.Delete("c"))
+	assert.Empty(t, c.Keys())
+}
@@ -0,0 +1,47 @@
+package kafkasink
+
+import (
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+type dedupeKey struct {
+	sink, cache, key string
+}
+
+// Deduper drops payloads that were already consumed, e.g. duplicated by the at least
+// once delivery of Kafka or replayed, so they don't evict the same keys again and again.
+// A payload is dropped unless its sequence number is higher than the one of the last
+// payload of its sink for its key. Payloads without a sequence number are not dropped.
+//
+// It remembers the last sequence number of the given number of keys; replays of keys
+// forgotten since are consumed again.
+type Deduper struct {
+	mu   sync.Mutex // serializes the check and the record of Accept
+	seen *lru.Cache[dedupeKey, uint64]
+}
+
+// NewDeduper returns a deduper remembering size keys.
+func NewDeduper(size int) *Deduper {
+	if size < 1 {
+		size = 1
+	}
+	seen, _ := lru.New[dedupeKey, uint64](size)
+	return &Deduper{seen: seen}
+}
+
+// Accept reports whether p is consumed for the first time, and records it.
+func (d *Deduper) Accept(p Payload) bool {
+	if p.Seq == 0 {
+		return true
+	}
+	k := dedupeKey{sink: p.Sink, cache: p.Cache, key: p.Key}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if last, ok := d.seen.Get(k); ok && p.Seq <= last {
+		return false
+	}
+	d.seen.Add(k, p.Seq)
+	return true
+}
@@ -0,0 +1,64 @@
+package kafkasink_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/dadav/stampede/kafkasink"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeduper(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute)
+	events := c.Events()
+	c.Set(ctx, "a", func() (int, error) { return 1, nil })
+	c.Set(ctx, "b", func() (int, error) { return 1, nil })
+	c.Set(ctx, "a", func() (int, error) { return 2, nil })
+	c.Close()
+
+	w := &writer{}
+	assert.NoError(t, kafkasink.Run(ctx, kafkasink.New(w, "cache-events"), events))
+
+	var payloads []kafkasink.Payload
+	for _, msg := range w.msgs {
+		var p kafkasink.Payload
+		assert.NoError(t, json.Unmarshal(msg.Value, &p))
+		payloads = append(payloads, p)
+	}
+	assert.Len(t, payloads, 3)
+
+	d := kafkasink.NewDeduper(8)
+	for _, p := range payloads {
+		assert.True(t, d.Accept(p))
+	}
+	// redelivered and replayed messages are dropped
+	assert.False(t, d.Accept(payloads[2]))
+	assert.False(t, d.Accept(payloads[0]))
+	// unsequenced payloads are always accepted
+	assert.True(t, d.Accept(kafkasink.Payload{Kind: "set", Key: "a"}))
+}
+
+func TestDeduperConcurrent(t *testing.T) {
+	d := kafkasink.NewDeduper(8)
+	var accepted int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(seq uint64) {
+			defer wg.Done()
+			if d.Accept(kafkasink.Payload{Kind: "set", Key: "a", Seq: seq}) {
+				atomic.AddInt32(&accepted, 1)
+			}
+		}(uint64(i%10 + 1))
+	}
+	wg.Wait()
+	// every redelivery is dropped, and later payloads never lose to earlier ones
+	assert.LessOrEqual(t, atomic.LoadInt32(&accepted), int32(10))
+	assert.False(t, d.Accept(kafkasink.Payload{Kind: "set", Key: "a", Seq: 10}))
+}
@@ -0,0 +1,158 @@
+// Package kafkasink publishes the events of stampede caches to Kafka, so data platform
+// consumers can track cache churn and other services can invalidate the caches they
+// derive from it.
+//
+// The package doesn't depend on a Kafka client: Writer is satisfied by a small adapter
+// around the client of choice, e.g. the WriteMessages method of a kafka-go Writer.
+package kafkasink
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/dadav/stampede"
+)
+
+// Message is a message to publish to Kafka.
+type Message struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// Writer publishes messages to Kafka.
+type Writer interface {
+	WriteMessages(ctx context.Context, msgs ...Message) error
+}
+
+// Payload is the JSON value of every published message. Values of entries are not
+// published. The message key is the cache key, so all events of a key land on the same
+// partition in order.
+type Payload struct {
+	Cache      string    `json:"cache,omitempty"`
+	Kind       string    `json:"kind"`
+	Key        string    `json:"key"`
+	BestBefore time.Time `json:"best_before"`
+	Expiry     time.Time `json:"expiry"`
+
+	// Sink identifies the publishing sink, Seq orders its payloads, see Deduper.
+	Sink string `json:"sink,omitempty"`
+	Seq  uint64 `json:"seq,omitempty"`
+
+	// KeyID and Signature sign the payload, see Keyring.
+	KeyID     string `json:"kid,omitempty"`
+	Signature []byte `json:"sig,omitempty"`
+}
+
+// Sink publishes cache events to a topic.
+type Sink struct {
+	w     Writer
+	topic string
+	id    string
+	seq   uint64
+
+	// Cache is published as the name of the cache, if set.
+	Cache string
+	// BatchSize is the maximum number of events written at once, 100 if 0.
+	BatchSize int
+	// Keyring signs the published payloads, if set.
+	Keyring *Keyring
+}
+
+// New returns a sink publishing to topic with w.
+func New(w Writer, topic string) *Sink {
+	var id [8]byte
+	rand.Read(id[:])
+	return &Sink{w: w, topic: topic, id: hex.EncodeToString(id[:])}
+}
+
+// Run publishes events, e.g. from stampede.Cache.Events, until the channel is closed or
+// ctx is done. Events arriving while a batch is written are published in the next one.
+// It returns the first error of w, or ErrNoCurrentKey for a Keyring without its current
+// key.
+func Run[K comparable, V any](ctx context.Context, s *Sink, events <-chan stampede.Event[K, V]) error {
+	size := s.BatchSize
+	if size <= 0 {
+		size = 100
+	}
+
+	batch := make([]Message, 0, size)
+	var err error
+	for {
+		batch = batch[:0]
+
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if batch, err = messages(s, batch, ev); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+	drain:
+		for len(batch) < size {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					break drain
+				}
+				if batch, err = messages(s, batch, ev); err != nil {
+					return err
+				}
+			default:
+				break drain
+			}
+		}
+
+		if err := s.w.WriteMessages(ctx, batch...); err != nil {
+			return fmt.Errorf("kafkasink: %w", err)
+		}
+	}
+}
+
+// messages appends the messages of ev to batch, one per key for the invalidations of
+// many keys, so that every message is keyed by its cache key.
+func messages[K comparable, V any](s *Sink, batch []Message, ev stampede.Event[K, V]) ([]Message, error) {
+	keys := ev.Keys
+	if ev.Kind != stampede.EventInvalidate || len(keys) == 0 {
+		keys = []K{ev.Key}
+	}
+	for _, key := range keys {
+		msg, err := s.message(ev.Kind, key, ev.BestBefore, ev.Expiry)
+		if err != nil {
+			return batch, err
+		}
+		batch = append(batch, msg)
+	}
+	return batch, nil
+}
+
+func (s *Sink) message(kind stampede.EventKind, key any, bestBefore, expiry time.Time) (Message, error) {
+	k := fmt.Sprint(key)
+	p := Payload{
+		Cache:      s.Cache,
+		Kind:       kind.String(),
+		Key:        k,
+		BestBefore: bestBefore,
+		Expiry:     expiry,
+		Sink:       s.id,
+		Seq:        atomic.AddUint64(&s.seq, 1),
+	}
+	if s.Keyring != nil {
+		var err error
+		if p, err = s.Keyring.sign(p); err != nil {
+			return Message{}, err
+		}
+	}
+	value, _ := json.Marshal(p)
+	return Message{Topic: s.topic, Key: []byte(k), Value: value}, nil
+}
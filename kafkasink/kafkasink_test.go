@@ -0,0 +1,49 @@
+package kafkasink_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/dadav/stampede/kafkasink"
+	"github.com/stretchr/testify/assert"
+)
+
+type writer struct {
+	msgs []kafkasink.Message
+}
+
+func (w *writer) WriteMessages(ctx context.Context, msgs ...kafkasink.Message) error {
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](1, time.Minute, time.Minute)
+	events := c.Events()
+
+	c.Set(ctx, "a", func() (int, error) { return 1, nil })
+	c.Set(ctx, "b", func() (int, error) { return 2, nil })
+	c.DeleteMany([]string{"b"})
+	c.Close()
+
+	w := &writer{}
+	sink := kafkasink.New(w, "cache-events")
+	sink.Cache = "numbers"
+	assert.NoError(t, kafkasink.Run(ctx, sink, events))
+
+	var kinds []string
+	for _, msg := range w.msgs {
+		assert.Equal(t, "cache-events", msg.Topic)
+
+		var p kafkasink.Payload
+		assert.NoError(t, json.Unmarshal(msg.Value, &p))
+		assert.Equal(t, "numbers", p.Cache)
+		assert.Equal(t, string(msg.Key), p.Key)
+		kinds = append(kinds, p.Kind+" "+p.Key)
+	}
+	assert.Equal(t, []string{"set a", "evict a", "set b", "invalidate b"}, kinds)
+}
@@ -0,0 +1,75 @@
+package kafkasink
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	// ErrUnsigned is returned by Verify for payloads without a signature.
+	ErrUnsigned = errors.New("kafkasink: payload not signed")
+
+	// ErrUnknownKey is returned by Verify for payloads signed with a key not in the
+	// keyring, e.g. a key that was rotated out.
+	ErrUnknownKey = errors.New("kafkasink: payload signed with unknown key")
+
+	// ErrBadSignature is returned by Verify for payloads whose signature doesn't match.
+	ErrBadSignature = errors.New("kafkasink: bad payload signature")
+
+	// ErrNoCurrentKey is returned by Run for keyrings without their Current key.
+	ErrNoCurrentKey = errors.New("kafkasink: current key not in keyring")
+)
+
+// Keyring holds the HMAC-SHA256 keys signing payloads, by id, so that consumers
+// invalidating their caches from the topic only act on events published by trusted
+// sinks. Payloads are signed with the Current key and verified with any key of the
+// keyring. Keys are rotated by adding the new key to the keyrings of all consumers,
+// making it current in the sinks, and removing the old key once it is no longer used.
+type Keyring struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+// sign returns p signed with the current key.
+func (k *Keyring) sign(p Payload) (Payload, error) {
+	key, ok := k.Keys[k.Current]
+	if !ok || len(key) == 0 {
+		return p, ErrNoCurrentKey
+	}
+	p.KeyID, p.Signature = k.Current, nil
+	p.Signature = mac(key, p)
+	return p, nil
+}
+
+// Verify decodes the value of a message published by a sink signing with a keyring
+// sharing keys with k, and verifies its signature.
+func (k *Keyring) Verify(value []byte) (Payload, error) {
+	var p Payload
+	if err := json.Unmarshal(value, &p); err != nil {
+		return p, err
+	}
+	if len(p.Signature) == 0 {
+		return p, ErrUnsigned
+	}
+	key, ok := k.Keys[p.KeyID]
+	if !ok {
+		return p, ErrUnknownKey
+	}
+	sig := p.Signature
+	p.Signature = nil
+	if !hmac.Equal(sig, mac(key, p)) {
+		return p, ErrBadSignature
+	}
+	p.Signature = sig
+	return p, nil
+}
+
+// mac returns the HMAC of p, which has no signature, with key.
+func mac(key []byte, p Payload) []byte {
+	b, _ := json.Marshal(p)
+	h := hmac.New(sha256.New, key)
+	h.Write(b)
+	return h.Sum(nil)
+}
@@ -0,0 +1,55 @@
+package kafkasink_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/dadav/stampede/kafkasink"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyring(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](1, time.Minute, time.Minute)
+	events := c.Events()
+	c.Set(ctx, "a", func() (int, error) { return 1, nil })
+	c.Close()
+
+	w := &writer{}
+	sink := kafkasink.New(w, "cache-events")
+	sink.Keyring = &kafkasink.Keyring{Current: "k2", Keys: map[string][]byte{"k2": []byte("new secret")}}
+	assert.NoError(t, kafkasink.Run(ctx, sink, events))
+	msg := w.msgs[0].Value
+
+	// consumers accept the old and the new key while the keys are rotated
+	consumer := &kafkasink.Keyring{Keys: map[string][]byte{"k1": []byte("old secret"), "k2": []byte("new secret")}}
+	p, err := consumer.Verify(msg)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", p.Key)
+	assert.Equal(t, "k2", p.KeyID)
+
+	_, err = (&kafkasink.Keyring{Keys: map[string][]byte{"k1": []byte("old secret")}}).Verify(msg)
+	assert.ErrorIs(t, err, kafkasink.ErrUnknownKey)
+
+	_, err = (&kafkasink.Keyring{Keys: map[string][]byte{"k2": []byte("forged")}}).Verify(msg)
+	assert.ErrorIs(t, err, kafkasink.ErrBadSignature)
+
+	_, err = consumer.Verify([]byte(`{"kind":"invalidate","key":"*"}`))
+	assert.ErrorIs(t, err, kafkasink.ErrUnsigned)
+}
+
+func TestKeyringNoCurrentKey(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](1, time.Minute, time.Minute)
+	events := c.Events()
+	c.Set(ctx, "a", func() (int, error) { return 1, nil })
+	c.Close()
+
+	w := &writer{}
+	sink := kafkasink.New(w, "cache-events")
+	sink.Keyring = &kafkasink.Keyring{Current: "k2", Keys: map[string][]byte{"k1": []byte("old secret")}}
+	assert.ErrorIs(t, kafkasink.Run(ctx, sink, events), kafkasink.ErrNoCurrentKey)
+	assert.Empty(t, w.msgs)
+}
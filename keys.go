@@ -0,0 +1,159 @@
+package stampede
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// KeyHash selects the digest used by WithKeyHashing.
+type KeyHash int
+
+const (
+	// KeyHashNone stores keys as they are.
+	KeyHashNone KeyHash = iota
+
+	// KeyHashXXHash stores keys as 64-bit xxhash digests. Fast, but collisions are
+	// possible for very large key sets.
+	KeyHashXXHash
+
+	// KeyHashSHA256 stores keys as sha256 digests.
+	KeyHashSHA256
+)
+
+// Keyer is implemented by keys that provide their own canonical representation. Keys
+// implementing Keyer are stored by their CacheKey instead of by value, which allows
+// structs with non-comparable fields as keys of a Cache[any, V], and gives composed
+// keys an explicit format instead of a hand-rolled fmt.Sprintf prone to collisions.
+type Keyer interface {
+	CacheKey() string
+}
+
+// cacheKey is the key of an entry in the underlying lru. Plain keys only set key,
+// hashed keys and Keyer keys only set digest.
+type cacheKey[K comparable] struct {
+	key    K
+	digest string
+}
+
+func (c *Cache[K, V]) cacheKey(key K) cacheKey[K] {
+	if c.strings != nil {
+		ck := c.keyOf(key)
+		return cacheKey[K]{key: internKey(c.strings, ck.key), digest: c.strings.intern(ck.digest)}
+	}
+	return c.keyOf(key)
+}
+
+func (c *Cache[K, V]) keyOf(key K) cacheKey[K] {
+	if c.keyHash == KeyHashNone && !c.keyers {
+		return cacheKey[K]{key: key}
+	}
+	k, isKeyer := any(key).(Keyer)
+
+	switch {
+	case c.keyHash == KeyHashNone && isKeyer:
+		return cacheKey[K]{digest: k.CacheKey()}
+	case c.keyHash == KeyHashXXHash:
+		var buf [8]byte
+		binary.LittleEndian.PutUint64(buf[:], xxhash.Sum64String(typedKeyString(key)))
+		return cacheKey[K]{digest: string(buf[:])}
+	case c.keyHash == KeyHashSHA256:
+		sum := sha256.Sum256([]byte(typedKeyString(key)))
+		return cacheKey[K]{digest: string(sum[:])}
+	default:
+		return cacheKey[K]{key: key}
+	}
+}
+
+var keyerType = reflect.TypeOf((*Keyer)(nil)).Elem()
+
+// mayBeKeyer reports whether keys of type K may implement Keyer. Checking it once
+// saves boxing every key in cacheKey.
+func mayBeKeyer[K comparable]() bool {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	return t.Kind() == reflect.Interface || t.Implements(keyerType)
+}
+
+// mayBeAny reports whether keys of type K may be of several dynamic types, e.g. for a
+// Cache[any, V], whose keys need typedKeyString to tell "1" and 1 apart.
+func mayBeAny[K comparable]() bool {
+	return reflect.TypeOf((*K)(nil)).Elem().Kind() == reflect.Interface
+}
+
+// typedKeyString returns the representation of key that is hashed by WithKeyHashing:
+// keyString tagged with the dynamic type of key, so keys of different types don't
+// collide.
+func typedKeyString[K comparable](key K) string {
+	return fmt.Sprintf("%T:", key) + keyString(key)
+}
+
+// keyString returns the representation of key within keys of its type.
+func keyString[K comparable](key K) string {
+	switch k := any(key).(type) {
+	case Keyer:
+		return k.CacheKey()
+	case string:
+		return k
+	case fmt.Stringer:
+		return k.String()
+	default:
+		return fmt.Sprintf("%#v", k)
+	}
+}
+
+// Keys returns the keys currently held by the cache, from oldest to newest. When key
+// hashing is enabled, only keys retained with WithRetainedKeys are returned.
+func (c *Cache[K, V]) Keys() []K {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	keys := make([]K, 0, c.values.Len())
+	for _, k := range c.values.Keys() {
+		if k.digest == "" {
+			keys = append(keys, k.key)
+			continue
+		}
+		if v, ok := c.values.Peek(k); ok && v.hasKey {
+			keys = append(keys, v.key)
+		}
+	}
+	return keys
+}
+
+// RangeByRecency calls fn for every cached entry, from the most to the least recently
+// used one, until fn returns false, e.g. to dump the hottest entries first. It works on
+// a snapshot, so fn may use the cache. key is the zero value for entries stored by
+// digest without retaining their key, see WithRetainedKeys.
+func (c *Cache[K, V]) RangeByRecency(fn func(key K, v V) bool) {
+	c.mu.RLock()
+	cks := c.values.Keys()
+	entries := make([]value[K, V], 0, len(cks)+len(c.pinned))
+	keys := make([]cacheKey[K], 0, len(cks)+len(c.pinned))
+	for i := len(cks) - 1; i >= 0; i-- {
+		if v, ok := c.values.Peek(cks[i]); ok {
+			entries = append(entries, v)
+			keys = append(keys, cks[i])
+		}
+	}
+	// pinned entries evicted from the lru are the least recently used ones
+	for ck, p := range c.pinned {
+		if p != nil {
+			entries = append(entries, *p)
+			keys = append(keys, ck)
+		}
+	}
+	c.mu.RUnlock()
+
+	for i, entry := range entries {
+		key := keys[i].key
+		if entry.hasKey {
+			key = entry.key
+		}
+		if !fn(key, c.read(entry.v)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package stampede
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencies keeps the durations and outcomes of the most recent fetches.
+type latencies struct {
+	mu      sync.Mutex
+	samples [128]time.Duration
+	failed  [128]bool
+	n       int // number of samples ever added
+}
+
+// minLatencySamples is the number of fetches needed before latencies are trusted.
+const minLatencySamples = 16
+
+func (l *latencies) add(d time.Duration, failed bool) {
+	l.mu.Lock()
+	l.samples[l.n%len(l.samples)] = d
+	l.failed[l.n%len(l.failed)] = failed
+	l.n++
+	l.mu.Unlock()
+}
+
+// errorRate returns the share of the recent fetches that failed, or false if there were
+// too few fetches yet.
+func (l *latencies) errorRate() (float64, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := l.n
+	if n > len(l.failed) {
+		n = len(l.failed)
+	}
+	if n < minLatencySamples {
+		return 0, false
+	}
+	var failed int
+	for _, f := range l.failed[:n] {
+		if f {
+			failed++
+		}
+	}
+	return float64(failed) / float64(n), true
+}
+
+// quantile returns the q quantile of the recent fetch durations, or false if there were
+// too few fetches yet.
+func (l *latencies) quantile(q float64) (time.Duration, bool) {
+	l.mu.Lock()
+	n := l.n
+	if n > len(l.samples) {
+		n = len(l.samples)
+	}
+	samples := make([]time.Duration, n)
+	copy(samples, l.samples[:n])
+	l.mu.Unlock()
+
+	if n < minLatencySamples {
+		return 0, false
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples[int(q*float64(n-1))], true
+}
+
+// doomed reports whether a fetch started with ctx is likely to outlive its deadline,
+// see WithDeadlineAwareness.
+func (c *Cache[K, V]) doomed(ctx context.Context) bool {
+	if !c.deadlineAware {
+		return false
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return false
+	}
+	p99, ok := c.latency.quantile(0.99)
+	return ok && time.Until(deadline) < p99
+}
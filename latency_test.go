@@ -0,0 +1,36 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadlineAwareness(t *testing.T) {
+	c := stampede.NewCacheKV[int, int](64, 0, time.Minute, stampede.WithDeadlineAwareness())
+	defer c.Close()
+
+	slow := func() (int, error) {
+		time.Sleep(20 * time.Millisecond)
+		return 2, nil
+	}
+	for i := 0; i < 16; i++ {
+		c.GetFresh(context.Background(), i, slow)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	val, err := c.GetFresh(ctx, 0, slow)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, val)
+	assert.Less(t, time.Since(start), 5*time.Millisecond)
+
+	// callers with enough time wait for the fetch
+	val, err = c.GetFresh(context.Background(), 1, func() (int, error) { return 3, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 3, val)
+}
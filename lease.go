@@ -0,0 +1,71 @@
+package stampede
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// leaseSuffix is appended to the store key of an entry for the key of its lease.
+const leaseSuffix = ":lease"
+
+// leasePolls is the number of times the store is polled while waiting for a lease.
+const leasePolls = 10
+
+func newLeaseID() string {
+	var id [8]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// lease takes the lease of skey, and returns the function releasing it, or reports that
+// another instance holds it, see WithStoreLeases. The lease is read before it is
+// written, so instances seeing a miss at the same time may both take it.
+func (c *Cache[K, V]) lease(ctx context.Context, skey string) (release func(), held bool) {
+	if c.leaseTTL <= 0 {
+		return nil, false
+	}
+	lkey := skey + leaseSuffix
+	if holder, err := c.store.Get(ctx, lkey); err == nil && !bytes.Equal(holder, []byte(c.leaseID)) {
+		return nil, true
+	}
+	if c.store.Set(ctx, lkey, []byte(c.leaseID), c.leaseTTL) != nil {
+		return nil, false
+	}
+	return func() {
+		if holder, err := c.store.Get(ctx, lkey); err == nil && bytes.Equal(holder, []byte(c.leaseID)) {
+			c.store.Delete(ctx, lkey)
+		}
+	}, false
+}
+
+// awaitLease polls the store until found reports the value of skey written by the holder
+// of its lease, and reports whether it was found before the lease wait elapsed, the
+// lease was released or ctx is done.
+func (c *Cache[K, V]) awaitLease(ctx context.Context, skey string, found func() bool) bool {
+	if c.leaseWait <= 0 {
+		return false
+	}
+	ticker := time.NewTicker(c.leaseWait / leasePolls)
+	defer ticker.Stop()
+	timer := time.NewTimer(c.leaseWait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if found() {
+				return true
+			}
+			if _, err := c.store.Get(ctx, skey+leaseSuffix); err != nil {
+				return found()
+			}
+		case <-timer.C:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreLeases(t *testing.T) {
+	ctx := context.Background()
+	store := stampede.NewMemoryStore()
+	newInstance := func(opts ...stampede.Option) *stampede.Cache[string, int] {
+		opts = append(opts, stampede.WithStore(store, stampede.JSONCodec{}), stampede.WithStoreLeases(time.Second, time.Second))
+		return stampede.NewCacheKV[string, int](8, time.Minute, time.Hour, opts...)
+	}
+	a, b := newInstance(), newInstance()
+	defer a.Close()
+	defer b.Close()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	go a.Get(ctx, "k", func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	<-started
+
+	// b waits for the value fetched by a instead of fetching it too
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	v, err := b.Get(ctx, "k", func() (int, error) { return 0, errors.New("origin called twice") })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
+
+func TestStoreLeasesServeStale(t *testing.T) {
+	ctx := context.Background()
+	store := stampede.NewMemoryStore()
+	newInstance := func() *stampede.Cache[string, int] {
+		return stampede.NewCacheKV[string, int](8, 10*time.Millisecond, time.Hour,
+			stampede.WithStore(store, stampede.JSONCodec{}), stampede.WithStoreEnvelope(), stampede.WithStoreLeases(time.Second, time.Second))
+	}
+	a, b := newInstance(), newInstance()
+	defer a.Close()
+	defer b.Close()
+
+	a.Get(ctx, "k", func() (int, error) { return 1, nil })
+	time.Sleep(15 * time.Millisecond)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+	go a.GetFresh(ctx, "k", func() (int, error) {
+		close(started)
+		<-release
+		return 2, nil
+	})
+	<-started
+
+	// while a refreshes the key, b serves it stale from the store
+	start := time.Now()
+	v, err := b.Get(ctx, "k", func() (int, error) { return 0, errors.New("origin called twice") })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
@@ -0,0 +1,114 @@
+package stampede
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goware/singleflight"
+)
+
+// Schedule refreshes key with fn every interval, starting right away, regardless of
+// traffic. The refreshes run until the returned stop function is called or the cache
+// is closed.
+func (c *Cache[K, V]) Schedule(key K, every time.Duration, fn singleflight.DoFunc[V]) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+
+	c.goBackground(func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		for {
+			c.Set(c.ctx, key, fn)
+
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			case <-c.ctx.Done():
+				return
+			}
+		}
+	})
+
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// janitor runs the periodic maintenance of the cache every interval, until the cache is
+// closed.
+func (c *Cache[K, V]) janitor(every time.Duration) {
+	c.goBackground(func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if c.maxIdle > 0 {
+					c.sweepIdle()
+				}
+				if c.retryBackoff > 0 {
+					c.runRetries()
+				}
+			case <-c.ctx.Done():
+				return
+			}
+		}
+	})
+}
+
+// janitorInterval returns how often the janitor runs, or 0 if the cache doesn't need
+// one.
+func (c *Cache[K, V]) janitorInterval() time.Duration {
+	every := c.maxIdle / 2
+	if c.retryBackoff > 0 && (every <= 0 || c.retryBackoff < every) {
+		every = c.retryBackoff
+	}
+	return every
+}
+
+// goBackground runs fn in a goroutine owned by the cache, which Close waits for.
+// Goroutines started after Close are not waited for.
+func (c *Cache[K, V]) goBackground(fn func()) {
+	c.closeMu.RLock()
+	tracked := !c.closed
+	if tracked {
+		c.wg.Add(1)
+	}
+	c.closeMu.RUnlock()
+
+	atomic.AddInt64(&c.background, 1)
+	go func() {
+		if tracked {
+			defer c.wg.Done()
+		}
+		defer atomic.AddInt64(&c.background, -1)
+		fn()
+	}()
+}
+
+// Close stops all scheduled refreshes and waits for them, and for all other background
+// refreshes, to return, closes the channels returned by Events, and flushes the stores
+// batching their writes, like FileStore. Cached values can still be read after Close.
+func (c *Cache[K, V]) Close() error {
+	c.closeMu.Lock()
+	c.closed = true
+	c.closeMu.Unlock()
+
+	c.cancel()
+	c.wg.Wait()
+	c.closeEvents()
+	return errors.Join(flushStore(c.store), flushStore(c.lkg))
+}
+
+// flushStore writes the pending changes of stores batching them, like FileStore.
+func flushStore(s Store) error {
+	if f, ok := s.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package stampede_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedule(t *testing.T) {
+	cache := stampede.NewCacheKV[string, int64](8, time.Minute, time.Minute)
+
+	var n int64
+	stop := cache.Schedule("flags", 20*time.Millisecond, func() (int64, error) {
+		return atomic.AddInt64(&n, 1), nil
+	})
+
+	time.Sleep(70 * time.Millisecond)
+	stop()
+	stop()
+
+	refreshes := atomic.LoadInt64(&n)
+	assert.GreaterOrEqual(t, refreshes, int64(3))
+	val, ok := cache.Peek("flags")
+	assert.True(t, ok)
+	assert.Equal(t, refreshes, val)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, refreshes, atomic.LoadInt64(&n))
+
+	cache.Schedule("other", 10*time.Millisecond, func() (int64, error) {
+		return atomic.AddInt64(&n, 1), nil
+	})
+	assert.NoError(t, cache.Close())
+	refreshes = atomic.LoadInt64(&n)
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, refreshes, atomic.LoadInt64(&n))
+}
@@ -0,0 +1,162 @@
+package stampede
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// saveLastKnownGood writes val to the last known good store, see WithLastKnownGood.
+func (c *Cache[K, V]) saveLastKnownGood(ctx context.Context, key K, ck cacheKey[K], val V) {
+	if c.lkg == nil {
+		return
+	}
+	if b, err := c.lkgCodec.Marshal(val); err == nil {
+		c.lkg.Set(ctx, c.storeKey(key, ck), b, c.lkgTTL)
+	}
+}
+
+// lastKnownGood returns the value of key in the last known good store.
+func (c *Cache[K, V]) lastKnownGood(ctx context.Context, key K, ck cacheKey[K]) (v V, ok bool) {
+	if c.lkg == nil {
+		return v, false
+	}
+	b, err := c.lkg.Get(ctx, c.storeKey(key, ck))
+	if err != nil {
+		return v, false
+	}
+	return v, c.lkgCodec.Unmarshal(b, &v) == nil
+}
+
+// DefaultFlushDelay is the default FlushDelay of FileStores.
+const DefaultFlushDelay = time.Second
+
+// FileStore is a Store persisting all its entries to a single file, which is rewritten
+// after changes. It is meant for a small number of keys that change rarely, like the
+// last known good values of configuration, see WithLastKnownGood.
+type FileStore struct {
+	// FlushDelay batches the changes made within it into a single rewrite of the file,
+	// DefaultFlushDelay by default. Without delay, every change is written right away.
+	// It must be set before the store is used.
+	FlushDelay time.Duration
+
+	mu      sync.Mutex
+	path    string
+	entries map[string]fileEntry
+	dirty   bool
+	timer   *time.Timer
+}
+
+type fileEntry struct {
+	Value  []byte
+	Expiry time.Time
+}
+
+// NewFileStore returns a FileStore persisting to path, with the entries already in it.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{FlushDelay: DefaultFlushDelay, path: path, entries: map[string]fileEntry{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&s.entries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || (!e.Expiry.IsZero() && e.Expiry.Before(time.Now())) {
+		return nil, ErrNotFound
+	}
+	return e.Value, nil
+}
+
+func (s *FileStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := fileEntry{Value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.Expiry = time.Now().Add(ttl)
+	}
+	s.entries[key] = e
+	return s.changed()
+}
+
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[key]; !ok {
+		return nil
+	}
+	delete(s.entries, key)
+	return s.changed()
+}
+
+// Flush writes the pending changes to the file. Cache.Close flushes its stores.
+func (s *FileStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if !s.dirty {
+		return nil
+	}
+	return s.flush()
+}
+
+// changed writes the changes to the file, or schedules it after FlushDelay.
+func (s *FileStore) changed() error {
+	s.dirty = true
+	if s.FlushDelay <= 0 {
+		return s.flush()
+	}
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.FlushDelay, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.timer = nil
+
+			// failed writes stay pending, for the next change or Flush
+			s.flush()
+		})
+	}
+	return nil
+}
+
+// flush drops expired entries and atomically replaces the file with the others.
+func (s *FileStore) flush() error {
+	now := time.Now()
+	for key, e := range s.entries {
+		if !e.Expiry.IsZero() && e.Expiry.Before(now) {
+			delete(s.entries, key)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(s.entries); err != nil {
+		return err
+	}
+	if err := writeFile(s.path, &buf); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
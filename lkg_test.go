@@ -0,0 +1,88 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLastKnownGood(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lkg")
+	errOrigin := errors.New("origin down")
+
+	s, err := stampede.NewFileStore(path)
+	assert.NoError(t, err)
+	c := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute,
+		stampede.WithLastKnownGood(s, stampede.JSONCodec{}, time.Hour))
+	val, err := c.Get(ctx, "flags", func() (string, error) { return "on", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "on", val)
+	assert.NoError(t, c.Close())
+
+	// a restarted instance with an empty cache and a failing origin
+	s, err = stampede.NewFileStore(path)
+	assert.NoError(t, err)
+	c = stampede.NewCacheKV[string, string](8, time.Minute, time.Minute,
+		stampede.WithLastKnownGood(s, stampede.JSONCodec{}, time.Hour))
+	val, err = c.Get(ctx, "flags", func() (string, error) { return "", errOrigin })
+	assert.NoError(t, err)
+	assert.Equal(t, "on", val)
+
+	_, err = c.Get(ctx, "other", func() (string, error) { return "", errOrigin })
+	assert.Equal(t, errOrigin, err)
+}
+
+func TestFileStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store")
+
+	s, err := stampede.NewFileStore(path)
+	assert.NoError(t, err)
+	assert.NoError(t, s.Set(ctx, "a", []byte("1"), 0))
+	assert.NoError(t, s.Set(ctx, "b", []byte("2"), time.Millisecond))
+	assert.NoError(t, s.Set(ctx, "c", []byte("3"), 0))
+	assert.NoError(t, s.Delete(ctx, "c"))
+	time.Sleep(5 * time.Millisecond)
+	assert.NoError(t, s.Flush())
+
+	s, err = stampede.NewFileStore(path)
+	assert.NoError(t, err)
+	b, err := s.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), b)
+	_, err = s.Get(ctx, "b")
+	assert.Equal(t, stampede.ErrNotFound, err)
+	_, err = s.Get(ctx, "c")
+	assert.Equal(t, stampede.ErrNotFound, err)
+}
+
+func TestFileStoreFlushDelay(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "store")
+
+	s, err := stampede.NewFileStore(path)
+	assert.NoError(t, err)
+	s.FlushDelay = 20 * time.Millisecond
+	assert.NoError(t, s.Set(ctx, "a", []byte("1"), 0))
+	assert.NoError(t, s.Set(ctx, "b", []byte("2"), 0))
+
+	// the changes are batched into a single write after the delay
+	_, err = os.Stat(path)
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Eventually(t, func() bool {
+		s, err := stampede.NewFileStore(path)
+		if err != nil {
+			return false
+		}
+		_, err = s.Get(ctx, "b")
+		return err == nil
+	}, time.Second, 5*time.Millisecond)
+}
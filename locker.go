@@ -0,0 +1,70 @@
+package stampede
+
+import (
+	"context"
+	"sync"
+)
+
+// Locker is a distributed lock provider, e.g. based on Redis or etcd leases (see the
+// etcdlock package), extending the coalescing of fetches from one instance to all
+// instances sharing a store. After a store miss, the instance holding the lock of a key
+// fetches it from the origin and writes it to the store, while the others wait for the
+// lock and then read the store.
+//
+// Lock blocks until the lock of key is acquired or ctx is done. Implementations should
+// expire locks of crashed holders. If Lock fails, the value is fetched without the lock.
+type Locker interface {
+	Lock(ctx context.Context, key string) (unlock func(), err error)
+}
+
+// lock acquires the lock of skey, and returns nil without a locker or if it fails.
+func (c *Cache[K, V]) lock(ctx context.Context, skey string) func() {
+	if c.locker == nil {
+		return nil
+	}
+	unlock, err := c.locker.Lock(ctx, skey)
+	if err != nil {
+		return nil
+	}
+	return unlock
+}
+
+// LocalLocker is a Locker for a single process, e.g. for tests.
+type LocalLocker struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+}
+
+// NewLocalLocker returns an empty LocalLocker.
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{locks: make(map[string]chan struct{})}
+}
+
+func (l *LocalLocker) Lock(ctx context.Context, key string) (func(), error) {
+	for {
+		l.mu.Lock()
+		held, ok := l.locks[key]
+		if !ok {
+			done := make(chan struct{})
+			l.locks[key] = done
+			l.mu.Unlock()
+
+			var once sync.Once
+			return func() {
+				once.Do(func() {
+					l.mu.Lock()
+					delete(l.locks, key)
+					l.mu.Unlock()
+					close(done)
+				})
+			}, nil
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-held:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package stampede_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocker(t *testing.T) {
+	ctx := context.Background()
+	store := stampede.NewMemoryStore()
+	locker := stampede.NewLocalLocker()
+
+	var calls int32
+	fetch := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return "v", nil
+	}
+
+	// separate caches stand in for separate instances
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		c := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithStore(store, stampede.JSONCodec{}), stampede.WithLocker(locker))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := c.Get(ctx, "k", fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, "v", val)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,22 @@
+package stampede
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultMemoizeSize is the number of results kept by a function wrapped with Memoize.
+const DefaultMemoizeSize = 1024
+
+// Memoize wraps fn with a stampede protected cache: results are cached per key, served
+// stale for up to ttl while being refreshed after freshFor, and concurrent calls for the
+// same key share a single call of fn.
+func Memoize[K comparable, V any](fn func(ctx context.Context, key K) (V, error), freshFor, ttl time.Duration, opts ...Option) func(ctx context.Context, key K) (V, error) {
+	cache := NewCacheKV[K, V](DefaultMemoizeSize, freshFor, ttl, opts...)
+
+	return func(ctx context.Context, key K) (V, error) {
+		return cache.GetContext(ctx, key, func(ctx context.Context) (V, error) {
+			return fn(ctx, key)
+		})
+	}
+}
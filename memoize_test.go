@@ -0,0 +1,46 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoize(t *testing.T) {
+	var calls int64
+	square := stampede.Memoize(func(ctx context.Context, n int) (string, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		if n < 0 {
+			return "", errors.New("negative")
+		}
+		return strconv.Itoa(n * n), nil
+	}, time.Minute, time.Minute)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := square(ctx, 3)
+			assert.NoError(t, err)
+			assert.Equal(t, "9", v)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), atomic.LoadInt64(&calls))
+
+	_, err := square(ctx, -1)
+	assert.Error(t, err)
+	_, err = square(ctx, -1)
+	assert.Error(t, err)
+	assert.Equal(t, int64(3), atomic.LoadInt64(&calls))
+}
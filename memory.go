@@ -0,0 +1,133 @@
+package stampede
+
+import "sync/atomic"
+
+// Sizer is implemented by values that know their approximate size in memory, which
+// counts towards the limits of WithWatermarks. Values not implementing Sizer have a
+// size of 1, so without any Sizer the watermarks limit the number of entries.
+type Sizer interface {
+	Size() int64
+}
+
+func sizeOf(v any) int64 {
+	if s, ok := v.(Sizer); ok {
+		return s.Size()
+	}
+	return 1
+}
+
+// Size returns the total size of all cached entries.
+func (c *Cache[K, V]) Size() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.size
+}
+
+// add stores entry, enforcing the watermarks. c.mu must be held.
+func (c *Cache[K, V]) add(ck cacheKey[K], entry value[K, V]) {
+	old, exists := c.values.Peek(ck)
+	_, pinned := c.pinned[ck]
+	if !exists && pinned {
+		if p := c.pinned[ck]; p != nil {
+			old, exists = *p, true
+			c.pinned[ck] = nil
+		}
+	}
+	if !exists && !pinned && c.batch == nil && c.softLimit > 0 && c.size >= c.softLimit {
+		return
+	}
+	if exists {
+		c.size -= old.size
+		c.untag(ck, old.tags())
+		// refreshes are not reads, see WithMaxIdle
+		if c.maxIdle > 0 && old.ext != entry.ext {
+			entry.ext.access = atomic.LoadInt64(&old.ext.access)
+		}
+	}
+
+	c.values.Add(ck, entry)
+	c.size += entry.size
+	c.tag(ck, entry.tags())
+	if c.softInvalidating {
+		c.invalidated(ck, entry)
+	} else {
+		c.emit(EventSet, ck, entry)
+	}
+
+	if c.batch == nil {
+		c.limit(ck)
+	}
+}
+
+// limit evicts entries once the size of the cache exceeds the hard limit, keeping the
+// entry of keep, just added. c.mu must be held.
+func (c *Cache[K, V]) limit(keep cacheKey[K]) {
+	if c.hardLimit > 0 && c.size > c.hardLimit {
+		target := c.softLimit
+		if target <= 0 {
+			target = c.hardLimit
+		}
+		c.evictTo(target, keep)
+	}
+}
+
+// evictTo evicts the least recently used entries until the size of the cache is at most
+// target. The entry of keep, just added, the entries of the batch being added and pinned
+// entries are kept. c.mu must be held.
+func (c *Cache[K, V]) evictTo(target int64, keep cacheKey[K]) {
+	for _, ck := range c.values.Keys() {
+		if c.size <= target {
+			return
+		}
+		if _, pinned := c.pinned[ck]; pinned || ck == keep {
+			continue
+		}
+		if _, batched := c.batch[ck]; batched {
+			continue
+		}
+		c.values.Remove(ck)
+	}
+}
+
+// admitMany reports whether the entries of batch, by their sizes, can all be added
+// together: without the soft limit refusing any of their keys, and without the others
+// evicting any of them for the size of the lru or the hard limit. c.mu must be held.
+func (c *Cache[K, V]) admitMany(batch map[cacheKey[K]]int64) bool {
+	if len(batch) > c.capacity {
+		return false
+	}
+	var size int64
+	for ck, s := range batch {
+		size += s
+		if c.softLimit <= 0 || c.size < c.softLimit {
+			continue
+		}
+		_, exists := c.values.Peek(ck)
+		_, pinned := c.pinned[ck]
+		if !exists && !pinned {
+			return false
+		}
+	}
+	return c.hardLimit <= 0 || size <= c.hardLimit
+}
+
+// onEvict is called by the lru for every removed entry, with c.mu held. Pinned entries
+// are kept outside the lru instead, unless they are invalidated.
+func (c *Cache[K, V]) onEvict(ck cacheKey[K], entry value[K, V]) {
+	if _, pinned := c.pinned[ck]; pinned && !c.invalidating {
+		c.pinned[ck] = &entry
+		return
+	}
+	c.evicted(ck, entry)
+}
+
+// evicted accounts for the removal of entry. c.mu must be held.
+func (c *Cache[K, V]) evicted(ck cacheKey[K], entry value[K, V]) {
+	c.size -= entry.size
+	c.untag(ck, entry.tags())
+	if c.invalidating {
+		c.invalidated(ck, entry)
+	} else {
+		c.emit(EventEvict, ck, entry)
+	}
+}
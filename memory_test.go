@@ -0,0 +1,71 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+type blob []byte
+
+func (b blob) Size() int64 {
+	return int64(len(b))
+}
+
+func TestWatermarks(t *testing.T) {
+	ctx := context.Background()
+	cache := stampede.NewCacheKV[string, blob](64, time.Minute, time.Minute, stampede.WithWatermarks(10, 20))
+
+	set := func(key string, size int) {
+		_, _, err := cache.Set(ctx, key, func() (blob, error) { return make(blob, size), nil })
+		assert.NoError(t, err)
+	}
+
+	set("a", 4)
+	set("b", 4)
+	set("c", 4)
+	assert.Equal(t, int64(12), cache.Size())
+
+	// above the soft limit new keys are not admitted, but existing ones are refreshed
+	set("d", 1)
+	_, ok := cache.Peek("d")
+	assert.False(t, ok)
+	set("a", 6)
+	assert.Equal(t, int64(14), cache.Size())
+
+	// above the hard limit entries are evicted down to the soft limit
+	set("c", 12)
+	assert.Equal(t, []string{"c"}, cache.Keys())
+	assert.Equal(t, int64(12), cache.Size())
+}
+
+func TestWatermarksHardOnly(t *testing.T) {
+	ctx := context.Background()
+	cache := stampede.NewCacheKV[string, blob](64, time.Minute, time.Minute, stampede.WithWatermarks(0, 10))
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		cache.Set(ctx, key, func() (blob, error) { return make(blob, 3), nil })
+	}
+	// only the oldest entry is evicted to get back to the hard limit
+	assert.Equal(t, []string{"b", "c", "d"}, cache.Keys())
+	assert.Equal(t, int64(9), cache.Size())
+}
+
+func TestWatermarksPinned(t *testing.T) {
+	ctx := context.Background()
+	cache := stampede.NewCacheKV[string, blob](64, time.Minute, time.Minute, stampede.WithWatermarks(0, 10))
+
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Pin(key)
+		cache.Set(ctx, key, func() (blob, error) { return make(blob, 3), nil })
+	}
+	cache.Set(ctx, "d", func() (blob, error) { return make(blob, 1), nil })
+	cache.Set(ctx, "e", func() (blob, error) { return make(blob, 1), nil })
+
+	// pinned entries stay, and only the unpinned entries needed are evicted
+	assert.Equal(t, []string{"a", "b", "c", "e"}, cache.Keys())
+	assert.Equal(t, int64(10), cache.Size())
+}
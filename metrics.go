@@ -0,0 +1,80 @@
+package stampede
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RouteStats counts the requests of a route by how they were served.
+type RouteStats struct {
+	Hits      int64 // served from cache
+	Stale     int64 // served from cache past their freshness
+	Coalesced int64 // served the response of a concurrent request
+	Origin    int64 // served by the origin handler
+}
+
+// RouteMetrics counts the requests served by the middleware of HandlerWithMetrics, or of
+// NewHandler with WithRouteMetrics, per route. Routes are labeled by their pattern, e.g.
+// "/products/{id}", rather than their raw path, to bound the cardinality of the labels.
+type RouteMetrics struct {
+	route func(r *http.Request) string
+
+	mu     sync.Mutex
+	routes map[string]*RouteStats
+}
+
+// NewRouteMetrics returns metrics labeling every request with route.
+func NewRouteMetrics(route func(r *http.Request) string) *RouteMetrics {
+	return &RouteMetrics{route: route, routes: make(map[string]*RouteStats)}
+}
+
+// HandlerWithMetrics is like Handler, but counts the requests it serves in m.
+func HandlerWithMetrics(cacheSize int, ttl time.Duration, m *RouteMetrics, paths ...string) func(next http.Handler) http.Handler {
+	return NewHandler(cacheSize, ttl, WithPaths(paths...), WithRouteMetrics(m))
+}
+
+// Routes returns a snapshot of the stats of every route.
+func (m *RouteMetrics) Routes() map[string]RouteStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	routes := make(map[string]RouteStats, len(m.routes))
+	for route, s := range m.routes {
+		routes[route] = *s
+	}
+	return routes
+}
+
+type routeOutcome int
+
+const (
+	routeHit routeOutcome = iota
+	routeStale
+	routeCoalesced
+	routeOrigin
+)
+
+func (m *RouteMetrics) observe(r *http.Request, o routeOutcome) {
+	if m == nil {
+		return
+	}
+	route := m.route(r)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.routes[route]
+	if s == nil {
+		s = &RouteStats{}
+		m.routes[route] = s
+	}
+	switch o {
+	case routeHit:
+		s.Hits++
+	case routeStale:
+		s.Stale++
+	case routeCoalesced:
+		s.Coalesced++
+	case routeOrigin:
+		s.Origin++
+	}
+}
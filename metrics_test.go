@@ -0,0 +1,54 @@
+package stampede_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteMetrics(t *testing.T) {
+	m := stampede.NewRouteMetrics(func(r *http.Request) string {
+		if strings.HasPrefix(r.URL.Path, "/products/") {
+			return "/products/{id}"
+		}
+		return r.URL.Path
+	})
+
+	release := make(chan struct{})
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-release
+		}
+		w.Write([]byte("ok"))
+	})
+	h := stampede.HandlerWithMetrics(16, time.Minute, m)(app)
+	get := func(path string) {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	get("/products/1")
+	get("/products/1")
+	get("/products/2")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			get("/slow")
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	routes := m.Routes()
+	assert.Equal(t, stampede.RouteStats{Hits: 1, Origin: 2}, routes["/products/{id}"])
+	assert.Equal(t, stampede.RouteStats{Coalesced: 2, Origin: 1}, routes["/slow"])
+}
@@ -0,0 +1,56 @@
+package stampede
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// SetReadOnly toggles read-only mode. A read-only cache keeps serving cached entries,
+// including expired ones, but neither refreshes nor admits entries; misses still call
+// the origin, coalesced as usual.
+func (c *Cache[K, V]) SetReadOnly(readOnly bool) {
+	var v int32
+	if readOnly {
+		v = 1
+	}
+	atomic.StoreInt32(&c.readOnly, v)
+}
+
+// ReadOnly reports whether the cache is in read-only mode, see SetReadOnly.
+func (c *Cache[K, V]) ReadOnly() bool {
+	return atomic.LoadInt32(&c.readOnly) == 1
+}
+
+// Disable takes the cache out of the serving path: every get calls the origin, and
+// nothing is read from or written to the cache or its store. Concurrent calls for the
+// same key are still coalesced, unless WithUncoalescedBypass is given.
+func (c *Cache[K, V]) Disable() {
+	atomic.StoreInt32(&c.disabled, 1)
+}
+
+// Enable puts a disabled cache back into the serving path.
+func (c *Cache[K, V]) Enable() {
+	atomic.StoreInt32(&c.disabled, 0)
+}
+
+// Disabled reports whether the cache is disabled, see Disable.
+func (c *Cache[K, V]) Disabled() bool {
+	return atomic.LoadInt32(&c.disabled) == 1
+}
+
+// bypass calls the origin for key without touching the cache.
+func (c *Cache[K, V]) bypass(ctx context.Context, key K, ck cacheKey[K], fn FetchFunc[V]) (V, error) {
+	fetch := func() (V, error) {
+		ctx, endFetch := c.startFetch(ctx, ck)
+		v, err := c.fetch(ctx, key, fn)
+		endFetch(err)
+		return v, err
+	}
+	if c.uncoalescedBypass {
+		return fetch()
+	}
+	endWait := c.startWait(ctx, ck)
+	v, err, _ := c.callGroup.Do(ck, fetch)
+	endWait(err)
+	return v, err
+}
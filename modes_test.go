@@ -0,0 +1,79 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnly(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, 10*time.Millisecond, 20*time.Millisecond)
+
+	calls := 0
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	_, err := c.Get(ctx, "cached", fetch)
+	assert.NoError(t, err)
+
+	c.SetReadOnly(true)
+	assert.True(t, c.ReadOnly())
+	time.Sleep(30 * time.Millisecond)
+
+	// expired entries are served without refreshing them
+	val, err := c.GetFresh(ctx, "cached", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+	val, _, err = c.Set(ctx, "cached", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+	assert.Equal(t, 1, calls)
+
+	// misses are fetched, but not admitted
+	val, err = c.Get(ctx, "missing", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, val)
+	_, ok := c.Peek("missing")
+	assert.False(t, ok)
+
+	c.SetReadOnly(false)
+	val, err = c.GetFresh(ctx, "cached", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, val)
+}
+
+func TestDisable(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute)
+
+	calls := 0
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	_, err := c.Get(ctx, "k", fetch)
+	assert.NoError(t, err)
+
+	c.Disable()
+	assert.True(t, c.Disabled())
+	for i := 2; i <= 3; i++ {
+		val, err := c.Get(ctx, "k", fetch)
+		assert.NoError(t, err)
+		assert.Equal(t, i, val)
+	}
+	_, _, err = c.Set(ctx, "k", fetch)
+	assert.NoError(t, err)
+
+	// nothing was written while disabled
+	c.Enable()
+	val, err := c.Get(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+}
@@ -0,0 +1,57 @@
+package stampede
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Name returns the name of the cache, see WithName.
+func (c *Cache[K, V]) Name() string {
+	return c.name
+}
+
+type cacheNameKey struct{}
+
+// CacheName returns the name of the cache that passed ctx to its Tracer, e.g. to label
+// spans, or "" if the cache is not named. See WithName.
+func CacheName(ctx context.Context) string {
+	name, _ := ctx.Value(cacheNameKey{}).(string)
+	return name
+}
+
+// withName returns ctx carrying the name of the cache, see CacheName.
+func (c *Cache[K, V]) withName(ctx context.Context) context.Context {
+	if c.name == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, cacheNameKey{}, c.name)
+}
+
+// errorf formats an error of the cache, naming the cache if it is named.
+func (c *Cache[K, V]) errorf(format string, args ...any) error {
+	if c.name == "" {
+		return fmt.Errorf("stampede: "+format, args...)
+	}
+	return fmt.Errorf("stampede: cache %q: "+format, append([]any{c.name}, args...)...)
+}
+
+// named wraps err, a sentinel error of the package, with the name of the cache if it is
+// named, so the message names the cache while errors.Is still matches err.
+func (c *Cache[K, V]) named(err error) error {
+	if c.name == "" || err == nil {
+		return err
+	}
+	return &namedError{name: c.name, err: err}
+}
+
+type namedError struct {
+	name string
+	err  error
+}
+
+func (e *namedError) Error() string {
+	return fmt.Sprintf("stampede: cache %q: %s", e.name, strings.TrimPrefix(e.err.Error(), "stampede: "))
+}
+
+func (e *namedError) Unwrap() error { return e.err }
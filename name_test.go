@@ -0,0 +1,65 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+type nameTracer struct {
+	names []string
+}
+
+func (t *nameTracer) StartFetch(ctx context.Context) (context.Context, func(error)) {
+	t.names = append(t.names, stampede.CacheName(ctx))
+	return ctx, func(error) {}
+}
+
+func (t *nameTracer) StartWait(ctx, fetch context.Context) func(error) {
+	return func(error) {}
+}
+
+type brokenStore struct {
+	stampede.Store
+}
+
+func (brokenStore) Ping(context.Context) error { return errors.New("unreachable") }
+
+func TestName(t *testing.T) {
+	tracer := &nameTracer{}
+	c := stampede.NewCacheKV[string, int](16, time.Minute, time.Minute, stampede.WithName("catalog"), stampede.WithTracer(tracer))
+	defer c.Close()
+
+	assert.Equal(t, "catalog", c.Name())
+	c.Get(context.Background(), "a", func() (int, error) { return 1, nil })
+	assert.Equal(t, []string{"catalog"}, tracer.names)
+	assert.Equal(t, "catalog", c.Stats().Name)
+	assert.Equal(t, "catalog", c.Stats().Add(c.Stats()).Name)
+	assert.Equal(t, "", c.Stats().Add(stampede.Stats{Name: "other"}).Name)
+
+	unnamed := stampede.NewCacheKV[string, int](16, time.Minute, time.Minute)
+	defer unnamed.Close()
+	assert.Equal(t, "", unnamed.Name())
+}
+
+func TestNameErrors(t *testing.T) {
+	c := stampede.NewCacheKV[string, int](16, time.Minute, time.Minute, stampede.WithName("catalog"), stampede.WithStore(brokenStore{}, stampede.JSONCodec{}))
+	defer c.Close()
+
+	assert.EqualError(t, c.Healthy(context.Background()), `stampede: cache "catalog": store: unreachable`)
+
+	// sentinel errors name the cache, and still match
+	ctx := stampede.WithLowPriority(context.Background())
+	_, err := c.Get(ctx, "a", func() (int, error) { return 1, nil })
+	assert.ErrorIs(t, err, stampede.ErrLowPriorityMiss)
+	assert.EqualError(t, err, `stampede: cache "catalog": low priority miss`)
+
+	_, err = c.Import(context.Background(), strings.NewReader("\x05garbage"))
+	assert.ErrorIs(t, err, stampede.ErrInvalidExport)
+	assert.Contains(t, err.Error(), `cache "catalog"`)
+}
@@ -0,0 +1,138 @@
+package stampede
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Normalizer rewrites a cache key, so that logically identical keys map to the same
+// cache entry. Normalizers are chained with WithKeyNormalizers for the programmatic
+// api, and with NormalizedKeyFunc for the http middleware.
+type Normalizer func(key string) string
+
+// Normalize applies the normalizers to key, in order.
+func Normalize(key string, normalizers ...Normalizer) string {
+	for _, n := range normalizers {
+		key = n(key)
+	}
+	return key
+}
+
+// LowerCase lowercases the whole key.
+func LowerCase() Normalizer {
+	return toLower
+}
+
+// SortQuery sorts the query parameters of an url-like key, so that parameter order
+// doesn't matter. Parameters are kept as they are otherwise, including their escaping.
+func SortQuery() Normalizer {
+	return func(key string) string {
+		path, query, ok := strings.Cut(key, "?")
+		if !ok || query == "" {
+			return key
+		}
+		params := strings.Split(query, "&")
+		sort.Strings(params)
+		return path + "?" + strings.Join(params, "&")
+	}
+}
+
+// StripQuery removes the named query parameters from an url-like key, e.g. tracking
+// parameters such as utm_source. Without names, the whole query is removed.
+func StripQuery(names ...string) Normalizer {
+	strip := map[string]struct{}{}
+	for _, name := range names {
+		strip[name] = struct{}{}
+	}
+
+	return func(key string) string {
+		path, query, ok := strings.Cut(key, "?")
+		if !ok {
+			return key
+		}
+		if len(strip) == 0 {
+			return path
+		}
+
+		all := strings.Split(query, "&")
+		params := all[:0]
+		for _, param := range all {
+			name, _, _ := strings.Cut(param, "=")
+			if unescaped, err := url.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+			if _, ok := strip[name]; !ok && param != "" {
+				params = append(params, param)
+			}
+		}
+		if len(params) == 0 {
+			return path
+		}
+		return path + "?" + strings.Join(params, "&")
+	}
+}
+
+// TrimTrailingSlash removes trailing slashes from the path of an url-like key, except
+// for the root path.
+func TrimTrailingSlash() Normalizer {
+	return func(key string) string {
+		path, query, ok := strings.Cut(key, "?")
+		trimmed := strings.TrimRight(path, "/")
+		if trimmed == "" && path != "" {
+			trimmed = "/"
+		}
+		if !ok {
+			return trimmed
+		}
+		return trimmed + "?" + query
+	}
+}
+
+// NormalizedKeyFunc returns an http cache key function, which builds the key from the
+// normalized request url path and query, and the request body.
+func NormalizedKeyFunc(normalizers ...Normalizer) func(r *http.Request) uint64 {
+	return func(r *http.Request) uint64 {
+		var buf []byte
+		if r.Body != nil {
+			buf, _ = io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewBuffer(buf))
+		}
+
+		key := r.URL.EscapedPath()
+		if r.URL.RawQuery != "" {
+			key += "?" + r.URL.RawQuery
+		}
+		return BytesToHash([]byte(Normalize(key, normalizers...)), buf)
+	}
+}
+
+func (c *Cache[K, V]) normalizeKey(key K) K {
+	if len(c.normalizers) == 0 {
+		return key
+	}
+	if s, ok := any(key).(string); ok {
+		return any(Normalize(s, c.normalizers...)).(K)
+	}
+	return key
+}
+
+// toLower lowercases s for case-insensitive matching. Strings that are not
+// valid utf-8 are only lowercased in their ascii range, as strings.ToLower would
+// replace every invalid byte with utf8.RuneError and make distinct strings collide.
+func toLower(s string) string {
+	if utf8.ValidString(s) {
+		return strings.ToLower(s)
+	}
+	b := []byte(s)
+	for i, c := range b {
+		if 'A' <= c && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,59 @@
+package stampede_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		normalizer stampede.Normalizer
+		in, out    string
+	}{
+		{stampede.LowerCase(), "/Users/ABC?Q=1", "/users/abc?q=1"},
+		{stampede.SortQuery(), "/users?b=2&a=1&a=0", "/users?a=0&a=1&b=2"},
+		{stampede.SortQuery(), "/users", "/users"},
+		{stampede.StripQuery(), "/users?b=2&a=1", "/users"},
+		{stampede.StripQuery("utm_source", "utm medium"), "/users?utm_source=x&id=1&utm+medium=y", "/users?id=1"},
+		{stampede.StripQuery("utm_source"), "/users?utm_source=x", "/users"},
+		{stampede.TrimTrailingSlash(), "/users//?id=1", "/users?id=1"},
+		{stampede.TrimTrailingSlash(), "/", "/"},
+		{stampede.TrimTrailingSlash(), "", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.out, stampede.Normalize(tt.in, tt.normalizer), tt.in)
+	}
+}
+
+func TestKeyNormalizers(t *testing.T) {
+	ctx := context.Background()
+	cache := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute,
+		stampede.WithKeyNormalizers(stampede.LowerCase(), stampede.TrimTrailingSlash(), stampede.SortQuery()))
+
+	var calls int
+	fetch := func() (string, error) {
+		calls++
+		return "v", nil
+	}
+	for _, key := range []string{"/users?a=1&b=2", "/Users/?b=2&a=1", "/USERS?a=1&b=2"} {
+		_, err := cache.Get(ctx, key, fetch)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []string{"/users?a=1&b=2"}, cache.Keys())
+}
+
+func TestNormalizedKeyFunc(t *testing.T) {
+	keyFunc := stampede.NormalizedKeyFunc(stampede.LowerCase(), stampede.StripQuery("utm_source"), stampede.SortQuery())
+
+	k1 := keyFunc(httptest.NewRequest("GET", "/Users?b=2&a=1&utm_source=x", nil))
+	k2 := keyFunc(httptest.NewRequest("GET", "/users?a=1&b=2", nil))
+	k3 := keyFunc(httptest.NewRequest("GET", "/users?a=1&b=3", nil))
+	assert.Equal(t, k1, k2)
+	assert.NotEqual(t, k1, k3)
+}
@@ -0,0 +1,121 @@
+// Package objcache caches objects of object stores such as S3 or GCS with stampede.
+// Refreshes are conditional on the ETag of the cached object, so refreshing a large
+// object that didn't change is a metadata round trip rather than a full re-download.
+package objcache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dadav/stampede"
+)
+
+// ErrNotModified is returned by a Fetcher when the object still has the given etag.
+var ErrNotModified = errors.New("objcache: not modified")
+
+// Object is a fetched object.
+type Object struct {
+	Data        []byte
+	ETag        string
+	ContentType string
+}
+
+// Fetcher fetches the object with the given key. When etag is set, the fetch is
+// conditional and returns ErrNotModified if the object still has that etag. SDK based
+// fetchers map etag to e.g. the IfNoneMatch field of a s3 GetObjectInput.
+type Fetcher interface {
+	Fetch(ctx context.Context, key, etag string) (*Object, error)
+}
+
+// FetcherFunc adapts a function to a Fetcher.
+type FetcherFunc func(ctx context.Context, key, etag string) (*Object, error)
+
+func (f FetcherFunc) Fetch(ctx context.Context, key, etag string) (*Object, error) {
+	return f(ctx, key, etag)
+}
+
+// HTTPFetcher returns a Fetcher that GETs keys as urls using If-None-Match, e.g.
+// presigned s3 urls or public gcs objects. A nil client uses http.DefaultClient.
+func HTTPFetcher(client *http.Client) Fetcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return FetcherFunc(func(ctx context.Context, key, etag string) (*Object, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, key, nil)
+		if err != nil {
+			return nil, err
+		}
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		switch {
+		case resp.StatusCode == http.StatusNotModified:
+			return nil, ErrNotModified
+		case resp.StatusCode != http.StatusOK:
+			return nil, fmt.Errorf("objcache: fetch %s: unexpected status %d", key, resp.StatusCode)
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return &Object{
+			Data:        data,
+			ETag:        resp.Header.Get("ETag"),
+			ContentType: resp.Header.Get("Content-Type"),
+		}, nil
+	})
+}
+
+// Cache caches objects fetched by a Fetcher.
+type Cache struct {
+	fetcher Fetcher
+	cache   *stampede.Cache[string, *Object]
+}
+
+// New returns a Cache fetching objects with f.
+func New(f Fetcher, size int, freshFor, ttl time.Duration, opts ...stampede.Option) *Cache {
+	return &Cache{
+		fetcher: f,
+		cache:   stampede.NewCacheKV[string, *Object](size, freshFor, ttl, opts...),
+	}
+}
+
+// Get returns the object with the given key, served from cache when possible.
+func (c *Cache) Get(ctx context.Context, key string) (*Object, error) {
+	return c.cache.GetContext(ctx, key, c.fetch(key))
+}
+
+// GetFresh is like Get, but never serves stale objects.
+func (c *Cache) GetFresh(ctx context.Context, key string) (*Object, error) {
+	return c.cache.GetFreshContext(ctx, key, c.fetch(key))
+}
+
+func (c *Cache) fetch(key string) stampede.FetchFunc[*Object] {
+	return func(ctx context.Context) (*Object, error) {
+		prev, ok := c.cache.Peek(key)
+
+		var etag string
+		if ok && prev != nil {
+			etag = prev.ETag
+		}
+
+		obj, err := c.fetcher.Fetch(ctx, key, etag)
+		if errors.Is(err, ErrNotModified) && etag != "" {
+			return prev, nil
+		}
+		return obj, err
+	}
+}
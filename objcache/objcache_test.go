@@ -0,0 +1,42 @@
+package objcache_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede/objcache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditionalRefresh(t *testing.T) {
+	var downloads, notModified int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		downloads++
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("large object"))
+	}))
+	defer ts.Close()
+
+	c := objcache.New(objcache.HTTPFetcher(ts.Client()), 16, 0, time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		obj, err := c.GetFresh(ctx, ts.URL+"/bucket/key")
+		assert.NoError(t, err)
+		assert.Equal(t, "large object", string(obj.Data))
+		assert.Equal(t, `"v1"`, obj.ETag)
+	}
+	assert.Equal(t, 1, downloads)
+	assert.Equal(t, 2, notModified)
+
+	_, err := c.Get(ctx, ts.URL+"/%zz")
+	assert.Error(t, err)
+}
@@ -0,0 +1,495 @@
+package stampede
+
+import (
+	"context"
+	"time"
+)
+
+// Option configures a Cache created with NewCache or NewCacheKV.
+type Option func(*options)
+
+type options struct {
+	lifetime *Lifetime
+
+	keyHash    KeyHash
+	retainKeys bool
+
+	normalizers []Normalizer
+
+	minTTL time.Duration
+	maxTTL time.Duration
+
+	softLimit int64
+	hardLimit int64
+
+	contextPolicy ContextPolicy
+
+	tracer Tracer
+
+	name     string
+	keyClass func(key any) string
+
+	store         Store
+	codec         Codec
+	storeEnvelope bool
+	schema        uint32
+	copyOnRead    bool
+
+	shadow       bool
+	shadowReport func(key, cached any, hit bool)
+
+	uncoalescedBypass bool
+
+	maxWaiters int
+
+	collapseWindow time.Duration
+
+	locker Locker
+
+	leaseTTL  time.Duration
+	leaseWait time.Duration
+
+	errorPolicy     func(err error) ErrorAction
+	negativeTTL     time.Duration
+	circuitCooldown time.Duration
+
+	workers   int
+	queueSize int
+	overflow  Overflow
+
+	budgetMultiple float64
+	budgetFloor    time.Duration
+
+	missingCapacity int
+	missingRotate   time.Duration
+
+	fallbackFn func(ctx context.Context, key any) (any, error)
+
+	versionFn func(ctx context.Context, key any) (string, error)
+
+	interning bool
+
+	deadlineAware bool
+
+	hedgeAfter time.Duration
+
+	maxErrorRate float64
+
+	secondary      func(ctx context.Context, key any) (any, error)
+	secondaryAfter time.Duration
+
+	retryBackoff    time.Duration
+	retryMaxBackoff time.Duration
+
+	slidingMax time.Duration
+	maxIdle    time.Duration
+
+	warmKeys int
+
+	replicaID     string
+	staggerWindow time.Duration
+
+	rampFor  time.Duration
+	rampRate int
+
+	lkg      Store
+	lkgCodec Codec
+	lkgTTL   time.Duration
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithLifetime sets the lifetime of values, instead of the freshFor and ttl given to
+// NewCacheKV.
+func WithLifetime(l Lifetime) Option {
+	return func(o *options) {
+		o.lifetime = &l
+	}
+}
+
+// WithKeyHashing stores cache keys as fixed-size digests instead of the keys themselves,
+// which bounds the memory used by enormous keys such as full SQL queries or long urls.
+func WithKeyHashing(h KeyHash) Option {
+	return func(o *options) {
+		o.keyHash = h
+	}
+}
+
+// WithRetainedKeys keeps the original key next to each hashed entry, so Keys can list
+// them. Meant for debugging only, as it gives up the memory savings of key hashing.
+func WithRetainedKeys() Option {
+	return func(o *options) {
+		o.retainKeys = true
+	}
+}
+
+// WithKeyNormalizers normalizes string keys with the given normalizers before they are
+// looked up or stored.
+func WithKeyNormalizers(normalizers ...Normalizer) Option {
+	return func(o *options) {
+		o.normalizers = append(o.normalizers, normalizers...)
+	}
+}
+
+// WithTTLBounds clamps the TTL of values implementing TTLer to [min, max]. A zero bound
+// is not enforced.
+func WithTTLBounds(min, max time.Duration) Option {
+	return func(o *options) {
+		o.minTTL = min
+		o.maxTTL = max
+	}
+}
+
+// WithWatermarks limits the total size of the cache, see Sizer. Once the soft limit is
+// reached, new keys are no longer admitted and only existing entries are refreshed.
+// Once the hard limit is exceeded, the least recently used entries are evicted until
+// the cache is back at the soft limit, or at the hard limit without a soft limit.
+// Pinned entries are never evicted. A zero limit is not enforced.
+func WithWatermarks(soft, hard int64) Option {
+	return func(o *options) {
+		o.softLimit = soft
+		o.hardLimit = hard
+	}
+}
+
+// WithContextPolicy sets how the context of background refreshes is built from the
+// context of the triggering request. Defaults to DetachContext().
+func WithContextPolicy(p ContextPolicy) Option {
+	return func(o *options) {
+		o.contextPolicy = p
+	}
+}
+
+// WithTracer traces origin fetches, and the callers waiting on them, with t.
+func WithTracer(t Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+// WithName names the cache, so applications with several caches can tell them apart.
+// The name labels the goroutines of origin fetches for pprof, is passed to the Tracer,
+// see CacheName, is reported in Stats and prefixes the errors of the cache.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithKeyClass classifies keys, e.g. by their prefix, without the cardinality of the
+// keys themselves. Key classes are used to label the goroutines of origin fetches for
+// pprof, and to break down hit rates in Stats.
+func WithKeyClass(fn func(key any) string) Option {
+	return func(o *options) {
+		o.keyClass = fn
+	}
+}
+
+// WithStore adds an external, shared store like Redis as second tier behind the
+// in-memory cache. Values are encoded with codec.
+func WithStore(s Store, codec Codec) Option {
+	return func(o *options) {
+		o.store = s
+		o.codec = codec
+	}
+}
+
+// WithStoreEnvelope wraps store entries in an Envelope carrying their freshness, keeping
+// them in the store for the full ttl. Stale store entries are refreshed from the origin,
+// and served while the origin fails.
+func WithStoreEnvelope() Option {
+	return func(o *options) {
+		o.storeEnvelope = true
+	}
+}
+
+// WithSchemaVersion namespaces store entries by the schema version of V. Entries written
+// with another version are misses, so a deployment changing the layout of V never
+// decodes incompatible entries left in the store by the previous one.
+func WithSchemaVersion(version uint32) Option {
+	return func(o *options) {
+		o.schema = version
+	}
+}
+
+// WithCopyOnRead returns a copy of cached values implementing Cloner to every caller,
+// so callers can't mutate the values shared with other callers.
+func WithCopyOnRead() Option {
+	return func(o *options) {
+		o.copyOnRead = true
+	}
+}
+
+// WithShadowMode makes the cache call the origin on every Get, while still filling the
+// cache and counting what would have been a hit, see Stats. report, if not nil, is
+// called with every key and the cached value that would have been returned, to
+// validate key functions and hit rates before enabling caching.
+func WithShadowMode(report func(key, cached any, hit bool)) Option {
+	return func(o *options) {
+		o.shadow = true
+		o.shadowReport = report
+	}
+}
+
+// WithUncoalescedBypass makes a disabled cache call the origin for every caller, instead
+// of coalescing concurrent calls for the same key, see Cache.Disable.
+func WithUncoalescedBypass() Option {
+	return func(o *options) {
+		o.uncoalescedBypass = true
+	}
+}
+
+// WithMaxWaiters caps the number of callers waiting on a single in-flight fetch. Callers
+// beyond the cap get the stale value if there is one, and ErrTooManyWaiters otherwise.
+func WithMaxWaiters(n int) Option {
+	return func(o *options) {
+		o.maxWaiters = n
+	}
+}
+
+// WithCollapseWindow makes misses arriving within window after a fetch of the same key
+// completed reuse its result, including errors, instead of fetching again. Deleting,
+// invalidating or publishing the key drops the result.
+func WithCollapseWindow(window time.Duration) Option {
+	return func(o *options) {
+		o.collapseWindow = window
+	}
+}
+
+// WithLocker serializes the origin fetches of a key across all instances sharing the
+// store with l. Without WithStore, l is not used.
+func WithLocker(l Locker) Option {
+	return func(o *options) {
+		o.locker = l
+	}
+}
+
+// WithErrorPolicy decides per error returned by the origin what the cache does with it,
+// see ErrorAction. Without a policy, errors are returned to the callers.
+func WithErrorPolicy(policy func(err error) ErrorAction) Option {
+	return func(o *options) {
+		o.errorPolicy = policy
+	}
+}
+
+// WithNegativeTTL sets how long errors are cached, see ErrorCache. Defaults to freshFor.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithCircuitCooldown sets how long the circuit stays open, see ErrorOpenCircuit.
+// Defaults to 5 seconds.
+func WithCircuitCooldown(cooldown time.Duration) Option {
+	return func(o *options) {
+		o.circuitCooldown = cooldown
+	}
+}
+
+// WithFallback returns the value of fn for key when the origin fails and the key has no
+// stale value, e.g. a default or a last known good value, so callers degrade gracefully.
+// Values of the wrong type, and errors of fn, return the error of the origin. Fallback
+// values are not cached.
+func WithFallback(fn func(ctx context.Context, key any) (any, error)) Option {
+	return func(o *options) {
+		o.fallbackFn = fn
+	}
+}
+
+// WithLastKnownGood writes every value fetched to s, encoded with codec and kept for ttl,
+// e.g. to a FileStore. The last known good value of a key is only served when the origin
+// fails and the key has no stale value, before WithFallback is consulted.
+func WithLastKnownGood(s Store, codec Codec, ttl time.Duration) Option {
+	return func(o *options) {
+		o.lkg = s
+		o.lkgCodec = codec
+		o.lkgTTL = ttl
+	}
+}
+
+// WithSlidingExpiration extends the lifetime of entries every time they are read while
+// fresh, so entries read often stay cached while idle ones age out, e.g. sessions. An
+// entry is not extended beyond maxAge after it was fetched.
+func WithSlidingExpiration(maxAge time.Duration) Option {
+	return func(o *options) {
+		o.slidingMax = maxAge
+	}
+}
+
+// WithMaxIdle evicts entries that were not read for d, regardless of their ttl, to keep
+// the working set small when there are many more keys than are read regularly. Idle
+// entries are evicted by a background sweep every d/2, until the cache is closed.
+func WithMaxIdle(d time.Duration) Option {
+	return func(o *options) {
+		o.maxIdle = d
+	}
+}
+
+// WithVersionCheck makes every read verify the version of its key with fn, e.g. a
+// counter in a shared store bumped by every write, and fetch the key again if the
+// version changed since it was fetched. It trades a round trip per read for never
+// serving outdated values, and should only be used for caches that need it. A failing
+// fn fetches the key. With WithStore, writers must delete the key from the store before
+// bumping its version.
+func WithVersionCheck(fn func(ctx context.Context, key any) (string, error)) Option {
+	return func(o *options) {
+		o.versionFn = fn
+	}
+}
+
+// WithInterning deduplicates the string keys and tags of the cache, for workloads that
+// build identical keys for every request: each key is stored once, however many
+// structures of the cache refer to it, and never retains the memory of the request it
+// was built from. It costs a map lookup per call.
+func WithInterning() Option {
+	return func(o *options) {
+		o.interning = true
+	}
+}
+
+// WithDeadlineAwareness serves cached values, even stale or expired ones, to callers
+// whose context deadline is shorter than the p99 latency of recent fetches, instead of
+// starting a fetch that would likely time out. The value is refreshed in the
+// background.
+func WithDeadlineAwareness() Option {
+	return func(o *options) {
+		o.deadlineAware = true
+	}
+}
+
+// WithHedging starts a second, hedged fetch of a key if its fetch didn't complete
+// within after, and uses the first of the two to succeed. It cuts the tail latency of
+// origins with occasional slow responses, at the cost of more origin calls.
+func WithHedging(after time.Duration) Option {
+	return func(o *options) {
+		o.hedgeAfter = after
+	}
+}
+
+// WithSecondary fetches keys from the secondary loader fn, e.g. a replica or another
+// region, when the fetch function fails or doesn't return within timeout. A zero
+// timeout only fails over on errors. Values of the secondary loader are cached like any
+// other, and Cache.Source and Event tell which loader produced an entry. With
+// WithHedging, hedged fetches use the secondary loader.
+func WithSecondary(fn func(ctx context.Context, key any) (any, error), timeout time.Duration) Option {
+	return func(o *options) {
+		o.secondary = fn
+		o.secondaryAfter = timeout
+	}
+}
+
+// WithRefreshRetry retries failed background refreshes, after backoff and then twice as
+// long after every further failure, up to maxBackoff, so stale entries recover from
+// transient origin errors without waiting for the next get of their key. Keys are retried
+// by a background sweep until they are refreshed or expire. A zero maxBackoff doesn't
+// limit the backoff.
+func WithRefreshRetry(backoff, maxBackoff time.Duration) Option {
+	return func(o *options) {
+		o.retryBackoff = backoff
+		o.retryMaxBackoff = maxBackoff
+	}
+}
+
+// WithHealthThreshold makes Healthy fail once more than maxErrorRate, between 0 and 1,
+// of the recent fetches failed. Without a threshold, errors don't affect Healthy.
+func WithHealthThreshold(maxErrorRate float64) Option {
+	return func(o *options) {
+		o.maxErrorRate = maxErrorRate
+	}
+}
+
+// WithStoreWarm loads the values of the n hottest keys from the store in the background
+// when the cache is created, so freshly started instances don't all fall through to the
+// origin at once. Keys fetched in the meantime keep their fetched values.
+// The hottest keys are ranked by the gets of all instances sharing the store, which
+// merge their hits into an index in the store every minute and when they are closed.
+// Keys must be encodable with the codec of the store. Without WithStore, and for caches
+// whose keys may be of several types, like those of NewCache, it does nothing.
+func WithStoreWarm(n int) Option {
+	return func(o *options) {
+		o.warmKeys = n
+	}
+}
+
+// WithStartupRamp limits the origin fetches of the cache to perSecond for d after it is
+// created, to protect the origin while many instances start at once with empty caches.
+// Callers beyond the limit wait for their turn, with some jitter, or until their context
+// is done. Background refreshes of cached values are not limited.
+func WithStartupRamp(d time.Duration, perSecond int) Option {
+	return func(o *options) {
+		o.rampFor = d
+		o.rampRate = perSecond
+	}
+}
+
+// WithRefreshStagger makes the replicas sharing a store take turns refreshing stale keys,
+// instead of all refreshing a popular key as soon as it goes stale. Each replica, named
+// by its unique replicaID, waits for its turn within window after a key went stale
+// before refreshing it; the first replica's refresh reaches the store, the others find
+// it there. Stale values are served meanwhile. The window should be well within the
+// grace period of the values, ttl - freshFor.
+func WithRefreshStagger(replicaID string, window time.Duration) Option {
+	return func(o *options) {
+		o.replicaID = replicaID
+		o.staggerWindow = window
+	}
+}
+
+// WithStoreLeases writes a lease, kept for ttl, next to a key in the store while an
+// instance fetches it from the origin after a store miss, like memcache leases. Other
+// instances missing the key meanwhile serve its stale value from the store, or wait up
+// to wait for the value to land in the store, instead of fetching it in parallel. Unlike
+// WithLocker, leases don't need a lock provider, but don't exclude each other strictly.
+// Without WithStore, it does nothing.
+func WithStoreLeases(ttl, wait time.Duration) Option {
+	return func(o *options) {
+		o.leaseTTL = ttl
+		o.leaseWait = wait
+	}
+}
+
+// WithMissingFilter remembers the keys for which the origin returned ErrNotFound in a
+// rotating bloom filter of capacity keys, so fetches of these keys return ErrNotFound
+// without reaching the origin, e.g. against lookups of random nonexistent keys. Keys
+// are forgotten one to two rotate intervals after they were added, or earlier when
+// capacity keys were added since. About 1% of the other uncached keys are mistaken
+// for missing ones; cached keys are not affected. Purge clears the filter.
+func WithMissingFilter(capacity int, rotate time.Duration) Option {
+	return func(o *options) {
+		o.missingCapacity = capacity
+		o.missingRotate = rotate
+	}
+}
+
+// WithRefreshBudget gives background refreshes a deadline of multiple times the p95 of
+// the recent fetch durations, but at least floor, independent of the request that
+// triggered them. Before enough fetches were made, the deadline is floor, or none if
+// floor is 0. Refreshes running out of their budget are counted in
+// Stats.RefreshTimeouts; the stale value is served until the next refresh. Callers
+// waiting on a refresh that runs out of its budget fetch the value again themselves.
+func WithRefreshBudget(multiple float64, floor time.Duration) Option {
+	return func(o *options) {
+		o.budgetMultiple = multiple
+		o.budgetFloor = floor
+	}
+}
+
+// WithRefreshWorkers runs the background refreshes of the cache, e.g. stale-while-
+// revalidate refreshes and retries, on at most workers goroutines, so a burst of stale
+// keys doesn't start a burst of goroutines. Up to queue refreshes wait for a worker;
+// overflow decides what happens to the others. Close waits for the queued refreshes.
+// Stats reports the queued and dropped refreshes.
+func WithRefreshWorkers(workers, queue int, overflow Overflow) Option {
+	return func(o *options) {
+		o.workers = workers
+		o.queueSize = queue
+		o.overflow = overflow
+	}
+}
@@ -0,0 +1,51 @@
+// Package otelstampede traces stampede caches with OpenTelemetry. Every origin fetch is
+// recorded as a "stampede.fetch" span, and every caller waiting on it as a
+// "stampede.wait" span linked to the fetch span. Spans of named caches carry the
+// "stampede.cache" attribute, see stampede.WithName.
+package otelstampede
+
+import (
+	"context"
+
+	"github.com/dadav/stampede"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer returns a stampede.Tracer recording spans with t, for use with
+// stampede.WithTracer.
+func Tracer(t trace.Tracer) stampede.Tracer {
+	return tracer{t}
+}
+
+type tracer struct {
+	t trace.Tracer
+}
+
+func (t tracer) StartFetch(ctx context.Context) (context.Context, func(error)) {
+	ctx, span := t.t.Start(ctx, "stampede.fetch", attributes(ctx))
+	return ctx, end(span)
+}
+
+func (t tracer) StartWait(ctx, fetch context.Context) func(error) {
+	_, span := t.t.Start(ctx, "stampede.wait", trace.WithLinks(trace.LinkFromContext(fetch)), attributes(ctx))
+	return end(span)
+}
+
+func attributes(ctx context.Context) trace.SpanStartOption {
+	if name := stampede.CacheName(ctx); name != "" {
+		return trace.WithAttributes(attribute.String("stampede.cache", name))
+	}
+	return trace.WithAttributes()
+}
+
+func end(span trace.Span) func(error) {
+	return func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
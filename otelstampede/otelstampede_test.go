@@ -0,0 +1,84 @@
+package otelstampede_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/dadav/stampede/otelstampede"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type span struct {
+	name  string
+	id    trace.SpanID
+	links []trace.Link
+	attrs []attribute.KeyValue
+}
+
+// recorder records the started spans, which are non-recording spans with a valid
+// span context.
+type recorder struct {
+	noop.Tracer
+
+	mu    sync.Mutex
+	spans []span
+}
+
+func (r *recorder) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := trace.SpanID{byte(len(r.spans) + 1)}
+	cfg := trace.NewSpanStartConfig(opts...)
+	r.spans = append(r.spans, span{name: name, id: id, links: cfg.Links(), attrs: cfg.Attributes()})
+
+	ctx = trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  id,
+	}))
+	return ctx, trace.SpanFromContext(ctx)
+}
+
+func TestTracer(t *testing.T) {
+	r := &recorder{}
+	cache := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithTracer(otelstampede.Tracer(r)))
+
+	ctx := context.Background()
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.GetContext(ctx, "a", func(ctx context.Context) (string, error) {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			return "v", nil
+		})
+	}()
+	<-started
+
+	_, err := cache.Get(ctx, "a", func() (string, error) { return "other", nil })
+	assert.NoError(t, err)
+	<-done
+
+	assert.Len(t, r.spans, 2)
+	assert.Equal(t, "stampede.fetch", r.spans[0].name)
+	assert.Equal(t, "stampede.wait", r.spans[1].name)
+	assert.Len(t, r.spans[1].links, 1)
+	assert.Equal(t, r.spans[0].id, r.spans[1].links[0].SpanContext.SpanID())
+}
+
+func TestTracerName(t *testing.T) {
+	r := &recorder{}
+	cache := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithTracer(otelstampede.Tracer(r)), stampede.WithName("catalog"))
+
+	cache.Get(context.Background(), "a", func() (string, error) { return "v", nil })
+	if assert.Len(t, r.spans, 1) {
+		assert.Equal(t, []attribute.KeyValue{attribute.String("stampede.cache", "catalog")}, r.spans[0].attrs)
+	}
+}
@@ -0,0 +1,188 @@
+// Package pgnotify broadcasts the invalidations of stampede caches between instances
+// through Postgres LISTEN/NOTIFY, for deployments whose only shared infrastructure is
+// their database.
+//
+// Invalidations are published with pg_notify through database/sql, so the package
+// doesn't depend on a driver. Notifications are received by a Listener, which is
+// satisfied by a small adapter around the listener of the driver of choice, e.g.
+// pq.Listener or the WaitForNotification method of a pgx connection.
+//
+// Any session of the database can notify a channel, so publishers may sign their
+// notifications with a Keyring, verified by the Subscriber.
+package pgnotify
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// MaxPayload is the maximum size of a notification payload in Postgres. Invalidations
+// of more keys are split into several notifications.
+const MaxPayload = 8000
+
+// Execer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Notification is a notification received on a channel.
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// Listener receives the notifications of the channels it LISTENs to. Next blocks until
+// a notification arrives or ctx is done.
+type Listener interface {
+	Next(ctx context.Context) (Notification, error)
+}
+
+// Payload is the JSON payload of every notification.
+type Payload struct {
+	Keys []string `json:"keys"`
+
+	// Publisher identifies the publisher, Seq numbers its notifications, see Subscriber.
+	Publisher string `json:"pub,omitempty"`
+	Seq       uint64 `json:"seq,omitempty"`
+
+	// KeyID and Signature sign the payload, see Keyring.
+	KeyID     string `json:"kid,omitempty"`
+	Signature []byte `json:"sig,omitempty"`
+}
+
+// Publisher publishes invalidations to a channel.
+type Publisher struct {
+	db      Execer
+	channel string
+	id      string
+	seq     uint64
+
+	// Keyring signs the published payloads, if set.
+	Keyring *Keyring
+}
+
+// NewPublisher returns a publisher notifying channel through db.
+func NewPublisher(db Execer, channel string) *Publisher {
+	var id [8]byte
+	rand.Read(id[:])
+	return &Publisher{db: db, channel: channel, id: hex.EncodeToString(id[:])}
+}
+
+// Invalidate notifies the listeners of the channel that keys are invalid. Within a
+// transaction, the notifications are only delivered once it commits, so listeners never
+// invalidate keys before the writes invalidating them are visible. It returns
+// ErrNoCurrentKey for a Keyring without its current key.
+func (p *Publisher) Invalidate(ctx context.Context, keys ...string) error {
+	for len(keys) > 0 {
+		payload, n, err := p.encode(keys)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("pgnotify: key of %d bytes exceeds the payload limit", len(keys[0]))
+		}
+		if _, err := p.db.ExecContext(ctx, "SELECT pg_notify($1, $2)", p.channel, payload); err != nil {
+			return fmt.Errorf("pgnotify: %w", err)
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
+
+// encode returns the signed payload of as many of keys as fit into MaxPayload, and
+// their number.
+func (p *Publisher) encode(keys []string) (string, int, error) {
+	seq := atomic.AddUint64(&p.seq, 1)
+	n := len(keys)
+	for n > 0 {
+		payload := Payload{Keys: keys[:n], Publisher: p.id, Seq: seq}
+		if p.Keyring != nil {
+			var err error
+			if payload, err = p.Keyring.sign(payload); err != nil {
+				return "", 0, err
+			}
+		}
+		b, _ := json.Marshal(payload)
+		if len(b) <= MaxPayload {
+			return string(b), n, nil
+		}
+		n /= 2
+	}
+	return "", 0, nil
+}
+
+// DedupeSize is the number of notifications a Subscriber remembers to skip duplicates.
+const DedupeSize = 1024
+
+type seenKey struct {
+	publisher string
+	seq       uint64
+}
+
+// Subscriber receives the invalidations published to a channel. It skips notifications
+// it already received, e.g. replayed by a proxy, among the last DedupeSize ones. The
+// zero value is ready to use.
+type Subscriber struct {
+	// Keyring verifies the notifications, if set: unsigned notifications and those not
+	// signed by a key of the keyring are skipped.
+	Keyring *Keyring
+
+	once sync.Once
+	seen *lru.Cache[seenKey, struct{}]
+}
+
+// Subscribe calls invalidate with the keys of every notification received by l, until l
+// fails or ctx is done, see Subscriber.Subscribe.
+func Subscribe(ctx context.Context, l Listener, invalidate func(keys []string)) error {
+	return (&Subscriber{}).Subscribe(ctx, l, invalidate)
+}
+
+// Subscribe calls invalidate with the keys of every notification received by l, until l
+// fails or ctx is done. Notifications that are not invalidations, duplicated or not
+// verified by the Keyring are skipped.
+func (s *Subscriber) Subscribe(ctx context.Context, l Listener, invalidate func(keys []string)) error {
+	s.once.Do(func() {
+		s.seen, _ = lru.New[seenKey, struct{}](DedupeSize)
+	})
+	for {
+		n, err := l.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("pgnotify: %w", err)
+		}
+		p, ok := s.accept(n.Payload)
+		if !ok {
+			continue
+		}
+		invalidate(p.Keys)
+	}
+}
+
+// accept decodes payload, and reports whether it is an invalidation to act on.
+func (s *Subscriber) accept(payload string) (Payload, bool) {
+	var p Payload
+	var err error
+	if s.Keyring != nil {
+		p, err = s.Keyring.Verify([]byte(payload))
+	} else {
+		err = json.Unmarshal([]byte(payload), &p)
+	}
+	if err != nil || len(p.Keys) == 0 {
+		return p, false
+	}
+	if p.Seq == 0 {
+		return p, true
+	}
+	seen, _ := s.seen.ContainsOrAdd(seenKey{publisher: p.Publisher, seq: p.Seq}, struct{}{})
+	return p, !seen
+}
@@ -0,0 +1,71 @@
+package pgnotify_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/dadav/stampede/pgnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+// postgres delivers the notifications of pg_notify to its listener.
+type postgres struct {
+	notifications chan pgnotify.Notification
+}
+
+func (pg *postgres) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	pg.notifications <- pgnotify.Notification{Channel: args[0].(string), Payload: args[1].(string)}
+	return nil, nil
+}
+
+func (pg *postgres) Next(ctx context.Context) (pgnotify.Notification, error) {
+	select {
+	case n := <-pg.notifications:
+		return n, nil
+	case <-ctx.Done():
+		return pgnotify.Notification{}, ctx.Err()
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pg := &postgres{notifications: make(chan pgnotify.Notification, 16)}
+
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Hour)
+	defer c.Close()
+	c.Get(ctx, "a", func() (int, error) { return 1, nil })
+	c.Get(ctx, "b", func() (int, error) { return 2, nil })
+
+	done := make(chan error)
+	go func() {
+		done <- pgnotify.Subscribe(ctx, pg, func(keys []string) { c.DeleteMany(keys) })
+	}()
+
+	assert.NoError(t, pgnotify.NewPublisher(pg, "cache").Invalidate(ctx, "a"))
+	assert.Eventually(t, func() bool { return c.Len() == 1 }, time.Second, time.Millisecond)
+	_, ok := c.Peek("b")
+	assert.True(t, ok)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestInvalidateSplitsPayloads(t *testing.T) {
+	pg := &postgres{notifications: make(chan pgnotify.Notification, 16)}
+	keys := make([]string, 100)
+	for i := range keys {
+		keys[i] = strings.Repeat("k", 200) + string(rune('0'+i%10))
+	}
+	assert.NoError(t, pgnotify.NewPublisher(pg, "cache").Invalidate(context.Background(), keys...))
+	assert.Greater(t, len(pg.notifications), 1)
+	for len(pg.notifications) > 0 {
+		assert.LessOrEqual(t, len((<-pg.notifications).Payload), pgnotify.MaxPayload)
+	}
+
+	assert.Error(t, pgnotify.NewPublisher(pg, "cache").Invalidate(context.Background(), strings.Repeat("k", pgnotify.MaxPayload)))
+}
@@ -0,0 +1,74 @@
+package pgnotify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+)
+
+var (
+	// ErrUnsigned is returned by Verify for payloads without a signature.
+	ErrUnsigned = errors.New("pgnotify: payload not signed")
+
+	// ErrUnknownKey is returned by Verify for payloads signed with a key not in the
+	// keyring, e.g. a key that was rotated out.
+	ErrUnknownKey = errors.New("pgnotify: payload signed with unknown key")
+
+	// ErrBadSignature is returned by Verify for payloads whose signature doesn't match.
+	ErrBadSignature = errors.New("pgnotify: bad payload signature")
+
+	// ErrNoCurrentKey is returned by Invalidate for keyrings without their Current key.
+	ErrNoCurrentKey = errors.New("pgnotify: current key not in keyring")
+)
+
+// Keyring holds the HMAC-SHA256 keys signing payloads, by id, so that subscribers only
+// invalidate their caches for notifications of trusted publishers, and not of any
+// session able to run pg_notify. Payloads are signed with the Current key and verified
+// with any key of the keyring, so keys are rotated like the keys of kafkasink.
+type Keyring struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+// sign returns p signed with the current key.
+func (k *Keyring) sign(p Payload) (Payload, error) {
+	key, ok := k.Keys[k.Current]
+	if !ok || len(key) == 0 {
+		return p, ErrNoCurrentKey
+	}
+	p.KeyID, p.Signature = k.Current, nil
+	p.Signature = mac(key, p)
+	return p, nil
+}
+
+// Verify decodes the payload of a notification published with a keyring sharing keys
+// with k, and verifies its signature.
+func (k *Keyring) Verify(payload []byte) (Payload, error) {
+	var p Payload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return p, err
+	}
+	if len(p.Signature) == 0 {
+		return p, ErrUnsigned
+	}
+	key, ok := k.Keys[p.KeyID]
+	if !ok {
+		return p, ErrUnknownKey
+	}
+	sig := p.Signature
+	p.Signature = nil
+	if !hmac.Equal(sig, mac(key, p)) {
+		return p, ErrBadSignature
+	}
+	p.Signature = sig
+	return p, nil
+}
+
+// mac returns the HMAC of p, which has no signature, with key.
+func mac(key []byte, p Payload) []byte {
+	b, _ := json.Marshal(p)
+	h := hmac.New(sha256.New, key)
+	h.Write(b)
+	return h.Sum(nil)
+}
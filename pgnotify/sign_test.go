@@ -0,0 +1,51 @@
+package pgnotify_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede/pgnotify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyring(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pg := &postgres{notifications: make(chan pgnotify.Notification, 16)}
+
+	pub := pgnotify.NewPublisher(pg, "cache")
+	pub.Keyring = &pgnotify.Keyring{Current: "k2", Keys: map[string][]byte{"k2": []byte("new secret")}}
+	assert.NoError(t, pub.Invalidate(ctx, "a"))
+	signed := <-pg.notifications
+
+	// subscribers accept the old and the new key while the keys are rotated
+	consumer := &pgnotify.Keyring{Keys: map[string][]byte{"k1": []byte("old secret"), "k2": []byte("new secret")}}
+	p, err := consumer.Verify([]byte(signed.Payload))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a"}, p.Keys)
+	assert.Equal(t, "k2", p.KeyID)
+
+	_, err = (&pgnotify.Keyring{Keys: map[string][]byte{"k2": []byte("forged")}}).Verify([]byte(signed.Payload))
+	assert.ErrorIs(t, err, pgnotify.ErrBadSignature)
+
+	// unsigned, forged and replayed notifications are skipped
+	got := make(chan []string, 16)
+	s := &pgnotify.Subscriber{Keyring: consumer}
+	go s.Subscribe(ctx, pg, func(keys []string) { got <- keys })
+
+	assert.NoError(t, pgnotify.NewPublisher(pg, "cache").Invalidate(ctx, "unsigned"))
+	pg.notifications <- signed
+	pg.notifications <- signed
+	assert.NoError(t, pub.Invalidate(ctx, "b"))
+	assert.Equal(t, []string{"a"}, <-got)
+	assert.Equal(t, []string{"b"}, <-got)
+	select {
+	case keys := <-got:
+		t.Fatalf("unexpected invalidation of %v", keys)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	pub.Keyring.Current = "k3"
+	assert.ErrorIs(t, pub.Invalidate(ctx, "c"), pgnotify.ErrNoCurrentKey)
+}
@@ -0,0 +1,61 @@
+package stampede
+
+import "math"
+
+// never is the expiry of pinned entries, which are refreshed rather than dropped.
+const never = math.MaxInt64
+
+// Pin keeps the entry of key, cached now or later, until Unpin. Pinned entries are
+// never evicted, not even to enforce the size or watermarks of the cache, and never
+// expire: once past their ttl they are served stale while being refreshed. Pinned
+// entries are still removed by InvalidateTag and Purge.
+func (c *Cache[K, V]) Pin(key K) {
+	ck := c.cacheKey(c.normalizeKey(key))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pinned == nil {
+		c.pinned = make(map[cacheKey[K]]*value[K, V])
+	}
+	if _, ok := c.pinned[ck]; !ok {
+		c.pinned[ck] = nil
+	}
+}
+
+// Unpin undoes Pin. If the entry of key was kept beyond the size of the cache, it is
+// evicted.
+func (c *Cache[K, V]) Unpin(key K) {
+	ck := c.cacheKey(c.normalizeKey(key))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pinned[ck]
+	if !ok {
+		return
+	}
+	delete(c.pinned, ck)
+	if p != nil {
+		c.evicted(ck, *p)
+	}
+}
+
+// stashed returns the entry of ck, given what was found in the lru, falling back to the
+// entry kept outside the lru if ck is pinned. c.mu must be held.
+func (c *Cache[K, V]) stashed(ck cacheKey[K], val value[K, V], ok bool) (value[K, V], bool) {
+	if ok || c.pinned == nil {
+		return val, ok
+	}
+	if p := c.pinned[ck]; p != nil {
+		return *p, true
+	}
+	return val, false
+}
+
+// unstash removes the entry of ck kept outside the lru because it is pinned, if any.
+// c.mu must be held.
+func (c *Cache[K, V]) unstash(ck cacheKey[K]) {
+	if p := c.pinned[ck]; p != nil {
+		c.pinned[ck] = nil
+		c.evicted(ck, *p)
+	}
+}
@@ -0,0 +1,60 @@
+package stampede_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPin(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](2, time.Minute, time.Minute)
+
+	c.Pin("flags")
+	c.Get(ctx, "flags", func() (int, error) { return 1, nil })
+	for _, key := range []string{"a", "b", "c", "d"} {
+		c.Get(ctx, key, func() (int, error) { return 2, nil })
+	}
+
+	val, ok := c.Peek("flags")
+	assert.True(t, ok)
+	assert.Equal(t, 1, val)
+	_, ok = c.Peek("a")
+	assert.False(t, ok)
+
+	// a refresh of the evicted entry replaces it
+	c.Set(ctx, "flags", func() (int, error) { return 3, nil })
+	val, _ = c.Peek("flags")
+	assert.Equal(t, 3, val)
+
+	c.Unpin("flags")
+	c.Get(ctx, "e", func() (int, error) { return 2, nil })
+	c.Get(ctx, "f", func() (int, error) { return 2, nil })
+	_, ok = c.Peek("flags")
+	assert.False(t, ok)
+}
+
+func TestPinExpired(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Millisecond, time.Millisecond)
+	c.Pin("flags")
+	c.Get(ctx, "flags", func() (int, error) { return 1, nil })
+	time.Sleep(5 * time.Millisecond)
+
+	// expired pinned entries are served stale while refreshed
+	refreshed := make(chan struct{})
+	var calls int32
+	val, err := c.Get(ctx, "flags", func() (int, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			defer close(refreshed)
+		}
+		return 2, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+	<-refreshed
+}
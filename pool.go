@@ -0,0 +1,111 @@
+package stampede
+
+import (
+	"errors"
+	"sync"
+)
+
+// Overflow is what the cache does with a background refresh when the queue of its
+// refresh workers is full, see WithRefreshWorkers.
+type Overflow int
+
+const (
+	// OverflowDrop drops the refresh, the default. Stale values are served until the
+	// next refresh; the channels of SetAsync receive ErrRefreshQueueFull.
+	OverflowDrop Overflow = iota
+
+	// OverflowBlock makes the caller wait for room in the queue.
+	OverflowBlock
+
+	// OverflowRun runs the refresh in its own goroutine, as without refresh workers.
+	OverflowRun
+)
+
+// ErrRefreshQueueFull is returned for background refreshes dropped by OverflowDrop.
+var ErrRefreshQueueFull = errors.New("stampede: refresh queue full")
+
+// refreshPool runs the background refreshes of a cache on a bounded number of workers,
+// see WithRefreshWorkers. Workers are goroutines owned by the cache, started as
+// refreshes are queued and returning once the queue is empty, so Close drains the
+// queue.
+type refreshPool struct {
+	mu      sync.Mutex
+	room    *sync.Cond // signaled when a refresh leaves the queue or a worker returns
+	queue   []func()
+	running int // workers
+	dropped int64
+}
+
+func newRefreshPool() *refreshPool {
+	p := &refreshPool{}
+	p.room = sync.NewCond(&p.mu)
+	return p
+}
+
+// goRefresh runs the background refresh fn on a refresh worker, or in its own goroutine
+// owned by the cache without refresh workers. It reports false if fn was dropped.
+func (c *Cache[K, V]) goRefresh(fn func()) bool {
+	p := c.pool
+	if p == nil {
+		c.goBackground(fn)
+		return true
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		if p.running < c.workers {
+			p.running++
+			c.goBackground(func() {
+				fn()
+				c.refreshWorker()
+			})
+			return true
+		}
+		if len(p.queue) < c.queueSize {
+			p.queue = append(p.queue, fn)
+			return true
+		}
+		if c.overflow != OverflowBlock {
+			break
+		}
+		p.room.Wait()
+	}
+	if c.overflow == OverflowRun {
+		c.goBackground(fn)
+		return true
+	}
+	p.dropped++
+	return false
+}
+
+// refreshWorker runs the queued refreshes until the queue is empty.
+func (c *Cache[K, V]) refreshWorker() {
+	p := c.pool
+	for {
+		p.mu.Lock()
+		if len(p.queue) == 0 {
+			p.running--
+			p.room.Signal()
+			p.mu.Unlock()
+			return
+		}
+		fn := p.queue[0]
+		p.queue[0] = nil
+		p.queue = p.queue[1:]
+		p.room.Signal()
+		p.mu.Unlock()
+
+		fn()
+	}
+}
+
+// queued returns the number of queued refreshes and the number of dropped ones.
+func (p *refreshPool) queued() (int, int64) {
+	if p == nil {
+		return 0, 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.queue), p.dropped
+}
@@ -0,0 +1,55 @@
+package stampede_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshWorkers(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[int, int](16, time.Minute, time.Minute, stampede.WithRefreshWorkers(1, 1, stampede.OverflowDrop))
+
+	release := make(chan struct{})
+	var done int64
+	slow := func() (int, error) {
+		<-release
+		atomic.AddInt64(&done, 1)
+		return 1, nil
+	}
+	first := c.SetAsync(ctx, 1, slow)
+	second := c.SetAsync(ctx, 2, slow)
+	assert.Equal(t, 1, c.Stats().RefreshQueued)
+
+	assert.ErrorIs(t, <-c.SetAsync(ctx, 3, slow), stampede.ErrRefreshQueueFull)
+	assert.Equal(t, int64(1), c.Stats().RefreshDropped)
+
+	close(release)
+	assert.NoError(t, <-first)
+	assert.NoError(t, <-second)
+	c.Close()
+	assert.Equal(t, int64(2), atomic.LoadInt64(&done))
+	assert.Equal(t, 0, c.Stats().Background)
+}
+
+func TestRefreshWorkersDrainOnClose(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[int, int](16, time.Minute, time.Minute, stampede.WithRefreshWorkers(1, 8, stampede.OverflowBlock))
+
+	var done int64
+	fn := func() (int, error) {
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt64(&done, 1)
+		return 1, nil
+	}
+	for i := 0; i < 8; i++ {
+		c.SetAsync(ctx, i, fn)
+	}
+	c.Close()
+	assert.Equal(t, int64(8), atomic.LoadInt64(&done))
+	assert.Equal(t, 0, c.Stats().RefreshQueued)
+}
@@ -0,0 +1,25 @@
+package stampede
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// fetch runs fn. When the cache is named or classifies its keys, fn runs with the pprof
+// labels stampede.cache and stampede.key_class, so cpu and goroutine profiles show which
+// caches and keys are burning time.
+func (c *Cache[K, V]) fetch(ctx context.Context, key K, fn FetchFunc[V]) (val V, err error) {
+	if c.name == "" && c.keyClass == nil {
+		return fn(ctx)
+	}
+
+	var class string
+	if c.keyClass != nil {
+		class = c.keyClass(key)
+	}
+
+	pprof.Do(ctx, pprof.Labels("stampede.cache", c.name, "stampede.key_class", class), func(ctx context.Context) {
+		val, err = fn(ctx)
+	})
+	return val, err
+}
@@ -0,0 +1,31 @@
+package stampede_test
+
+import (
+	"context"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfilerLabels(t *testing.T) {
+	cache := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute,
+		stampede.WithName("users"),
+		stampede.WithKeyClass(func(key any) string {
+			class, _, _ := strings.Cut(key.(string), ":")
+			return class
+		}))
+
+	_, err := cache.GetContext(context.Background(), "user:1", func(ctx context.Context) (string, error) {
+		name, _ := pprof.Label(ctx, "stampede.cache")
+		class, _ := pprof.Label(ctx, "stampede.key_class")
+		return name + "/" + class, nil
+	})
+	assert.NoError(t, err)
+
+	val, _ := cache.Peek("user:1")
+	assert.Equal(t, "users/user", val)
+}
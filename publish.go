@@ -0,0 +1,58 @@
+package stampede
+
+import (
+	"context"
+	"time"
+)
+
+// Publish installs v as the value of key, fresh for freshFor and kept for ttl, and
+// writes it through to the store, for writers that know the authoritative value of a
+// key, e.g. consumers of a change data capture stream. A refresh of key in flight while
+// v is published doesn't replace it: its callers get v, and the refreshed value is
+// dropped. Read-only and disabled caches are not changed.
+func (c *Cache[K, V]) Publish(key K, v V, freshFor, ttl time.Duration) {
+	if c.ReadOnly() || c.Disabled() {
+		return
+	}
+	key = c.normalizeKey(key)
+	ck := c.cacheKey(key)
+
+	now := time.Now()
+	bestBefore, expiry := now.Add(freshFor), now.Add(ttl)
+	entry := c.entry(key, ck, v, bestBefore, expiry)
+	entry.published = true
+
+	c.mu.Lock()
+	c.add(ck, entry)
+	c.notify(ck, v)
+	c.mu.Unlock()
+
+	c.forgetError(ck)
+	c.uncollapse(ck)
+	ctx := context.Background()
+	if c.store != nil {
+		c.save(ctx, c.storeKey(key, ck), v, bestBefore, expiry)
+	}
+	c.saveLastKnownGood(ctx, key, ck, v)
+}
+
+// publishedSince returns the value of ck if it was published since start, see Publish.
+// It must be called with mu held.
+func (c *Cache[K, V]) publishedSince(ck cacheKey[K], start time.Time) (V, bool) {
+	val, ok := c.values.Peek(ck)
+	val, ok = c.stashed(ck, val, ok)
+	if ok && val.published && val.created >= start.UnixNano() {
+		return val.v, true
+	}
+	var zero V
+	return zero, false
+}
+
+// publishedInStore reports whether a value of ck was published since start, so that a
+// fetch started before doesn't overwrite it in the store.
+func (c *Cache[K, V]) publishedInStore(ck cacheKey[K], start time.Time) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.publishedSince(ck, start)
+	return ok
+}
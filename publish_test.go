@@ -0,0 +1,53 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Hour)
+	defer c.Close()
+
+	c.Publish("k", 1, time.Minute, time.Hour)
+	v, err := c.Get(ctx, "k", func() (int, error) { return 0, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+
+	// a refresh in flight while a value is published doesn't replace it
+	started, release := make(chan struct{}), make(chan struct{})
+	res := make(chan int)
+	go func() {
+		v, _, _ := c.Set(ctx, "k", func() (int, error) {
+			close(started)
+			<-release
+			return 2, nil
+		})
+		res <- v
+	}()
+	<-started
+	c.Publish("k", 3, time.Minute, time.Hour)
+	close(release)
+	assert.Equal(t, 3, <-res)
+
+	v, _ = c.Get(ctx, "k", func() (int, error) { return 0, nil })
+	assert.Equal(t, 3, v)
+}
+
+func TestPublishStore(t *testing.T) {
+	ctx := context.Background()
+	store := stampede.NewMemoryStore()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Hour, stampede.WithStore(store, stampede.JSONCodec{}))
+	defer c.Close()
+	c.Publish("k", 1, time.Minute, time.Hour)
+
+	other := stampede.NewCacheKV[string, int](8, time.Minute, time.Hour, stampede.WithStore(store, stampede.JSONCodec{}))
+	defer other.Close()
+	v, _ := other.Get(ctx, "k", func() (int, error) { return 0, nil })
+	assert.Equal(t, 1, v)
+}
@@ -0,0 +1,69 @@
+package stampede
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Purger purges the responses cached by the middlewares of HandlerWithPurger, or of
+// NewHandler with WithPurger, by the tags set by the origin in their Surrogate-Key or
+// Cache-Tag headers, like the tag purges of CDNs.
+type Purger struct {
+	mu     sync.Mutex
+	caches []*Cache[uint64, responseValue]
+}
+
+// NewPurger returns a Purger for the middlewares created with WithPurger.
+func NewPurger() *Purger {
+	return &Purger{}
+}
+
+func (p *Purger) add(cache *Cache[uint64, responseValue]) {
+	p.mu.Lock()
+	p.caches = append(p.caches, cache)
+	p.mu.Unlock()
+}
+
+// HandlerWithPurger is like Handler, but also returns a Purger for the cached responses.
+func HandlerWithPurger(cacheSize int, ttl time.Duration, paths ...string) (func(next http.Handler) http.Handler, *Purger) {
+	p := NewPurger()
+	return NewHandler(cacheSize, ttl, WithPaths(paths...), WithPurger(p)), p
+}
+
+// Purge removes the responses tagged with any of tags, and returns how many were removed.
+func (p *Purger) Purge(tags ...string) int {
+	p.mu.Lock()
+	caches := p.caches
+	p.mu.Unlock()
+
+	var n int
+	for _, cache := range caches {
+		for _, tag := range tags {
+			n += cache.InvalidateTag(tag)
+		}
+	}
+	return n
+}
+
+// ServeHTTP purges the tags listed in the Surrogate-Key header, or in tag query
+// parameters, of POST and PURGE requests, and responds with the number of purged
+// responses. It is meant to be mounted on an internal admin route, or behind
+// RequireAuth.
+func (p *Purger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != "PURGE" {
+		w.Header().Set("Allow", "POST, PURGE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	tags := strings.Fields(strings.Join(r.Header.Values("Surrogate-Key"), " "))
+	tags = append(tags, r.URL.Query()["tag"]...)
+	if len(tags) == 0 {
+		http.Error(w, "no tags to purge", http.StatusBadRequest)
+		return
+	}
+	w.Write([]byte(strconv.Itoa(p.Purge(tags...)) + "\n"))
+}
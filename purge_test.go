@@ -0,0 +1,54 @@
+package stampede_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurger(t *testing.T) {
+	calls := 0
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		switch r.URL.Path {
+		case "/products/1":
+			w.Header().Set("Surrogate-Key", "product-1 products")
+		case "/products/2":
+			w.Header().Set("Cache-Tag", "product-2, products")
+		}
+		w.Write([]byte(r.URL.Path))
+	})
+	h, purger := stampede.HandlerWithPurger(16, time.Minute)
+	srv := h(app)
+
+	get := func(path string) {
+		srv.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	purge := func(req *http.Request) string {
+		w := httptest.NewRecorder()
+		purger.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	for _, path := range []string{"/products/1", "/products/2", "/about"} {
+		get(path)
+		get(path)
+	}
+	assert.Equal(t, 3, calls)
+
+	assert.Equal(t, "1\n", purge(httptest.NewRequest("PURGE", "/purge?tag=product-1", nil)))
+	get("/products/1")
+	get("/products/2")
+	assert.Equal(t, 4, calls)
+
+	req := httptest.NewRequest("POST", "/purge", nil)
+	req.Header.Set("Surrogate-Key", "products")
+	assert.Equal(t, "2\n", purge(req))
+	get("/products/2")
+	get("/about")
+	assert.Equal(t, 5, calls)
+}
@@ -0,0 +1,81 @@
+package stampede
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ramp spaces the origin fetches of a cache evenly while it warms up after its
+// creation, see WithStartupRamp.
+type ramp struct {
+	end      time.Time
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time // when the next fetch may start
+}
+
+func newRamp(d time.Duration, perSecond int) *ramp {
+	if d <= 0 || perSecond <= 0 {
+		return nil
+	}
+	interval := time.Second / time.Duration(perSecond)
+	if interval <= 0 {
+		// more than a fetch per nanosecond isn't limited
+		return nil
+	}
+	now := time.Now()
+	return &ramp{end: now.Add(d), interval: interval, next: now}
+}
+
+type backgroundRefreshKey struct{}
+
+// inBackground marks ctx as the context of a background refresh, which doesn't wait for
+// the ramp: it refreshes a cached value, which is served meanwhile.
+func inBackground(ctx context.Context) context.Context {
+	return context.WithValue(ctx, backgroundRefreshKey{}, true)
+}
+
+// wait blocks until the caller may fetch from the origin, with some jitter so waiting
+// callers don't all start at once, or until ctx is done. Background refreshes don't
+// wait.
+func (r *ramp) wait(ctx context.Context) error {
+	if r == nil {
+		return nil
+	}
+	if bg, _ := ctx.Value(backgroundRefreshKey{}).(bool); bg {
+		return nil
+	}
+	now := time.Now()
+	if !now.Before(r.end) {
+		return nil
+	}
+
+	r.mu.Lock()
+	slot := r.next
+	if slot.Before(now) {
+		slot = now
+	}
+	r.next = slot.Add(r.interval)
+	r.mu.Unlock()
+
+	if slot.After(r.end) {
+		slot = r.end
+	}
+	d := slot.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	d += time.Duration(rand.Int63n(int64(r.interval)))
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,67 @@
+package stampede_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStartupRamp(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[int, int](16, time.Minute, time.Minute, stampede.WithStartupRamp(300*time.Millisecond, 20))
+	defer c.Close()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.Get(ctx, i, func() (int, error) { return i, nil })
+		}(i)
+	}
+	wg.Wait()
+	// 20 fetches per second start at most every 50ms
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+
+	// the ramp ends after its duration
+	time.Sleep(time.Until(start.Add(300 * time.Millisecond)))
+	start = time.Now()
+	for i := 5; i < 10; i++ {
+		c.Get(ctx, i, func() (int, error) { return i, nil })
+	}
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestStartupRampRefresh(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](16, 10*time.Millisecond, time.Minute, stampede.WithStartupRamp(time.Minute, 1))
+	defer c.Close()
+
+	c.Get(ctx, "a", func() (int, error) { return 1, nil })
+	time.Sleep(20 * time.Millisecond)
+
+	// the background refresh of the stale value doesn't wait for the next slot
+	v, _ := c.Get(ctx, "a", func() (int, error) { return 2, nil })
+	assert.Equal(t, 1, v)
+	assert.Eventually(t, func() bool {
+		v, _ := c.Peek("a")
+		return v == 2
+	}, 500*time.Millisecond, 5*time.Millisecond)
+}
+
+func TestStartupRampHighRate(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](16, time.Minute, time.Minute, stampede.WithStartupRamp(time.Minute, 2_000_000_000))
+	defer c.Close()
+
+	for _, key := range []string{"a", "b"} {
+		v, err := c.Get(ctx, key, func() (int, error) { return 1, nil })
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	}
+}
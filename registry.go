@@ -0,0 +1,106 @@
+package stampede
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Registry creates and tracks named caches, for applications with many purpose
+// specific caches.
+type Registry struct {
+	mu     sync.RWMutex
+	caches map[string]registered
+}
+
+// registered is the part of a Cache that doesn't depend on its key and value types.
+type registered interface {
+	Stats() Stats
+	Purge()
+	Close() error
+}
+
+func NewRegistry() *Registry {
+	return &Registry{caches: map[string]registered{}}
+}
+
+// Register creates a cache named name in r, see WithName. Names must be unique.
+func Register[K comparable, V any](r *Registry, name string, size int, freshFor, ttl time.Duration, opts ...Option) (*Cache[K, V], error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.caches[name]; ok {
+		return nil, fmt.Errorf("stampede: cache %q is already registered", name)
+	}
+	c := NewCacheKV[K, V](size, freshFor, ttl, append([]Option{WithName(name)}, opts...)...)
+	r.caches[name] = c
+	return c, nil
+}
+
+// Lookup returns the cache named name, if it was registered with the same key and
+// value types.
+func Lookup[K comparable, V any](r *Registry, name string) (*Cache[K, V], bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.caches[name].(*Cache[K, V])
+	return c, ok
+}
+
+// Names returns the names of all registered caches, sorted.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.caches))
+	for name := range r.caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats returns the stats of every registered cache by name.
+func (r *Registry) Stats() map[string]Stats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stats := make(map[string]Stats, len(r.caches))
+	for name, c := range r.caches {
+		stats[name] = c.Stats()
+	}
+	return stats
+}
+
+// TotalStats returns the stats of all registered caches added up.
+func (r *Registry) TotalStats() Stats {
+	var total Stats
+	for _, s := range r.Stats() {
+		total = total.Add(s)
+	}
+	return total
+}
+
+// Purge removes all entries from all registered caches.
+func (r *Registry) Purge() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.caches {
+		c.Purge()
+	}
+}
+
+// Close closes all registered caches, and returns the errors of all of them.
+func (r *Registry) Close() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var errs []error
+	for _, c := range r.caches {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	ctx := context.Background()
+	r := stampede.NewRegistry()
+	defer r.Close()
+
+	users, err := stampede.Register[int, string](r, "users", 8, time.Minute, time.Minute)
+	assert.NoError(t, err)
+	flags, err := stampede.Register[string, bool](r, "flags", 8, time.Minute, time.Minute)
+	assert.NoError(t, err)
+
+	_, err = stampede.Register[int, string](r, "users", 8, time.Minute, time.Minute)
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{"flags", "users"}, r.Names())
+
+	c, ok := stampede.Lookup[int, string](r, "users")
+	assert.True(t, ok)
+	assert.Same(t, users, c)
+	_, ok = stampede.Lookup[string, string](r, "users")
+	assert.False(t, ok)
+
+	users.Set(ctx, 1, func() (string, error) { return "a", nil })
+	users.Set(ctx, 2, func() (string, error) { return "b", nil })
+	flags.Set(ctx, "beta", func() (bool, error) { return true, nil })
+
+	assert.Equal(t, 2, r.Stats()["users"].Entries)
+	total := r.TotalStats()
+	assert.Equal(t, 3, total.Entries)
+	assert.Equal(t, int64(3), total.Size)
+
+	r.Purge()
+	total = r.TotalStats()
+	assert.Equal(t, 0, total.Entries)
+	assert.Equal(t, int64(0), total.Size)
+}
+
+type unflushableStore struct {
+	stampede.Store
+	err error
+}
+
+func (s unflushableStore) Flush() error { return s.err }
+
+func TestRegistryClose(t *testing.T) {
+	r := stampede.NewRegistry()
+	errA, errB := errors.New("a"), errors.New("b")
+	for name, err := range map[string]error{"a": errA, "b": errB, "c": nil} {
+		store := unflushableStore{stampede.NewMemoryStore(), err}
+		_, err := stampede.Register[string, int](r, name, 8, time.Minute, time.Minute, stampede.WithStore(store, stampede.JSONCodec{}))
+		assert.NoError(t, err)
+	}
+
+	err := r.Close()
+	assert.ErrorIs(t, err, errA)
+	assert.ErrorIs(t, err, errB)
+}
@@ -0,0 +1,67 @@
+package stampede
+
+import (
+	"context"
+	"sync"
+)
+
+type requestCacheKey struct{}
+
+// requestCache holds the values got with a context of RequestCache, by cache and key.
+type requestCache struct {
+	mu     sync.Mutex
+	values map[any]any
+}
+
+// requestKey identifies a memoized value by cache key, as keys may not be usable as map
+// keys, e.g. Keyer keys.
+type requestKey[K comparable, V any] struct {
+	c  *Cache[K, V]
+	ck cacheKey[K]
+}
+
+// RequestCache returns a context memoizing the values got with it, e.g. the context of
+// an http request, so a handler getting the same key several times reaches the cache
+// once: later gets of the key return the same value without locking the cache, even
+// GetFresh. Errors are not memoized. The memoized values are dropped with the context.
+func RequestCache(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(requestCacheKey{}).(*requestCache); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, requestCacheKey{}, &requestCache{values: make(map[any]any)})
+}
+
+// memoized returns the value of ck memoized in the request cache of ctx, if any.
+func (c *Cache[K, V]) memoized(ctx context.Context, ck cacheKey[K]) (rc *requestCache, v V, ok bool) {
+	rc, _ = ctx.Value(requestCacheKey{}).(*requestCache)
+	if rc == nil {
+		return nil, v, false
+	}
+	rc.mu.Lock()
+	cached, ok := rc.values[requestKey[K, V]{c: c, ck: ck}]
+	rc.mu.Unlock()
+	if ok {
+		v = cached.(V)
+	}
+	return rc, v, ok
+}
+
+func (rc *requestCache) memoize(key, v any) {
+	rc.mu.Lock()
+	rc.values[key] = v
+	rc.mu.Unlock()
+}
+
+// getRequest is get through the request cache of ctx.
+func (c *Cache[K, V]) getRequest(ctx context.Context, key K, freshOnly bool, fn FetchFunc[V]) (V, error) {
+	ck := c.cacheKey(key)
+	rc, v, ok := c.memoized(ctx, ck)
+	if ok {
+		return v, nil
+	}
+	v, err := c.get(ctx, key, freshOnly, fn)
+	if rc != nil && err == nil {
+		rc.memoize(requestKey[K, V]{c: c, ck: ck}, v)
+	}
+	return v, err
+}
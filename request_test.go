@@ -0,0 +1,77 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestCache(t *testing.T) {
+	c := stampede.NewCacheKV[string, int](16, time.Minute, time.Minute)
+	defer c.Close()
+	other := stampede.NewCacheKV[string, int](16, time.Minute, time.Minute)
+	defer other.Close()
+
+	ctx := stampede.RequestCache(context.Background())
+	var calls int
+	fn := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+	for i := 0; i < 5; i++ {
+		v, err := c.Get(ctx, "a", fn)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	}
+	assert.Equal(t, 1, calls)
+
+	// the memoized value is served even if the cache changed meanwhile
+	c.Delete("a")
+	v, _ := c.GetFresh(ctx, "a", fn)
+	assert.Equal(t, 1, v)
+
+	// caches are memoized apart
+	v, _ = other.Get(ctx, "a", fn)
+	assert.Equal(t, 2, v)
+
+	// a new request sees the cache
+	v, _ = c.Get(stampede.RequestCache(context.Background()), "a", fn)
+	assert.Equal(t, 3, v)
+}
+
+func TestRequestCacheError(t *testing.T) {
+	c := stampede.NewCacheKV[string, int](16, time.Minute, time.Minute)
+	defer c.Close()
+
+	ctx := stampede.RequestCache(context.Background())
+	_, err := c.Get(ctx, "a", func() (int, error) { return 0, errors.New("boom") })
+	assert.Error(t, err)
+	v, err := c.Get(ctx, "a", func() (int, error) { return 1, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, v)
+}
+
+func TestRequestCacheKeyer(t *testing.T) {
+	c := stampede.NewCache(16, time.Minute, time.Minute)
+	defer c.Close()
+
+	ctx := stampede.RequestCache(context.Background())
+	var calls int
+	fn := func() (any, error) {
+		calls++
+		return calls, nil
+	}
+	for i := 0; i < 3; i++ {
+		v, err := c.Get(ctx, userQuery{Tenant: "a", IDs: []int{1, 2}}, fn)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	}
+	c.Delete(userQuery{Tenant: "a", IDs: []int{1, 2}})
+	v, _ := c.GetFresh(ctx, userQuery{Tenant: "a", IDs: []int{1, 2}}, fn)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,78 @@
+package stampede
+
+import (
+	"time"
+)
+
+// retry is a background refresh of a key that failed, see WithRefreshRetry.
+type retry[K comparable, V any] struct {
+	key     K
+	fn      FetchFunc[V]
+	attempt int
+	due     int64 // unix nanoseconds
+}
+
+// retryLater queues another refresh of key after its attempt-th refresh failed, with
+// exponential backoff.
+func (c *Cache[K, V]) retryLater(key K, ck cacheKey[K], fn FetchFunc[V], attempt int) {
+	if c.retryBackoff <= 0 {
+		return
+	}
+
+	backoff := c.retryBackoff
+	for i := 0; i < attempt && (c.retryMaxBackoff <= 0 || backoff < c.retryMaxBackoff); i++ {
+		backoff *= 2
+	}
+	if c.retryMaxBackoff > 0 && backoff > c.retryMaxBackoff {
+		backoff = c.retryMaxBackoff
+	}
+
+	c.retriesMu.Lock()
+	defer c.retriesMu.Unlock()
+	if r, ok := c.retries[ck]; ok && r.attempt >= attempt {
+		return
+	}
+	if c.retries == nil {
+		c.retries = make(map[cacheKey[K]]*retry[K, V])
+	}
+	c.retries[ck] = &retry[K, V]{key: key, fn: fn, attempt: attempt, due: time.Now().Add(backoff).UnixNano()}
+}
+
+// runRetries refreshes the keys whose retry is due. Retries of keys that are no longer
+// cached, expired or were refreshed in the meantime are dropped.
+func (c *Cache[K, V]) runRetries() {
+	now := time.Now().UnixNano()
+	due := make(map[cacheKey[K]]*retry[K, V])
+	c.retriesMu.Lock()
+	for ck, r := range c.retries {
+		if r.due <= now {
+			due[ck] = r
+			delete(c.retries, ck)
+		}
+	}
+	c.retriesMu.Unlock()
+
+	for ck, r := range due {
+		c.mu.RLock()
+		val, ok := c.values.Peek(ck)
+		val, ok = c.stashed(ck, val, ok)
+		c.mu.RUnlock()
+		if !ok || val.IsFresh() || val.IsExpired() {
+			continue
+		}
+
+		ck, r := ck, r
+		c.goRefresh(func() {
+			if _, _, err := c.refresh(c.ctx, r.key, ck, r.fn); err != nil && c.ctx.Err() == nil {
+				c.retryLater(r.key, ck, r.fn, r.attempt+1)
+			}
+		})
+	}
+}
+
+// retrying returns the number of keys waiting for a retry.
+func (c *Cache[K, V]) retrying() int {
+	c.retriesMu.Lock()
+	defer c.retriesMu.Unlock()
+	return len(c.retries)
+}
@@ -0,0 +1,41 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshRetry(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, 10*time.Millisecond, time.Minute, stampede.WithRefreshRetry(5*time.Millisecond, 20*time.Millisecond))
+	defer c.Close()
+
+	c.Get(ctx, "k", func() (int, error) { return 1, nil })
+	time.Sleep(20 * time.Millisecond)
+
+	var calls int32
+	fetch := func() (int, error) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return 0, errors.New("origin blip")
+		}
+		return 2, nil
+	}
+	// the stale value is served, and its refresh fails
+	val, err := c.Get(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+
+	// the key is retried without reads until the origin recovers
+	assert.Eventually(t, func() bool {
+		v, _ := c.Peek("k")
+		return v == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	assert.Eventually(t, func() bool { return c.Stats().Retrying == 0 }, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,91 @@
+package stampede
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Source is the loader that produced an entry, see WithSecondary.
+type Source uint8
+
+const (
+	// SourcePrimary is the fetch function passed to the cache.
+	SourcePrimary Source = iota
+	// SourceSecondary is the loader given to WithSecondary.
+	SourceSecondary
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourcePrimary:
+		return "primary"
+	case SourceSecondary:
+		return "secondary"
+	}
+	return fmt.Sprintf("Source(%d)", uint8(s))
+}
+
+// errSecondaryType is returned by the secondary loader for values of the wrong type.
+var errSecondaryType = errors.New("stampede: secondary loader returned a value of the wrong type")
+
+// origin fetches key from the primary loader fn, failing over to the secondary loader,
+// see WithSecondary. It waits for its turn during the startup ramp, see WithStartupRamp.
+func (c *Cache[K, V]) origin(ctx context.Context, key K, fn FetchFunc[V]) (V, error) {
+	if err := c.ramp.wait(ctx); err != nil {
+		var zero V
+		return zero, err
+	}
+	if c.secondary == nil {
+		return c.hedge(ctx, key, fn)
+	}
+
+	pctx := ctx
+	if c.secondaryAfter > 0 {
+		var cancel context.CancelFunc
+		pctx, cancel = context.WithTimeout(ctx, c.secondaryAfter)
+		defer cancel()
+	}
+	v, err := c.hedge(pctx, key, fn)
+	if err == nil || err == Unchanged || ctx.Err() != nil {
+		return v, err
+	}
+	if m := metaFrom(ctx); m != nil {
+		// drop whatever the failed primary recorded about the entry
+		*m = entryMeta{previous: m.previous, hasPrevious: m.hasPrevious}
+	}
+	if sv, serr := c.fetch(ctx, key, c.secondaryFunc(key)); serr == nil {
+		return sv, nil
+	}
+	return v, err
+}
+
+// secondaryFunc returns a FetchFunc fetching key from the secondary loader, which
+// records the source of the value.
+func (c *Cache[K, V]) secondaryFunc(key K) FetchFunc[V] {
+	return func(ctx context.Context) (V, error) {
+		var zero V
+		v, err := c.secondary(ctx, key)
+		if err != nil {
+			return zero, err
+		}
+		sv, ok := v.(V)
+		if !ok {
+			return zero, errSecondaryType
+		}
+		if m := metaFrom(ctx); m != nil {
+			m.source = SourceSecondary
+		}
+		return sv, nil
+	}
+}
+
+// Source returns the loader that produced the cached value of key.
+func (c *Cache[K, V]) Source(key K) (Source, bool) {
+	ck := c.cacheKey(c.normalizeKey(key))
+	c.mu.RLock()
+	val, ok := c.values.Peek(ck)
+	val, ok = c.stashed(ck, val, ok)
+	c.mu.RUnlock()
+	return val.source, ok
+}
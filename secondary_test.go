@@ -0,0 +1,57 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecondary(t *testing.T) {
+	ctx := context.Background()
+	secondary := func(ctx context.Context, key any) (any, error) {
+		if key == "down" {
+			return nil, errors.New("secondary down")
+		}
+		return 2, nil
+	}
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute,
+		stampede.WithSecondary(secondary, 20*time.Millisecond))
+	defer c.Close()
+	events := c.Events()
+
+	val, err := c.GetContext(ctx, "ok", func(ctx context.Context) (int, error) { return 1, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+	source, ok := c.Source("ok")
+	assert.True(t, ok)
+	assert.Equal(t, stampede.SourcePrimary, source)
+
+	failing := func(ctx context.Context) (int, error) { return 0, errors.New("primary down") }
+	val, err = c.GetContext(ctx, "failing", failing)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, val)
+	source, _ = c.Source("failing")
+	assert.Equal(t, stampede.SourceSecondary, source)
+
+	slow := func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	start := time.Now()
+	val, err = c.GetContext(ctx, "slow", slow)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, val)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// the error of the primary is returned if both fail
+	_, err = c.GetContext(ctx, "down", failing)
+	assert.EqualError(t, err, "primary down")
+
+	assert.Equal(t, stampede.SourcePrimary, (<-events).Source)
+	assert.Equal(t, stampede.SourceSecondary, (<-events).Source)
+	assert.Equal(t, "secondary", stampede.SourceSecondary.String())
+}
@@ -0,0 +1,87 @@
+package stampede
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Session gives the requests of one user read-your-writes consistency: keys the user
+// wrote are not served stale, nor from entries older than the write, for a while after
+// the write, even if the cache or its store still hold the previous value. A Session
+// is safe for concurrent use.
+type Session[K comparable, V any] struct {
+	cache  *Cache[K, V]
+	window time.Duration
+
+	mu      sync.Mutex
+	written map[cacheKey[K]]time.Time // by cache key, as keys may not be usable as map keys
+}
+
+// Session returns a Session ensuring that writes are read for window.
+func (c *Cache[K, V]) Session(window time.Duration) *Session[K, V] {
+	return &Session[K, V]{cache: c, window: window, written: make(map[cacheKey[K]]time.Time)}
+}
+
+// Wrote records that keys were written now, e.g. to the database behind the cache.
+func (s *Session[K, V]) Wrote(keys ...K) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		s.written[s.cache.cacheKey(s.cache.normalizeKey(key))] = now
+	}
+}
+
+// Get is like Cache.GetContext, but fetches keys written within the window from the
+// origin, skipping the store, unless their entry is fresh and was fetched after the
+// write.
+func (s *Session[K, V]) Get(ctx context.Context, key K, fn FetchFunc[V]) (V, error) {
+	key = s.cache.normalizeKey(key)
+	ck := s.cache.cacheKey(key)
+	at, ok := s.wroteAt(ck)
+	if !ok {
+		return s.cache.GetContext(ctx, key, fn)
+	}
+	if s.cache.createdAfter(ck, at) {
+		return s.cache.GetFreshContext(ctx, key, fn)
+	}
+	v, _, err := s.cache.SetContext(context.WithValue(ctx, sessionWriteKey{}, true), key, fn)
+	return v, err
+}
+
+// Set is like Cache.SetContext, and records that key was written.
+func (s *Session[K, V]) Set(ctx context.Context, key K, fn FetchFunc[V]) (V, bool, error) {
+	s.Wrote(key)
+	return s.cache.SetContext(context.WithValue(ctx, sessionWriteKey{}, true), key, fn)
+}
+
+type sessionWriteKey struct{}
+
+// wroteInSession reports whether ctx fetches a key written in its session, which skips
+// reading the store, as it may still hold the previous value.
+func wroteInSession(ctx context.Context) bool {
+	ok, _ := ctx.Value(sessionWriteKey{}).(bool)
+	return ok
+}
+
+// wroteAt returns when the key of ck was written, if within the window.
+func (s *Session[K, V]) wroteAt(ck cacheKey[K]) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	at, ok := s.written[ck]
+	if ok && time.Since(at) > s.window {
+		delete(s.written, ck)
+		return at, false
+	}
+	return at, ok
+}
+
+// createdAfter reports whether the entry of ck was added after t.
+func (c *Cache[K, V]) createdAfter(ck cacheKey[K], t time.Time) bool {
+	c.mu.RLock()
+	val, ok := c.values.Peek(ck)
+	val, ok = c.stashed(ck, val, ok)
+	c.mu.RUnlock()
+	return ok && val.created > t.UnixNano()
+}
@@ -0,0 +1,79 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute)
+	db := map[string]string{"name": "old"}
+	fetch := func(ctx context.Context) (string, error) { return db["name"], nil }
+
+	val, _ := c.GetContext(ctx, "name", fetch)
+	assert.Equal(t, "old", val)
+
+	s := c.Session(time.Minute)
+	db["name"] = "new"
+	s.Wrote("name")
+
+	// the write is read, and cached afterwards
+	val, err := s.Get(ctx, "name", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", val)
+	db["name"] = "newer"
+	val, _ = s.Get(ctx, "name", fetch)
+	assert.Equal(t, "new", val)
+
+	s = c.Session(time.Millisecond)
+	s.Wrote("name")
+	time.Sleep(5 * time.Millisecond)
+	val, _ = s.Get(ctx, "name", fetch)
+	assert.Equal(t, "new", val)
+}
+
+func TestSessionStore(t *testing.T) {
+	ctx := context.Background()
+	store := stampede.NewMemoryStore()
+	c := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithStore(store, stampede.JSONCodec{}))
+	db := map[string]string{"name": "old"}
+	fetch := func(ctx context.Context) (string, error) { return db["name"], nil }
+
+	val, _ := c.GetContext(ctx, "name", fetch)
+	assert.Equal(t, "old", val)
+
+	// the store still holds the previous value, but the write is read from the origin
+	// and written through
+	s := c.Session(time.Minute)
+	db["name"] = "new"
+	s.Wrote("name")
+	val, err := s.Get(ctx, "name", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", val)
+	b, err := store.Get(ctx, "name")
+	assert.NoError(t, err)
+	assert.Equal(t, `"new"`, string(b))
+}
+
+func TestSessionKeyer(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCache(8, time.Minute, time.Minute)
+	key := userQuery{Tenant: "a", IDs: []int{1}}
+	name := "old"
+	fetch := func(ctx context.Context) (any, error) { return name, nil }
+
+	val, _ := c.GetContext(ctx, key, fetch)
+	assert.Equal(t, "old", val)
+
+	s := c.Session(time.Minute)
+	name = "new"
+	s.Wrote(userQuery{Tenant: "a", IDs: []int{1}})
+	val, err := s.Get(ctx, key, fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "new", val)
+}
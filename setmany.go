@@ -0,0 +1,109 @@
+package stampede
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SetMany fetches the values of several related keys concurrently and installs them
+// together: readers see either none or all of the new values, so views composed of the
+// keys never mix old and new ones. Like with Publish, refreshes of the keys in flight
+// during SetMany don't replace the installed values. If any fetch fails, no value is
+// installed and the error of the first failed key in the order of their key strings is
+// returned. Values are written through to the store afterwards. Read-only and disabled
+// caches return the values without installing them, and so do caches that can't keep
+// all of them, e.g. past their soft limit, or with fewer entries than keys.
+func (c *Cache[K, V]) SetMany(ctx context.Context, fns map[K]FetchFunc[V]) (map[K]V, error) {
+	type result struct {
+		key   K
+		ck    cacheKey[K]
+		fn    FetchFunc[V]
+		entry value[K, V]
+		meta  *entryMeta
+		err   error
+	}
+
+	results := make([]result, 0, len(fns))
+	for key, fn := range fns {
+		key := c.normalizeKey(key)
+		results = append(results, result{key: key, ck: c.cacheKey(key), fn: fn})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return keyString(results[i].key) < keyString(results[j].key)
+	})
+
+	var wg sync.WaitGroup
+	for i := range results {
+		r := &results[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, endFetch := c.startFetch(ctx, r.ck)
+			ctx, r.meta = withMeta(ctx)
+			start := time.Now()
+			v, err := c.fetch(ctx, r.key, r.fn)
+			endFetch(err)
+			if r.err = err; err != nil {
+				return
+			}
+			bestBefore, expiry := c.expiry(ctx, v)
+			r.entry = c.entry(r.key, r.ck, v, bestBefore, expiry)
+			c.apply(&r.entry, r.meta)
+			r.entry.setCost(costOf(v, time.Since(start)))
+		}()
+	}
+	wg.Wait()
+
+	vals := make(map[K]V, len(results))
+	for _, r := range results {
+		if r.err != nil {
+			return nil, r.err
+		}
+		vals[r.key] = r.entry.v
+	}
+	if c.ReadOnly() || c.Disabled() {
+		return vals, nil
+	}
+
+	batch := make(map[cacheKey[K]]int64, len(results))
+	for _, r := range results {
+		if !r.meta.NoStore {
+			batch[r.ck] = r.entry.size
+		}
+	}
+
+	c.mu.Lock()
+	if !c.admitMany(batch) {
+		c.mu.Unlock()
+		return vals, nil
+	}
+	c.batch = batch
+	now := time.Now().UnixNano()
+	for _, r := range results {
+		if !r.meta.NoStore {
+			r.entry.created, r.entry.published = now, true
+			c.add(r.ck, r.entry)
+			c.notify(r.ck, r.entry.v)
+		}
+	}
+	c.limit(cacheKey[K]{})
+	c.batch = nil
+	c.mu.Unlock()
+
+	for _, r := range results {
+		c.spend(r.entry.cost())
+		if r.meta.NoStore {
+			continue
+		}
+		c.forgetError(r.ck)
+		c.uncollapse(r.ck)
+		if c.store != nil {
+			bestBefore, expiry := time.Unix(0, r.entry.bestBefore), time.Unix(0, r.entry.expiry)
+			c.save(ctx, c.storeKey(r.key, r.ck), r.entry.v, bestBefore, expiry)
+		}
+		c.saveLastKnownGood(ctx, r.key, r.ck, r.entry.v)
+	}
+	return vals, nil
+}
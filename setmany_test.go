@@ -0,0 +1,123 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMany(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute)
+	value := func(v int) stampede.FetchFunc[int] {
+		return func(ctx context.Context) (int, error) { return v, nil }
+	}
+
+	vals, err := c.SetMany(ctx, map[string]stampede.FetchFunc[int]{"a": value(1), "b": value(2)})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, vals)
+	val, _ := c.Peek("b")
+	assert.Equal(t, 2, val)
+
+	// nothing is installed if any fetch fails
+	errOrigin := errors.New("origin down")
+	_, err = c.SetMany(ctx, map[string]stampede.FetchFunc[int]{
+		"a": value(3),
+		"b": func(ctx context.Context) (int, error) { return 0, errOrigin },
+	})
+	assert.Equal(t, errOrigin, err)
+	val, _ = c.Peek("a")
+	assert.Equal(t, 1, val)
+
+	// the error of the first failed key is returned
+	for i := 0; i < 10; i++ {
+		_, err = c.SetMany(ctx, map[string]stampede.FetchFunc[int]{
+			"a": func(ctx context.Context) (int, error) { return 0, errOrigin },
+			"b": func(ctx context.Context) (int, error) { return 0, errors.New("other") },
+		})
+		assert.Equal(t, errOrigin, err)
+	}
+}
+
+func TestSetManyInFlight(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan int)
+	go func() {
+		v, _, _ := c.Set(ctx, "a", func() (int, error) {
+			close(started)
+			<-release
+			return 1, nil
+		})
+		done <- v
+	}()
+	<-started
+
+	_, err := c.SetMany(ctx, map[string]stampede.FetchFunc[int]{
+		"a": func(ctx context.Context) (int, error) { return 2, nil },
+		"b": func(ctx context.Context) (int, error) { return 3, nil },
+	})
+	assert.NoError(t, err)
+
+	// the refresh in flight doesn't replace the installed value
+	close(release)
+	assert.Equal(t, 2, <-done)
+	val, _ := c.Peek("a")
+	assert.Equal(t, 2, val)
+}
+
+func TestSetManyFull(t *testing.T) {
+	ctx := context.Background()
+	sized := func(v int, size int64) stampede.FetchFunc[int] {
+		return stampede.ValueFunc(func(context.Context) (stampede.Value[int], error) {
+			return stampede.Value[int]{V: v, Size: size}, nil
+		})
+	}
+	fill := func(c *stampede.Cache[string, int], keys ...string) {
+		for _, key := range keys {
+			c.GetContext(ctx, key, sized(0, 1))
+		}
+	}
+	cached := func(c *stampede.Cache[string, int], keys ...string) (n int) {
+		for _, key := range keys {
+			if _, ok := c.Peek(key); ok {
+				n++
+			}
+		}
+		return n
+	}
+
+	// past the soft limit, new keys are not admitted
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithWatermarks(3, 8))
+	fill(c, "x", "y", "z")
+	vals, err := c.SetMany(ctx, map[string]stampede.FetchFunc[int]{"x": sized(1, 1), "a": sized(2, 1)})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"x": 1, "a": 2}, vals)
+	assert.Equal(t, 0, cached(c, "a"))
+	val, _ := c.Peek("x")
+	assert.Equal(t, 0, val)
+
+	// more keys than entries
+	c = stampede.NewCacheKV[string, int](2, time.Minute, time.Minute)
+	fill(c, "x", "y")
+	c.SetMany(ctx, map[string]stampede.FetchFunc[int]{"a": sized(1, 1), "b": sized(2, 1), "c": sized(3, 1)})
+	assert.Equal(t, 0, cached(c, "a", "b", "c"))
+	assert.Equal(t, 2, cached(c, "x", "y"))
+	c.SetMany(ctx, map[string]stampede.FetchFunc[int]{"a": sized(1, 1), "b": sized(2, 1)})
+	assert.Equal(t, 2, cached(c, "a", "b"))
+
+	// beyond the hard limit
+	c = stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithWatermarks(0, 4))
+	fill(c, "x", "y", "z")
+	c.SetMany(ctx, map[string]stampede.FetchFunc[int]{"a": sized(1, 3), "b": sized(2, 3)})
+	assert.Equal(t, 0, cached(c, "a", "b"))
+	c.SetMany(ctx, map[string]stampede.FetchFunc[int]{"a": sized(1, 2), "b": sized(2, 2)})
+	assert.Equal(t, 2, cached(c, "a", "b"))
+	assert.Equal(t, 0, cached(c, "x", "y", "z"))
+}
@@ -0,0 +1,38 @@
+package stampede
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// getShadow records whether get would have served a cached value, and fetches from the
+// origin regardless, see WithShadowMode. The store is not consulted.
+func (c *Cache[K, V]) getShadow(ctx context.Context, key K, ck cacheKey[K], freshOnly bool, fn FetchFunc[V]) (V, error) {
+	val, ok := c.lookup(ck)
+	hit := ok && (val.IsFresh() || !freshOnly && !val.IsExpired())
+	if hit {
+		atomic.AddInt64(&c.shadowHits, 1)
+	} else {
+		atomic.AddInt64(&c.shadowMisses, 1)
+	}
+	if c.shadowReport != nil {
+		var cached any
+		if hit {
+			cached = val.Value()
+		}
+		c.shadowReport(key, cached, hit)
+	}
+
+	ctx, endFetch := c.startFetch(ctx, ck)
+	v, err := c.fetch(ctx, key, fn)
+	endFetch(err)
+	if err != nil {
+		return v, err
+	}
+
+	bestBefore, expiry := c.expiry(ctx, v)
+	c.mu.Lock()
+	c.add(ck, c.entry(key, ck, v, bestBefore, expiry))
+	c.mu.Unlock()
+	return v, nil
+}
@@ -0,0 +1,38 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadowMode(t *testing.T) {
+	ctx := context.Background()
+
+	var reported []any
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithShadowMode(func(key, cached any, hit bool) {
+		reported = append(reported, cached)
+	}))
+
+	calls := 0
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	for i := 1; i <= 3; i++ {
+		val, err := c.Get(ctx, "k", fetch)
+		assert.NoError(t, err)
+		assert.Equal(t, i, val)
+	}
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []any{nil, 1, 2}, reported)
+
+	stats := c.Stats()
+	assert.Equal(t, int64(2), stats.ShadowHits)
+	assert.Equal(t, int64(1), stats.ShadowMisses)
+	assert.Equal(t, 1, stats.Entries)
+}
@@ -0,0 +1,147 @@
+package stampede
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// ErrEntryTooLarge is returned by SlabStore.Set for entries larger than a slab.
+var ErrEntryTooLarge = errors.New("stampede: entry too large")
+
+// SlabStore is an in-memory Store keeping encoded entries in a ring of large,
+// preallocated slabs. Its index holds no pointers, so millions of entries don't add to
+// the time the garbage collector spends scanning the heap. Once all slabs are full,
+// the oldest slab is reused, dropping its entries.
+//
+// Used with WithStore, the in-memory cache in front of a SlabStore holds the decoded
+// entries that are accessed most, and can be kept small.
+type SlabStore struct {
+	mu    sync.Mutex
+	slabs [][]byte
+	cur   int                 // slab being written
+	gens  []uint32            // generation of every slab, bumped on reuse
+	index map[uint64]slabSlot // by xxhash of the key
+	keys  [][]uint64          // hashes of the keys written to every slab
+}
+
+type slabSlot struct {
+	slab   uint32
+	gen    uint32
+	off    uint32
+	n      uint32 // length of the key and value
+	expiry int64  // unix nanoseconds, 0 for none
+}
+
+// NewSlabStore returns a SlabStore of count slabs of slabSize bytes each. A count below
+// 1 is treated as 1.
+func NewSlabStore(slabSize, count int) *SlabStore {
+	if count < 1 {
+		count = 1
+	}
+	if slabSize < 0 {
+		slabSize = 0
+	}
+	s := &SlabStore{
+		slabs: make([][]byte, count),
+		gens:  make([]uint32, count),
+		index: make(map[uint64]slabSlot),
+		keys:  make([][]uint64, count),
+	}
+	for i := range s.slabs {
+		s.slabs[i] = make([]byte, 0, slabSize)
+	}
+	return s
+}
+
+func (s *SlabStore) Get(ctx context.Context, key string) ([]byte, error) {
+	h := xxhash.Sum64String(key)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slot, ok := s.index[h]
+	if !ok || slot.gen != s.gens[slot.slab] {
+		return nil, ErrNotFound
+	}
+	if slot.expiry != 0 && slot.expiry < time.Now().UnixNano() {
+		delete(s.index, h)
+		return nil, ErrNotFound
+	}
+
+	b := s.slabs[slot.slab][slot.off : slot.off+slot.n]
+	klen := int(binary.LittleEndian.Uint16(b))
+	if string(b[2:2+klen]) != key {
+		return nil, ErrNotFound // hash collision
+	}
+	return append([]byte(nil), b[2+klen:]...), nil
+}
+
+func (s *SlabStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	n := 2 + len(key) + len(value)
+	if n > cap(s.slabs[0]) || len(key) > 0xffff {
+		return ErrEntryTooLarge
+	}
+	h := xxhash.Sum64String(key)
+
+	var expiry int64
+	if ttl > 0 {
+		expiry = time.Now().Add(ttl).UnixNano()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.slabs[s.cur])+n > cap(s.slabs[s.cur]) {
+		s.rotate()
+	}
+	slab := s.slabs[s.cur]
+	off := len(slab)
+	slab = binary.LittleEndian.AppendUint16(slab, uint16(len(key)))
+	slab = append(slab, key...)
+	slab = append(slab, value...)
+	s.slabs[s.cur] = slab
+	s.keys[s.cur] = append(s.keys[s.cur], h)
+
+	s.index[h] = slabSlot{
+		slab:   uint32(s.cur),
+		gen:    s.gens[s.cur],
+		off:    uint32(off),
+		n:      uint32(n),
+		expiry: expiry,
+	}
+	return nil
+}
+
+// rotate moves on to the next slab, dropping its entries. s.mu must be held.
+func (s *SlabStore) rotate() {
+	s.cur = (s.cur + 1) % len(s.slabs)
+	s.slabs[s.cur] = s.slabs[s.cur][:0]
+	s.gens[s.cur]++
+
+	for _, h := range s.keys[s.cur] {
+		if slot, ok := s.index[h]; ok && slot.slab == uint32(s.cur) {
+			delete(s.index, h)
+		}
+	}
+	s.keys[s.cur] = s.keys[s.cur][:0]
+}
+
+func (s *SlabStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.index, xxhash.Sum64String(key))
+	return nil
+}
+
+// Len returns the number of entries, including expired ones not accessed since.
+func (s *SlabStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.index)
+}
@@ -0,0 +1,58 @@
+package stampede_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlabStore(t *testing.T) {
+	ctx := context.Background()
+	s := stampede.NewSlabStore(64, 2)
+
+	assert.NoError(t, s.Set(ctx, "k", []byte("value"), 0))
+	b, err := s.Get(ctx, "k")
+	assert.NoError(t, err)
+	assert.Equal(t, "value", string(b))
+
+	assert.NoError(t, s.Set(ctx, "short", []byte("v"), time.Nanosecond))
+	time.Sleep(time.Millisecond)
+	_, err = s.Get(ctx, "short")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+
+	assert.ErrorIs(t, s.Set(ctx, "big", make([]byte, 64), 0), stampede.ErrEntryTooLarge)
+
+	// filling both slabs drops the entries of the oldest one
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, s.Set(ctx, "key"+strconv.Itoa(i), []byte("0123456789"), 0))
+	}
+	_, err = s.Get(ctx, "k")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+	b, err = s.Get(ctx, "key9")
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789", string(b))
+
+	assert.NoError(t, s.Delete(ctx, "key9"))
+	_, err = s.Get(ctx, "key9")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+}
+
+func TestSlabStoreOneSlab(t *testing.T) {
+	ctx := context.Background()
+	s := stampede.NewSlabStore(16, 0)
+
+	assert.NoError(t, s.Set(ctx, "a", []byte("12345"), 0))
+	assert.NoError(t, s.Set(ctx, "b", []byte("12345"), 0))
+	// the only slab is reused once full
+	assert.NoError(t, s.Set(ctx, "c", []byte("12345"), 0))
+	_, err := s.Get(ctx, "a")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+	b, err := s.Get(ctx, "c")
+	assert.NoError(t, err)
+	assert.Equal(t, "12345", string(b))
+	assert.Equal(t, 1, s.Len())
+}
@@ -0,0 +1,37 @@
+package stampede
+
+import "time"
+
+// slide extends the lifetime of val, the fresh entry of ck just read, see
+// WithSlidingExpiration. Extensions by less than a tenth of the fresh period are
+// skipped, to not lock the cache on every read.
+func (c *Cache[K, V]) slide(ck cacheKey[K], val value[K, V]) {
+	if c.slidingMax <= 0 {
+		return
+	}
+
+	freshFor, ttl := c.valueLifetime(val.v)
+	now := time.Now().UnixNano()
+	bestBefore, expiry := now+int64(freshFor), now+int64(ttl)
+	if limit := val.created + int64(c.slidingMax); expiry > limit {
+		expiry = limit
+		if bestBefore > limit {
+			bestBefore = limit
+		}
+	}
+	if bestBefore-val.bestBefore < int64(freshFor/10) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cur, ok := c.values.Peek(ck)
+	if !ok || cur.created != val.created || bestBefore <= cur.bestBefore {
+		return
+	}
+	cur.bestBefore = bestBefore
+	if expiry > cur.expiry {
+		cur.expiry = expiry
+	}
+	c.values.Add(ck, cur)
+}
@@ -0,0 +1,38 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlidingExpiration(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, 50*time.Millisecond, 50*time.Millisecond,
+		stampede.WithSlidingExpiration(150*time.Millisecond))
+
+	var calls int
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	c.GetFresh(ctx, "session", fetch)
+	c.GetFresh(ctx, "idle", fetch)
+	for i := 0; i < 4; i++ {
+		time.Sleep(25 * time.Millisecond)
+		val, err := c.GetFresh(ctx, "session", fetch)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, val)
+	}
+
+	// the idle entry aged out, the other one lives until its max age
+	val, _ := c.GetFresh(ctx, "idle", fetch)
+	assert.Equal(t, 3, val)
+	time.Sleep(75 * time.Millisecond)
+	val, _ = c.GetFresh(ctx, "session", fetch)
+	assert.Equal(t, 4, val)
+}
@@ -0,0 +1,88 @@
+package stampede
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// spooler keeps response bodies in memory up to threshold bytes, and spools larger
+// ones to a temporary file in dir, see HandlerWithSpool.
+type spooler struct {
+	dir       string
+	threshold int
+}
+
+// spool is the body of a response being written.
+type spool struct {
+	s   *spooler
+	buf bytes.Buffer
+	f   *os.File
+	n   int64
+	err error
+}
+
+func (s *spooler) spool() *spool {
+	return &spool{s: s}
+}
+
+func (s *spool) Write(b []byte) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	s.n += int64(len(b))
+	if s.f == nil && (s.s == nil || s.buf.Len()+len(b) <= s.s.threshold) {
+		return s.buf.Write(b)
+	}
+	if s.f == nil {
+		if s.f, s.err = os.CreateTemp(s.s.dir, "stampede-*"); s.err != nil {
+			return 0, s.err
+		}
+		// the file is only reachable through the cached response, and is closed by its
+		// finalizer once the response is evicted and no longer being served
+		os.Remove(s.f.Name())
+		if _, s.err = s.buf.WriteTo(s.f); s.err != nil {
+			return 0, s.err
+		}
+	}
+	var n int
+	n, s.err = s.f.Write(b)
+	return n, s.err
+}
+
+// body sets the body of val to the spooled body.
+func (s *spool) body(val *responseValue) error {
+	if s.err != nil {
+		if s.f != nil {
+			s.f.Close()
+		}
+		return s.err
+	}
+	if s.f != nil {
+		val.spooled, val.size = s.f, s.n
+		return nil
+	}
+	val.body = s.buf.Bytes()
+	return nil
+}
+
+// errSpool is returned for responses whose body couldn't be spooled, which are not
+// cached.
+var errSpool = errors.New("stampede: spooling response")
+
+// HandlerWithSpool is like Handler, but spools the bodies of responses larger than
+// threshold bytes to dir, see WithSpool.
+func HandlerWithSpool(cacheSize int, ttl time.Duration, dir string, threshold int, paths ...string) func(next http.Handler) http.Handler {
+	return NewHandler(cacheSize, ttl, WithPaths(paths...), WithSpool(dir, threshold))
+}
+
+// reader returns the body of v for reading.
+func (v responseValue) reader() io.ReadSeeker {
+	if v.spooled != nil {
+		return io.NewSectionReader(v.spooled, 0, v.size)
+	}
+	return bytes.NewReader(v.body)
+}
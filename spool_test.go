@@ -0,0 +1,78 @@
+package stampede_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerWithSpool(t *testing.T) {
+	dir := t.TempDir()
+	body := bytes.Repeat([]byte("0123456789"), 100_000)
+
+	var calls int32
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body)
+	})
+	h := stampede.HandlerWithSpool(16, time.Minute, dir, 1024)(app)
+
+	get := func(header http.Header) *http.Response {
+		r := httptest.NewRequest("GET", "/report", nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	for i := 0; i < 2; i++ {
+		res := get(nil)
+		b, _ := io.ReadAll(res.Body)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, body, b)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	res := get(http.Header{"Range": {"bytes=10-19"}})
+	b, _ := io.ReadAll(res.Body)
+	assert.Equal(t, http.StatusPartialContent, res.StatusCode)
+	assert.Equal(t, "0123456789", string(b))
+
+	// spooled files are deleted right away, and only kept open
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestHandlerWithSpoolError(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789"), 1000)
+
+	var calls int32
+	var writeErr error
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		_, writeErr = w.Write(body)
+	})
+	// the spool directory doesn't exist
+	h := stampede.HandlerWithSpool(16, time.Minute, t.TempDir()+"/missing", 1024)(app)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/report", nil))
+		assert.NoError(t, writeErr)
+		assert.Equal(t, body, w.Body.Bytes())
+	}
+	// responses that couldn't be spooled aren't cached
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
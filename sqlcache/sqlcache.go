@@ -0,0 +1,144 @@
+// Package sqlcache caches and coalesces read queries with stampede. Identical concurrent
+// queries (same normalized query and args) run once against the database, results are
+// materialized and cached for subsequent callers.
+package sqlcache
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/dadav/stampede"
+)
+
+// Queryer is implemented by *sql.DB, *sql.Conn, *sql.Tx and sqlx.DB.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// Cache caches the rows of queries run against a Queryer, scanned into T.
+type Cache[T any] struct {
+	db    Queryer
+	scan  func(rows *sql.Rows) (T, error)
+	cache *stampede.Cache[uint64, []T]
+}
+
+// New returns a Cache running queries against db and scanning every row with scan.
+func New[T any](db Queryer, scan func(rows *sql.Rows) (T, error), size int, freshFor, ttl time.Duration, opts ...stampede.Option) *Cache[T] {
+	return &Cache[T]{
+		db:    db,
+		scan:  scan,
+		cache: stampede.NewCacheKV[uint64, []T](size, freshFor, ttl, opts...),
+	}
+}
+
+// Query returns the scanned rows of query, served from cache when possible.
+func (c *Cache[T]) Query(ctx context.Context, query string, args ...any) ([]T, error) {
+	return c.cache.GetContext(ctx, Key(query, args...), func(ctx context.Context) ([]T, error) {
+		return c.query(ctx, query, args...)
+	})
+}
+
+// QueryFresh is like Query, but never serves stale rows.
+func (c *Cache[T]) QueryFresh(ctx context.Context, query string, args ...any) ([]T, error) {
+	return c.cache.GetFreshContext(ctx, Key(query, args...), func(ctx context.Context) ([]T, error) {
+		return c.query(ctx, query, args...)
+	})
+}
+
+func (c *Cache[T]) query(ctx context.Context, query string, args ...any) ([]T, error) {
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []T
+	for rows.Next() {
+		v, err := c.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, rows.Err()
+}
+
+// Key returns the cache key of query and args. Whitespace outside of quoted literals
+// is collapsed, so formatting differences don't split the cache. Args are keyed by the
+// value sent to the database: pointers are dereferenced, driver.Valuer args are keyed
+// by their Value and times by their instant.
+func Key(query string, args ...any) uint64 {
+	parts := make([]string, 0, len(args)+1)
+	parts = append(parts, NormalizeQuery(query))
+	for _, arg := range args {
+		parts = append(parts, argString(arg))
+	}
+	return stampede.StringToHash(parts...)
+}
+
+// argString returns the representation of arg in keys.
+func argString(arg any) string {
+	for {
+		switch a := arg.(type) {
+		case nil:
+			return "nil"
+		case sql.NamedArg:
+			return "@" + a.Name + "=" + argString(a.Value)
+		case time.Time:
+			return "time.Time:" + a.UTC().Format(time.RFC3339Nano)
+		case driver.Valuer:
+			if rv := reflect.ValueOf(a); rv.Kind() == reflect.Pointer && rv.IsNil() {
+				return "nil"
+			}
+			v, err := a.Value()
+			if err != nil {
+				return fmt.Sprintf("%T:%v", arg, arg)
+			}
+			arg = v
+			continue
+		}
+
+		rv := reflect.ValueOf(arg)
+		if rv.Kind() != reflect.Pointer {
+			return fmt.Sprintf("%T:%v", arg, arg)
+		}
+		if rv.IsNil() {
+			return "nil"
+		}
+		arg = rv.Elem().Interface()
+	}
+}
+
+// NormalizeQuery collapses runs of whitespace outside of quoted literals and
+// identifiers into a single space, and trims the query.
+func NormalizeQuery(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+
+	var quote rune
+	space := false
+	for _, r := range strings.TrimSpace(query) {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package sqlcache_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede/sqlcache"
+	"github.com/stretchr/testify/assert"
+)
+
+var queries int64
+
+// fakeDriver answers every query with the rows 1, 2 and 3, slowly.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+type fakeStmt struct{}
+
+func (fakeStmt) Close() error                                    { return nil }
+func (fakeStmt) NumInput() int                                   { return -1 }
+func (fakeStmt) Exec(args []driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	atomic.AddInt64(&queries, 1)
+	time.Sleep(50 * time.Millisecond)
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct{ n int64 }
+
+func (r *fakeRows) Columns() []string { return []string{"id"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n == 3 {
+		return io.EOF
+	}
+	r.n++
+	dest[0] = r.n
+	return nil
+}
+
+func init() {
+	sql.Register("sqlcache-fake", fakeDriver{})
+}
+
+func TestQuery(t *testing.T) {
+	db, err := sql.Open("sqlcache-fake", "")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	c := sqlcache.New(db, func(rows *sql.Rows) (int, error) {
+		var id int
+		err := rows.Scan(&id)
+		return id, err
+	}, 16, time.Minute, time.Minute)
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			query := "SELECT id FROM users WHERE tenant = ?"
+			if i%2 == 0 {
+				query = "SELECT id\n  FROM users\n  WHERE tenant = ?"
+			}
+			ids, err := c.Query(ctx, query, "a")
+			assert.NoError(t, err)
+			assert.Equal(t, []int{1, 2, 3}, ids)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int64(1), atomic.LoadInt64(&queries))
+
+	_, err = c.Query(ctx, "SELECT id FROM users WHERE tenant = ?", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&queries))
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	assert.Equal(t, "SELECT * FROM t WHERE a = 'x  y'", sqlcache.NormalizeQuery("  SELECT *\n\tFROM t  WHERE a = 'x  y' "))
+	assert.NotEqual(t, sqlcache.Key("SELECT ?", 1), sqlcache.Key("SELECT ?", "1"))
+}
+
+func TestKeyArgs(t *testing.T) {
+	// pointers are keyed by the value they point to, not by their address
+	a, b := 1, 2
+	assert.NotEqual(t, sqlcache.Key("SELECT ?", &a), sqlcache.Key("SELECT ?", &b))
+	c := 1
+	assert.Equal(t, sqlcache.Key("SELECT ?", &a), sqlcache.Key("SELECT ?", &c))
+	assert.Equal(t, sqlcache.Key("SELECT ?", &a), sqlcache.Key("SELECT ?", 1))
+	var nilInt *int
+	assert.Equal(t, sqlcache.Key("SELECT ?", nilInt), sqlcache.Key("SELECT ?", nil))
+
+	// valuers are keyed by their value
+	assert.Equal(t, sqlcache.Key("SELECT ?", sql.NullString{String: "x", Valid: true}), sqlcache.Key("SELECT ?", sql.NullString{String: "x", Valid: true}))
+	assert.NotEqual(t, sqlcache.Key("SELECT ?", sql.NullString{String: "x", Valid: true}), sqlcache.Key("SELECT ?", sql.NullString{String: "y", Valid: true}))
+	assert.Equal(t, sqlcache.Key("SELECT ?", sql.NullString{}), sqlcache.Key("SELECT ?", nil))
+
+	// times are keyed by their instant, without the monotonic clock reading
+	now := time.Now()
+	assert.Equal(t, sqlcache.Key("SELECT ?", now), sqlcache.Key("SELECT ?", now.Round(0).In(time.FixedZone("x", 3600))))
+	assert.NotEqual(t, sqlcache.Key("SELECT ?", now), sqlcache.Key("SELECT ?", now.Add(time.Nanosecond)))
+}
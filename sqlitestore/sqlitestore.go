@@ -0,0 +1,113 @@
+// Package sqlitestore is a stampede.Store backed by a table in a SQLite database file,
+// a persistent store for CLIs and edge deployments without a cache server.
+//
+// The package doesn't depend on a SQLite driver: New takes a *sql.DB opened with the
+// driver of choice, e.g. modernc.org/sqlite or github.com/mattn/go-sqlite3. The database
+// is switched to WAL mode, so reads don't block on writes.
+//
+// Expired rows are treated as missing, and deleted by Compact.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/dadav/stampede"
+)
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Store stores entries in a SQLite table.
+type Store struct {
+	db *sql.DB
+
+	get, set, del, compact string
+}
+
+var _ stampede.Store = (*Store)(nil)
+
+// New returns a store keeping its entries in table of db, which is created if it
+// doesn't exist.
+func New(ctx context.Context, db *sql.DB, table string) (*Store, error) {
+	if !identifier.MatchString(table) {
+		return nil, fmt.Errorf("sqlitestore: invalid table name %q", table)
+	}
+
+	stmts := []string{
+		"PRAGMA journal_mode=WAL",
+		"CREATE TABLE IF NOT EXISTS " + table + " (key TEXT PRIMARY KEY, value BLOB NOT NULL, expires_at INTEGER NOT NULL) WITHOUT ROWID",
+		"CREATE INDEX IF NOT EXISTS " + table + "_expires_at ON " + table + " (expires_at)",
+	}
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("sqlitestore: %w", err)
+		}
+	}
+
+	return &Store{
+		db:      db,
+		get:     "SELECT value FROM " + table + " WHERE key = ? AND (expires_at = 0 OR expires_at > ?)",
+		set:     "INSERT INTO " + table + " (key, value, expires_at) VALUES (?, ?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value, expires_at = excluded.expires_at",
+		del:     "DELETE FROM " + table + " WHERE key = ?",
+		compact: "DELETE FROM " + table + " WHERE expires_at != 0 AND expires_at <= ?",
+	}, nil
+}
+
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRowContext(ctx, s.get, key, time.Now().UnixNano()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, stampede.ErrNotFound
+	}
+	return value, err
+}
+
+// Set writes value for key, expiring after ttl. A zero ttl doesn't expire.
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var expiresAt int64
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl).UnixNano()
+	}
+	_, err := s.db.ExecContext(ctx, s.set, key, value, expiresAt)
+	return err
+}
+
+func (s *Store) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, s.del, key)
+	return err
+}
+
+// Compact deletes the expired rows, and returns how many were deleted. With vacuum, the
+// database file is rebuilt afterwards to return the space of the deleted rows to the
+// file system, which blocks all other writers while it runs.
+func (s *Store) Compact(ctx context.Context, vacuum bool) (int64, error) {
+	res, err := s.db.ExecContext(ctx, s.compact, time.Now().UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("sqlitestore: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	if vacuum {
+		if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+			return n, fmt.Errorf("sqlitestore: %w", err)
+		}
+	}
+	return n, nil
+}
+
+// RunCompaction compacts the store every interval, without vacuum, until ctx is done.
+func (s *Store) RunCompaction(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Compact(ctx, false)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
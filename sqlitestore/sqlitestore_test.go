@@ -0,0 +1,130 @@
+package sqlitestore_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/dadav/stampede/sqlitestore"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver runs the statements of the store against a map, like SQLite would.
+type fakeDriver struct {
+	mu     sync.Mutex
+	rows   map[string]fakeRow
+	vacuum int
+}
+
+type fakeRow struct {
+	value     []byte
+	expiresAt int64
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{c.d, query}, nil }
+func (fakeConn) Close() error                                { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                   { return nil, driver.ErrSkip }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return -1 }
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		s.d.rows[args[0].(string)] = fakeRow{value: args[1].([]byte), expiresAt: args[2].(int64)}
+	case strings.HasPrefix(s.query, "DELETE") && strings.Contains(s.query, "key = ?"):
+		delete(s.d.rows, args[0].(string))
+	case strings.HasPrefix(s.query, "DELETE"):
+		var n int64
+		for key, row := range s.d.rows {
+			if row.expiresAt != 0 && row.expiresAt <= args[0].(int64) {
+				delete(s.d.rows, key)
+				n++
+			}
+		}
+		return driver.RowsAffected(n), nil
+	case s.query == "VACUUM":
+		s.d.vacuum++
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	row, ok := s.d.rows[args[0].(string)]
+	if !ok || (row.expiresAt != 0 && row.expiresAt <= args[1].(int64)) {
+		return &fakeRows{}, nil
+	}
+	return &fakeRows{values: [][]byte{row.value}}, nil
+}
+
+type fakeRows struct{ values [][]byte }
+
+func (r *fakeRows) Columns() []string { return []string{"value"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+var fake = &fakeDriver{rows: map[string]fakeRow{}}
+
+func init() {
+	sql.Register("sqlitestore-fake", fake)
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlitestore-fake", "cache.db")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	_, err = sqlitestore.New(ctx, db, "cache; DROP TABLE users")
+	assert.Error(t, err)
+
+	s, err := sqlitestore.New(ctx, db, "cache")
+	assert.NoError(t, err)
+
+	_, err = s.Get(ctx, "a")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+
+	assert.NoError(t, s.Set(ctx, "a", []byte("1"), 0))
+	assert.NoError(t, s.Set(ctx, "b", []byte("2"), time.Millisecond))
+	v, err := s.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), v)
+
+	time.Sleep(2 * time.Millisecond)
+	_, err = s.Get(ctx, "b")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+
+	n, err := s.Compact(ctx, true)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, 1, fake.vacuum)
+
+	assert.NoError(t, s.Delete(ctx, "a"))
+	_, err = s.Get(ctx, "a")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+}
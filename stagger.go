@@ -0,0 +1,28 @@
+package stampede
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// staggered reports whether the refresh of the stale val is left to other replicas for
+// now, see WithRefreshStagger. Every replica takes its turn at a point within the
+// stagger window after val went stale, given by the hash of the key, the replica and the
+// epoch of val, so the replica refreshing a key first changes from one epoch to the next.
+func (c *Cache[K, V]) staggered(key K, ck cacheKey[K], val value[K, V]) bool {
+	if c.staggerWindow <= 0 {
+		return false
+	}
+
+	var epoch [8]byte
+	binary.LittleEndian.PutUint64(epoch[:], uint64(val.bestBefore/int64(c.staggerWindow)))
+	h := xxhash.New()
+	h.WriteString(c.storeKey(key, ck))
+	h.WriteString(c.replicaID)
+	h.Write(epoch[:])
+
+	turn := int64(h.Sum64() % uint64(c.staggerWindow))
+	return time.Now().UnixNano() < val.bestBefore+turn
+}
@@ -0,0 +1,37 @@
+package stampede_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshStagger(t *testing.T) {
+	ctx := context.Background()
+	var calls int32
+	fetch := func() (int, error) { return int(atomic.AddInt32(&calls, 1)), nil }
+
+	c := stampede.NewCacheKV[string, int](8, 10*time.Millisecond, 25*time.Hour, stampede.WithRefreshStagger("a", 24*time.Hour))
+	defer c.Close()
+	c.Get(ctx, "k", fetch)
+	time.Sleep(15 * time.Millisecond)
+
+	// stale values are served without refreshing until the turn of the replica, which
+	// is hardly ever that soon within a day
+	for i := 0; i < 5; i++ {
+		v, err := c.Get(ctx, "k", fetch)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, v)
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// fresh values are still required by GetFresh
+	v, err := c.GetFresh(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, v)
+}
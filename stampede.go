@@ -2,7 +2,9 @@ package stampede
 
 import (
 	"context"
+	"encoding/binary"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cespare/xxhash/v2"
@@ -13,49 +15,376 @@ import (
 // Prevents cache stampede https://en.wikipedia.org/wiki/Cache_stampede by only running a
 // single data fetch operation per expired / missing key regardless of number of requests to that key.
 
-func NewCache(size int, freshFor, ttl time.Duration) *Cache[any, any] {
-	return NewCacheKV[any, any](size, freshFor, ttl)
+func NewCache(size int, freshFor, ttl time.Duration, opts ...Option) *Cache[any, any] {
+	return NewCacheKV[any, any](size, freshFor, ttl, opts...)
 }
 
-func NewCacheKV[K comparable, V any](size int, freshFor, ttl time.Duration) *Cache[K, V] {
-	values, _ := lru.New[K, value[V]](size)
-	return &Cache[K, V]{
+// NewCacheKV returns a cache of up to size values, fresh for freshFor and kept for ttl,
+// see Lifetime. Invalid arguments are normalized: a size below 1 is 1, negative
+// durations are 0 and a ttl shorter than freshFor is freshFor. Use Validate or
+// MustNewCacheKV to reject them instead.
+func NewCacheKV[K comparable, V any](size int, freshFor, ttl time.Duration, opts ...Option) *Cache[K, V] {
+	if size < 1 {
+		size = 1
+	}
+	if freshFor < 0 {
+		freshFor = 0
+	}
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Cache[K, V]{
 		freshFor: freshFor,
 		ttl:      ttl,
-		values:   values,
+		options:  newOptions(opts),
+		ctx:      ctx,
+		cancel:   cancel,
+		keyers:   mayBeKeyer[K](),
+		anyKeys:  mayBeAny[K](),
+	}
+	if l := c.options.lifetime; l != nil {
+		c.freshFor, c.ttl = l.Fresh, l.TTL()
+	}
+	c.ramp = newRamp(c.rampFor, c.rampRate)
+	if c.missingCapacity > 0 {
+		c.missing = newMissingFilter(c.missingCapacity, c.missingRotate)
+	}
+	if c.workers > 0 {
+		c.pool = newRefreshPool()
+	}
+	if c.leaseTTL > 0 {
+		c.leaseID = newLeaseID()
+	}
+	c.values, _ = lru.NewWithEvict[cacheKey[K], value[K, V]](size, c.onEvict)
+	c.capacity = size
+	if c.interning {
+		c.strings = newInterner(2 * size)
+	}
+	if every := c.janitorInterval(); every > 0 {
+		c.janitor(every)
+	}
+	if c.anyKeys {
+		// the keys of the hot index can't be decoded to their dynamic types
+		c.warmKeys = 0
+	}
+	if c.warmKeys > 0 && c.store != nil {
+		// a slow or unreachable store mustn't hold up the creation of the cache
+		c.goBackground(func() { c.warmFromStore(ctx) })
+		c.hotPublisher()
 	}
+	return c
 }
 
 type Cache[K comparable, V any] struct {
-	values *lru.Cache[K, value[V]]
+	values   *lru.Cache[cacheKey[K], value[K, V]]
+	capacity int // of values, in entries
 
+	ttlMu    sync.RWMutex
 	freshFor time.Duration
 	ttl      time.Duration
 
+	options
+	keyers  bool // keys may implement Keyer
+	anyKeys bool // keys may be of several types, see mayBeAny
+
+	strings *interner // see WithInterning
+
+	size int64 // total size of all entries, see Sizer
+
 	mu        sync.RWMutex
-	callGroup singleflight.Group[K, V]
+	callGroup singleflight.Group[cacheKey[K], V]
+
+	// fetches holds the contexts of in-flight traced fetches, see Tracer
+	fetchesMu sync.Mutex
+	fetches   map[cacheKey[K]]context.Context
+
+	// ctx is done once the cache is closed, wg tracks the goroutines owned by the cache
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	closeMu sync.RWMutex
+	closed  bool
+
+	background int64 // number of live background goroutines
+
+	shadowHits, shadowMisses int64
+
+	readOnly int32
+	disabled int32
+
+	// waiters counts the callers of do per key, see WithMaxWaiters
+	waitersMu sync.Mutex
+	waiters   map[cacheKey[K]]int
+
+	// entryLocks serialize the updates of keys, see Update
+	entryLocksMu sync.Mutex
+	entryLocks   map[cacheKey[K]]*entryLock
+
+	// collapsed holds the results of recent fetches, see WithCollapseWindow
+	collapsedMu sync.Mutex
+	collapsed   map[cacheKey[K]]collapsed[V]
+
+	// classes counts the outcomes of gets per key class, see ClassStats
+	classesMu sync.Mutex
+	classes   map[string]*ClassStats
+
+	// pinned holds the pinned keys, with their entries once evicted from the lru, see
+	// Pin. It is guarded by mu.
+	pinned map[cacheKey[K]]*value[K, V]
+
+	// tags indexes the keys of entries by their tags, see Tagger. It is guarded by mu.
+	tags map[string]map[cacheKey[K]]struct{}
+
+	// subscribers receive the events of the cache, see Events. They are guarded by mu.
+	subscribers  []chan Event[K, V]
+	invalidating bool         // entries removed by the lru are invalidated, not evicted
+	invalidation *Event[K, V] // pending EventInvalidate of the removed entries
+
+	softInvalidating bool                  // entries added are invalidated, see InvalidateMany
+	batch            map[cacheKey[K]]int64 // sizes of the entries being added by SetMany
+	dropped          int64
+
+	refreshTimeouts int64 // see WithRefreshBudget
+
+	pool *refreshPool // nil without WithRefreshWorkers
+
+	// watchers receive the values fetched for their keys, see Watch. They are guarded
+	// by mu.
+	watchers map[cacheKey[K]][]chan V
+
+	latency latencies // of recent fetches
+
+	ramp *ramp // nil without a startup ramp, see WithStartupRamp
+
+	leaseID string // identifies the leases of the cache, see WithStoreLeases
+
+	hot hotKeys[K] // see WithStoreWarm
+
+	costMu  sync.Mutex
+	spent   Cost
+	avoided Cost
+
+	// negatives holds the cached errors of keys, see ErrorCache
+	negativesMu  sync.Mutex
+	negatives    map[cacheKey[K]]negative
+	circuitUntil int64 // unix nanoseconds, see ErrorOpenCircuit
+
+	missing *missingFilter // nil without WithMissingFilter
+
+	// retries holds the failed background refreshes to retry, see WithRefreshRetry
+	retriesMu sync.Mutex
+	retries   map[cacheKey[K]]*retry[K, V]
 }
 
 func (c *Cache[K, V]) Get(ctx context.Context, key K, fn singleflight.DoFunc[V]) (V, error) {
-	return c.get(ctx, key, false, fn)
+	return c.GetContext(ctx, key, fetchFunc(fn))
 }
 
 func (c *Cache[K, V]) GetFresh(ctx context.Context, key K, fn singleflight.DoFunc[V]) (V, error) {
-	return c.get(ctx, key, true, fn)
+	return c.GetFreshContext(ctx, key, fetchFunc(fn))
 }
 
 func (c *Cache[K, V]) Set(ctx context.Context, key K, fn singleflight.DoFunc[V]) (V, bool, error) {
-	v, err, shared := c.callGroup.Do(key, c.set(key, fn))
-	return v, shared, err
+	return c.SetContext(ctx, key, fetchFunc(fn))
+}
+
+// FetchFunc fetches a value with the context given by the cache: the context of the
+// caller for synchronous fetches, and the context built by the ContextPolicy for
+// background refreshes.
+type FetchFunc[V any] func(ctx context.Context) (V, error)
+
+func fetchFunc[V any](fn singleflight.DoFunc[V]) FetchFunc[V] {
+	return func(context.Context) (V, error) {
+		return fn()
+	}
+}
+
+// GetContext is like Get, but passes a context to fn.
+func (c *Cache[K, V]) GetContext(ctx context.Context, key K, fn FetchFunc[V]) (V, error) {
+	v, err := c.getRequest(ctx, c.normalizeKey(key), false, fn)
+	return c.read(v), err
 }
 
-func (c *Cache[K, V]) get(ctx context.Context, key K, freshOnly bool, fn singleflight.DoFunc[V]) (V, error) {
+// GetFreshContext is like GetFresh, but passes a context to fn.
+func (c *Cache[K, V]) GetFreshContext(ctx context.Context, key K, fn FetchFunc[V]) (V, error) {
+	v, err := c.getRequest(ctx, c.normalizeKey(key), true, fn)
+	return c.read(v), err
+}
+
+// SetContext is like Set, but passes a context to fn.
+func (c *Cache[K, V]) SetContext(ctx context.Context, key K, fn FetchFunc[V]) (V, bool, error) {
+	key = c.normalizeKey(key)
+	if c.Disabled() {
+		v, err := c.bypass(ctx, key, c.cacheKey(key), fn)
+		return v, false, err
+	}
+	v, shared, err := c.do(ctx, key, c.cacheKey(key), fn)
+	return c.read(v), shared, err
+}
+
+// GetFreshWithin is like GetFresh, but waits at most maxWait for the refresh of a stale
+// value and returns the stale value if the refresh takes longer. The refresh still
+// lands in the background. Missing and expired values are always waited for.
+func (c *Cache[K, V]) GetFreshWithin(ctx context.Context, key K, maxWait time.Duration, fn singleflight.DoFunc[V]) (V, error) {
+	if c.shadow || c.Disabled() || LowPriority(ctx) {
+		return c.GetFreshContext(ctx, key, fetchFunc(fn))
+	}
+	key = c.normalizeKey(key)
+	ck := c.cacheKey(key)
+	val, ok := c.lookup(ck)
+	if ok && !c.ReadOnly() && !c.verify(ctx, key, val) {
+		v, err := c.miss(ctx, key, ck, fetchFunc(fn))
+		return c.read(v), err
+	}
+
+	if ok && (val.IsFresh() || c.ReadOnly()) {
+		c.avoid(val.cost())
+		c.slide(ck, val)
+		return c.read(val.Value()), nil
+	}
+	if !ok || val.IsExpired() {
+		v, err := c.miss(ctx, key, ck, fetchFunc(fn))
+		return c.read(v), err
+	}
+
+	timer := time.NewTimer(maxWait)
+	defer timer.Stop()
+
+	select {
+	case r := <-c.doAsync(c.refreshContext(ctx), key, ck, fetchFunc(fn)):
+		return c.read(r.Val), r.Err
+	case <-timer.C:
+		c.avoid(val.cost())
+		return c.read(val.Value()), nil
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// SetAsync is like Set, but doesn't block. The returned channel receives the error of
+// the refresh once it landed, or the error of ctx if it is done before.
+func (c *Cache[K, V]) SetAsync(ctx context.Context, key K, fn singleflight.DoFunc[V]) <-chan error {
+	key = c.normalizeKey(key)
+	ck := c.cacheKey(key)
+	res := c.doAsync(c.refreshContext(ctx), key, ck, fetchFunc(fn))
+
+	errc := make(chan error, 1)
+	c.goBackground(func() {
+		select {
+		case r := <-res:
+			errc <- r.Err
+		case <-ctx.Done():
+			errc <- ctx.Err()
+		}
+	})
+	return errc
+}
+
+// Peek returns the cached value of key without fetching it, including stale and
+// expired values, and without updating its recency.
+func (c *Cache[K, V]) Peek(key K) (V, bool) {
+	ck := c.cacheKey(c.normalizeKey(key))
 	c.mu.RLock()
-	val, ok := c.values.Get(key)
+	val, ok := c.values.Peek(ck)
+	val, ok = c.stashed(ck, val, ok)
 	c.mu.RUnlock()
+	return c.read(val.Value()), ok
+}
+
+func (c *Cache[K, V]) do(ctx context.Context, key K, ck cacheKey[K], fn FetchFunc[V]) (V, bool, error) {
+	if c.maxWaiters > 0 {
+		if !c.addWaiter(ck) {
+			return c.rejectWaiter(ck)
+		}
+		defer c.removeWaiter(ck)
+	}
+
+	var fetched bool
+	set := c.set(ctx, key, ck, fn)
+	endWait := c.startWait(ctx, ck)
+	v, err, shared := c.callGroup.Do(ck, func() (V, error) {
+		fetched = true
+		return set()
+	})
+	// the fetch we waited for was canceled by the context of its caller, e.g. a background
+	// refresh out of its budget, fetch again with ours
+	for !fetched && ctx.Err() == nil && isContextError(err) {
+		v, err, shared = c.callGroup.Do(ck, func() (V, error) {
+			fetched = true
+			return set()
+		})
+	}
+	endWait(err)
+	if !fetched && err == nil {
+		c.avoidFetch(ck)
+	}
+	return v, shared, err
+}
+
+// doAsync runs do in a background goroutine owned by the cache, see goRefresh.
+func (c *Cache[K, V]) doAsync(ctx context.Context, key K, ck cacheKey[K], fn FetchFunc[V]) <-chan singleflight.Result[V] {
+	res := make(chan singleflight.Result[V], 1)
+	queued := c.goRefresh(func() {
+		v, shared, err := c.refresh(ctx, key, ck, fn)
+		if err != nil {
+			c.retryLater(key, ck, fn, 0)
+		}
+		res <- singleflight.Result[V]{Val: v, Err: err, Shared: shared}
+	})
+	if !queued {
+		res <- singleflight.Result[V]{Err: c.named(ErrRefreshQueueFull)}
+	}
+	return res
+}
+
+func (c *Cache[K, V]) get(ctx context.Context, key K, freshOnly bool, fn FetchFunc[V]) (V, error) {
+	ck := c.cacheKey(key)
+	if c.warmKeys > 0 {
+		c.hot.hit(key, ck)
+	}
+	if c.Disabled() {
+		return c.bypass(ctx, key, ck, fn)
+	}
+	if c.shadow {
+		return c.getShadow(ctx, key, ck, freshOnly, fn)
+	}
+	val, ok := c.lookup(ck)
+
+	// read-only - serve whatever is cached, even expired values
+	if ok && c.ReadOnly() {
+		c.avoid(val.cost())
+		return val.Value(), nil
+	}
+
+	// value exists, but is outdated according to its version - sync update
+	if ok && !c.verify(ctx, key, val) {
+		c.record(key, outcomeStale)
+		return c.miss(ctx, key, ck, fn)
+	}
 
 	// value exists and is fresh - just return
 	if ok && val.IsFresh() {
+		c.record(key, outcomeHit)
+		c.avoid(val.cost())
+		c.slide(ck, val)
+		return val.Value(), nil
+	}
+
+	// low priority - serve whatever is cached while updating in the background, but
+	// never wait for the origin
+	if LowPriority(ctx) {
+		if !ok {
+			c.record(key, outcomeMiss)
+			var zero V
+			return zero, c.named(ErrLowPriorityMiss)
+		}
+		c.record(key, outcomeStale)
+		c.avoid(val.cost())
+		c.doAsync(c.refreshContext(ctx), key, ck, fn)
 		return val.Value(), nil
 	}
 
@@ -64,65 +393,358 @@ func (c *Cache[K, V]) get(ctx context.Context, key K, freshOnly bool, fn singlef
 	if ok && !freshOnly && !val.IsExpired() {
 		// TODO: technically could be a stampede of goroutines here if the value is expired
 		// and we're OK with serving it stale
-		go c.Set(ctx, key, fn)
+		c.record(key, outcomeStale)
+		c.avoid(val.cost())
+		if !c.staggered(key, ck, val) {
+			c.doAsync(c.refreshContext(ctx), key, ck, fn)
+		}
+		return val.Value(), nil
+	}
+
+	// value is cached, but the origin is unlikely to answer before the caller gives up -
+	// serve what we have while updating in the background
+	if ok && c.doomed(ctx) {
+		c.record(key, outcomeStale)
+		c.avoid(val.cost())
+		c.doAsync(c.refreshContext(ctx), key, ck, fn)
 		return val.Value(), nil
 	}
 
 	// value doesn't exist or is expired, or is stale and we need it fresh (freshOnly:true) - sync update
-	v, _, err := c.Set(ctx, key, fn)
-	return v, err
+	switch {
+	case !ok:
+		c.record(key, outcomeMiss)
+	case val.IsExpired():
+		c.record(key, outcomeExpired)
+	default:
+		c.record(key, outcomeStale)
+	}
+	return c.miss(ctx, key, ck, fn)
 }
 
-func (c *Cache[K, V]) set(key K, fn singleflight.DoFunc[V]) singleflight.DoFunc[V] {
+func (c *Cache[K, V]) lookup(ck cacheKey[K]) (value[K, V], bool) {
+	c.mu.RLock()
+	val, ok := c.values.Get(ck)
+	val, ok = c.stashed(ck, val, ok)
+	if _, pinned := c.pinned[ck]; pinned && ok {
+		val.expiry = never
+	}
+	c.mu.RUnlock()
+	if ok && c.maxIdle > 0 {
+		atomic.StoreInt64(&val.ext.access, time.Now().UnixNano())
+	}
+	return val, ok
+}
+
+func (c *Cache[K, V]) set(ctx context.Context, key K, ck cacheKey[K], fn FetchFunc[V]) singleflight.DoFunc[V] {
 	return singleflight.DoFunc[V](func() (V, error) {
-		val, err := fn()
+		readOnly := c.ReadOnly()
+		if readOnly {
+			if val, ok := c.lookup(ck); ok {
+				return val.Value(), nil
+			}
+		}
+
+		if v, err, ok := c.failFast(ck); ok {
+			return c.fallback(ctx, key, ck, v, err)
+		}
+
+		ctx, endFetch := c.startFetch(ctx, ck)
+		ctx, meta := withMeta(ctx)
+		c.withPrevious(meta, ck)
+		version := c.version(ctx, key)
+		start := time.Now()
+		val, bestBefore, expiry, err := c.load(ctx, key, ck, fn)
+		c.latency.add(time.Since(start), err != nil && err != Unchanged)
+		if err == Unchanged {
+			endFetch(nil)
+			val, err = c.unchanged(ctx, ck)
+			return c.collapse(ctx, ck, val, err)
+		}
+		endFetch(err)
 		if err != nil {
-			return val, err
+			val, err = c.failed(ck, val, err)
+			val, err = c.fallback(ctx, key, ck, val, err)
+			return c.collapse(ctx, ck, val, err)
+		}
+		if readOnly || meta.NoStore {
+			return c.collapse(ctx, ck, val, nil)
+		}
+
+		if bestBefore.IsZero() {
+			bestBefore, expiry = c.expiry(ctx, val)
 		}
+		entry := c.entry(key, ck, val, bestBefore, expiry)
+		entry.setVersion(version)
+		c.apply(&entry, meta)
+		entry.source = meta.source
+		entry.setCost(costOf(val, time.Since(start)))
+		c.spend(entry.cost())
 
 		c.mu.Lock()
-		c.values.Add(key, value[V]{
-			v:          val,
-			expiry:     time.Now().Add(c.ttl),
-			bestBefore: time.Now().Add(c.freshFor),
-		})
+		if published, ok := c.publishedSince(ck, start); ok {
+			c.mu.Unlock()
+			return published, nil
+		}
+		c.add(ck, entry)
+		c.notify(ck, val)
 		c.mu.Unlock()
+		c.saveLastKnownGood(ctx, key, ck, val)
 
-		return val, nil
+		return c.collapse(ctx, ck, val, nil)
 	})
 }
 
-type value[V any] struct {
+func (c *Cache[K, V]) entry(key K, ck cacheKey[K], val V, bestBefore, expiry time.Time) value[K, V] {
+	entry := value[K, V]{
+		v:          val,
+		expiry:     expiry.UnixNano(),
+		bestBefore: bestBefore.UnixNano(),
+		created:    time.Now().UnixNano(),
+		size:       sizeOf(val),
+	}
+	entry.setTags(c.internTags(tagsOf(val)))
+	if c.maxIdle > 0 {
+		entry.extend().access = entry.created
+	}
+	if ck.digest != "" && (c.retainKeys || c.keyHash == KeyHashNone) {
+		entry.key = internKey(c.strings, key)
+		entry.hasKey = true
+	}
+	return entry
+}
+
+// TTLer is implemented by values that carry their own time to live, like DNS records.
+// A value with a TTL is fresh for that TTL, and is served stale for the same grace
+// period as other values of the cache (ttl - freshFor) afterwards.
+type TTLer interface {
+	TTL() time.Duration
+}
+
+// lifetime returns how long val stays fresh, and how long it is kept at all. A ttl
+// shorter than freshFor leaves no grace period. Values returned by a ValueFunc fetched
+// with ctx override both.
+func (c *Cache[K, V]) lifetime(ctx context.Context, val V) (freshFor, ttl time.Duration) {
+	freshFor, ttl = c.valueLifetime(val)
+	if m := metaFrom(ctx); m != nil {
+		if m.FreshFor > 0 {
+			freshFor, ttl = m.FreshFor, m.FreshFor+ttl-freshFor
+		}
+		if m.TTL > 0 {
+			ttl = m.TTL
+		}
+		if ttl < freshFor {
+			ttl = freshFor
+		}
+	}
+	return freshFor, ttl
+}
+
+func (c *Cache[K, V]) valueLifetime(val V) (freshFor, ttl time.Duration) {
+	cacheFreshFor, cacheTTL := c.TTL()
+	if cacheTTL < cacheFreshFor {
+		cacheTTL = cacheFreshFor
+	}
+
+	t, ok := any(val).(TTLer)
+	if !ok {
+		return cacheFreshFor, cacheTTL
+	}
+
+	freshFor = t.TTL()
+	if c.minTTL > 0 && freshFor < c.minTTL {
+		freshFor = c.minTTL
+	}
+	if c.maxTTL > 0 && freshFor > c.maxTTL {
+		freshFor = c.maxTTL
+	}
+	return freshFor, freshFor + cacheTTL - cacheFreshFor
+}
+
+// expiry returns when val, fetched now, stops being fresh and expires.
+func (c *Cache[K, V]) expiry(ctx context.Context, val V) (bestBefore, expiry time.Time) {
+	freshFor, ttl := c.lifetime(ctx, val)
+	now := time.Now()
+	return now.Add(freshFor), now.Add(ttl)
+}
+
+// SetTTL changes how long values stay fresh, and how long they are kept at all. Only
+// values set afterwards are affected, which allows to e.g. lengthen caching during an
+// origin incident without a redeploy.
+func (c *Cache[K, V]) SetTTL(freshFor, ttl time.Duration) {
+	c.ttlMu.Lock()
+	c.freshFor, c.ttl = freshFor, ttl
+	c.ttlMu.Unlock()
+}
+
+// SetLifetime is like SetTTL, see Lifetime.
+func (c *Cache[K, V]) SetLifetime(l Lifetime) {
+	c.SetTTL(l.Fresh, l.TTL())
+}
+
+// Lifetime returns the lifetime of values set by NewCacheKV, SetTTL or SetLifetime.
+func (c *Cache[K, V]) Lifetime() Lifetime {
+	freshFor, ttl := c.TTL()
+	l := Lifetime{Fresh: freshFor}
+	if ttl > freshFor {
+		l.Grace = ttl - freshFor
+	}
+	return l
+}
+
+// TTL returns the durations set by NewCacheKV or SetTTL.
+func (c *Cache[K, V]) TTL() (freshFor, ttl time.Duration) {
+	c.ttlMu.RLock()
+	defer c.ttlMu.RUnlock()
+	return c.freshFor, c.ttl
+}
+
+// Lifetime is the lifetime of cached values, in three phases:
+//
+//   - fresh: for Fresh after the fetch, values are served as they are.
+//   - grace: for Grace after that, values are stale. Get serves them while refreshing
+//     them in the background, GetFresh waits for the refresh.
+//   - dead: afterwards values are expired and never served, every get waits for the
+//     refresh.
+//
+// It is the same as the freshFor and ttl of NewCacheKV, with ttl = Fresh + Grace. A ttl
+// shorter than freshFor is treated as freshFor, without grace period.
+type Lifetime struct {
+	Fresh time.Duration
+	Grace time.Duration
+}
+
+// TTL returns how long values are kept at all.
+func (l Lifetime) TTL() time.Duration {
+	return l.Fresh + l.Grace
+}
+
+// value is a cached value, fresh until bestBefore and dead after expiry, see Lifetime.
+//
+// Caches may hold tens of millions of entries, so entries are kept small: times are
+// unix nanoseconds, and the metadata most entries don't have is kept in ext. On 64-bit
+// platforms, an entry takes 56 bytes plus the sizes of K and V, and the lru adds about
+// 80 bytes of bookkeeping per entry, plus two copies of the cache key.
+type value[K comparable, V any] struct {
 	v V
 
-	bestBefore time.Time // cache entry freshness cutoff
-	expiry     time.Time // cache entry time to live cutoff
+	key K // original key, only retained for keys stored by digest, see hasKey
+
+	size    int64
+	latency time.Duration // cost of values not implementing Coster
+
+	bestBefore int64 // cache entry freshness cutoff
+	expiry     int64 // cache entry time to live cutoff
+	created    int64 // when the entry was added, see WithSlidingExpiration
+
+	ext *entryExt // nil for entries without any of its metadata
+
+	hasKey    bool
+	source    Source
+	published bool // see Publish
+}
+
+// entryExt is the metadata of an entry that most entries don't have. It is shared by
+// all copies of the entry, and only modified before the entry is added, except for
+// access.
+type entryExt struct {
+	cost    Cost // of values implementing Coster
+	hasCost bool
+	tags    []string
+	version string // version of the origin when fetched, see WithVersionCheck
+	access  int64  // unix nanoseconds of the last read, see WithMaxIdle
+}
+
+func (v *value[K, V]) IsFresh() bool {
+	return v.bestBefore > time.Now().UnixNano()
 }
 
-func (v *value[V]) IsFresh() bool {
-	return v.bestBefore.After(time.Now())
+func (v *value[K, V]) IsExpired() bool {
+	return v.expiry < time.Now().UnixNano()
 }
 
-func (v *value[V]) IsExpired() bool {
-	return v.expiry.Before(time.Now())
+// extend returns the metadata of v, allocating it if needed. Only for entries not
+// added yet.
+func (v *value[K, V]) extend() *entryExt {
+	if v.ext == nil {
+		v.ext = &entryExt{}
+	}
+	return v.ext
+}
+
+func (v *value[K, V]) cost() Cost {
+	if v.ext != nil && v.ext.hasCost {
+		return v.ext.cost
+	}
+	return Cost{Latency: v.latency}
+}
+
+func (v *value[K, V]) setCost(cost Cost) {
+	if cost.Bytes == 0 && cost.Units == 0 && (v.ext == nil || !v.ext.hasCost) {
+		v.latency = cost.Latency
+		return
+	}
+	e := v.extend()
+	e.cost, e.hasCost = cost, true
+}
+
+func (v *value[K, V]) tags() []string {
+	if v.ext == nil {
+		return nil
+	}
+	return v.ext.tags
 }
 
-func (v *value[V]) Value() V {
+func (v *value[K, V]) setTags(tags []string) {
+	if tags != nil || v.ext != nil {
+		v.extend().tags = tags
+	}
+}
+
+func (v *value[K, V]) version() string {
+	if v.ext == nil {
+		return ""
+	}
+	return v.ext.version
+}
+
+func (v *value[K, V]) setVersion(version string) {
+	if version != "" || v.ext != nil {
+		v.extend().version = version
+	}
+}
+
+func (v *value[K, V]) Value() V {
 	return v.v
 }
 
+// BytesToHash returns the xxhash of b. Every part but the last is prefixed with its
+// length, so moving bytes from one part to the next always yields a different hash.
 func BytesToHash(b ...[]byte) uint64 {
 	d := xxhash.New()
-	for _, v := range b {
+	for i, v := range b {
+		if i < len(b)-1 {
+			writeLen(d, len(v))
+		}
 		d.Write(v)
 	}
 	return d.Sum64()
 }
 
+// StringToHash is like BytesToHash, but for strings.
 func StringToHash(s ...string) uint64 {
 	d := xxhash.New()
-	for _, v := range s {
+	for i, v := range s {
+		if i < len(s)-1 {
+			writeLen(d, len(v))
+		}
 		d.WriteString(v)
 	}
 	return d.Sum64()
 }
+
+func writeLen(d *xxhash.Digest, n int) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(n))
+	d.Write(buf[:])
+}
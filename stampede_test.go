@@ -2,11 +2,14 @@ package stampede_test
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -122,12 +125,14 @@ func TestHandler(t *testing.T) {
 			defer wg.Done()
 			resp, err := http.Get(ts.URL)
 			if err != nil {
-				t.Fatal(err)
+				t.Error(err)
+				return
 			}
 
 			body, err := io.ReadAll(resp.Body)
 			if err != nil {
-				t.Fatal(err)
+				t.Error(err)
+				return
 			}
 			defer resp.Body.Close()
 
@@ -217,12 +222,14 @@ func TestBypassCORSHeaders(t *testing.T) {
 
 				resp, err := http.DefaultClient.Do(req)
 				if err != nil {
-					t.Fatal(err)
+					t.Error(err)
+					return
 				}
 
 				body, err := io.ReadAll(resp.Body)
 				if err != nil {
-					t.Fatal(err)
+					t.Error(err)
+					return
 				}
 				defer resp.Body.Close()
 
@@ -289,3 +296,449 @@ func TestPanic(t *testing.T) {
 		t.Log(resp.StatusCode)
 	}
 }
+
+func TestKeyHashing(t *testing.T) {
+	ctx := context.Background()
+	longKey := strings.Repeat("SELECT * FROM t WHERE id = 1 OR ", 1024)
+
+	for _, h := range []stampede.KeyHash{stampede.KeyHashXXHash, stampede.KeyHashSHA256} {
+		var calls int
+		fetch := func() (string, error) {
+			calls++
+			return "v", nil
+		}
+
+		cache := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithKeyHashing(h))
+		for i := 0; i < 3; i++ {
+			val, err := cache.Get(ctx, longKey, fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, "v", val)
+		}
+		assert.Equal(t, 1, calls)
+		assert.Empty(t, cache.Keys())
+
+		cache = stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithKeyHashing(h), stampede.WithRetainedKeys())
+		_, err := cache.Get(ctx, longKey, fetch)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{longKey}, cache.Keys())
+	}
+}
+
+func TestKeyHashingTypes(t *testing.T) {
+	ctx := context.Background()
+	for _, h := range []stampede.KeyHash{stampede.KeyHashXXHash, stampede.KeyHashSHA256} {
+		cache := stampede.NewCache(8, time.Minute, time.Minute, stampede.WithKeyHashing(h))
+		cache.Get(ctx, "1", func() (any, error) { return "string", nil })
+		v, err := cache.Get(ctx, 1, func() (any, error) { return "int", nil })
+		assert.NoError(t, err)
+		assert.Equal(t, "int", v)
+	}
+}
+
+type userQuery struct {
+	Tenant string
+	IDs    []int
+}
+
+func (q userQuery) CacheKey() string {
+	return fmt.Sprintf("%q%v", q.Tenant, q.IDs)
+}
+
+func TestKeyer(t *testing.T) {
+	ctx := context.Background()
+
+	var calls int
+	fetch := func() (any, error) {
+		calls++
+		return "v", nil
+	}
+
+	// userQuery isn't comparable, so it can only be used as key through Keyer
+	cache := stampede.NewCache(8, time.Minute, time.Minute)
+	for i := 0; i < 3; i++ {
+		_, err := cache.Get(ctx, userQuery{Tenant: "a", IDs: []int{1, 2}}, fetch)
+		assert.NoError(t, err)
+	}
+	_, err := cache.Get(ctx, userQuery{Tenant: "a", IDs: []int{1, 3}}, fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Len(t, cache.Keys(), 2)
+}
+
+func TestRangeByRecency(t *testing.T) {
+	ctx := context.Background()
+	cache := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute)
+	for i, key := range []string{"a", "b", "c"} {
+		i := i
+		cache.Get(ctx, key, func() (int, error) { return i, nil })
+	}
+	cache.Get(ctx, "a", nil)
+
+	var keys []string
+	cache.RangeByRecency(func(key string, v int) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equal(t, []string{"a", "c", "b"}, keys)
+
+	keys = nil
+	cache.RangeByRecency(func(key string, v int) bool {
+		keys = append(keys, key)
+		return len(keys) < 2
+	})
+	assert.Equal(t, []string{"a", "c"}, keys)
+}
+
+func TestPeek(t *testing.T) {
+	cache := stampede.NewCacheKV[string, string](8, 0, time.Minute)
+
+	_, ok := cache.Peek("a")
+	assert.False(t, ok)
+
+	_, _, err := cache.Set(context.Background(), "a", func() (string, error) { return "v", nil })
+	assert.NoError(t, err)
+
+	// stale values are returned too
+	val, ok := cache.Peek("a")
+	assert.True(t, ok)
+	assert.Equal(t, "v", val)
+}
+
+type record struct {
+	addr string
+	ttl  time.Duration
+}
+
+func (r record) TTL() time.Duration {
+	return r.ttl
+}
+
+func TestTTLer(t *testing.T) {
+	ctx := context.Background()
+	cache := stampede.NewCacheKV[string, record](8, time.Minute, time.Minute, stampede.WithTTLBounds(50*time.Millisecond, time.Hour))
+
+	var calls int
+	lookup := func(ttl time.Duration) func() (record, error) {
+		return func() (record, error) {
+			calls++
+			return record{addr: "10.0.0.1", ttl: ttl}, nil
+		}
+	}
+
+	// a zero ttl is clamped to the minimum
+	_, err := cache.GetFresh(ctx, "a", lookup(0))
+	assert.NoError(t, err)
+	_, err = cache.GetFresh(ctx, "a", lookup(0))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = cache.GetFresh(ctx, "a", lookup(0))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestSetAsync(t *testing.T) {
+	ctx := context.Background()
+	cache := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute)
+
+	release := make(chan struct{})
+	errc := cache.SetAsync(ctx, "a", func() (string, error) {
+		<-release
+		return "v", nil
+	})
+
+	_, ok := cache.Peek("a")
+	assert.False(t, ok)
+
+	close(release)
+	assert.NoError(t, <-errc)
+	val, ok := cache.Peek("a")
+	assert.True(t, ok)
+	assert.Equal(t, "v", val)
+
+	errc = cache.SetAsync(ctx, "b", func() (string, error) {
+		return "", io.ErrUnexpectedEOF
+	})
+	assert.Equal(t, io.ErrUnexpectedEOF, <-errc)
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	errc = cache.SetAsync(cctx, "c", func() (string, error) {
+		time.Sleep(time.Second)
+		return "v", nil
+	})
+	assert.Equal(t, context.Canceled, <-errc)
+}
+
+func TestGetFreshWithin(t *testing.T) {
+	ctx := context.Background()
+	cache := stampede.NewCacheKV[string, string](8, 0, time.Minute)
+
+	slow := func(v string) func() (string, error) {
+		return func() (string, error) {
+			time.Sleep(100 * time.Millisecond)
+			return v, nil
+		}
+	}
+
+	// missing values are waited for
+	val, err := cache.GetFreshWithin(ctx, "a", time.Millisecond, slow("v1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", val)
+
+	// stale values are served when the refresh takes too long
+	val, err = cache.GetFreshWithin(ctx, "a", 10*time.Millisecond, slow("v2"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", val)
+
+	time.Sleep(150 * time.Millisecond)
+	val, err = cache.GetFreshWithin(ctx, "a", time.Second, slow("v3"))
+	assert.NoError(t, err)
+	assert.Equal(t, "v3", val)
+}
+
+func TestSetTTL(t *testing.T) {
+	ctx := context.Background()
+	cache := stampede.NewCacheKV[string, int](8, 0, time.Minute)
+
+	var calls int
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	_, err := cache.GetFresh(ctx, "a", fetch)
+	assert.NoError(t, err)
+	_, err = cache.GetFresh(ctx, "a", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	cache.SetTTL(time.Hour, 2*time.Hour)
+	freshFor, ttl := cache.TTL()
+	assert.Equal(t, time.Hour, freshFor)
+	assert.Equal(t, 2*time.Hour, ttl)
+
+	_, err = cache.GetFresh(ctx, "a", fetch)
+	assert.NoError(t, err)
+	val, err := cache.GetFresh(ctx, "a", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, val)
+	assert.Equal(t, 3, calls)
+}
+
+func TestHandlerStreaming(t *testing.T) {
+	calls := 0
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/events" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: 1\n\n"))
+			w.(http.Flusher).Flush()
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	h := stampede.Handler(16, time.Minute)(app)
+
+	serve := func(path string, header http.Header) string {
+		r := httptest.NewRequest("GET", path, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Body.String()
+	}
+
+	// upgrades and event streams are passed through
+	for i := 0; i < 2; i++ {
+		serve("/ws", http.Header{"Upgrade": {"websocket"}, "Connection": {"Upgrade"}})
+		serve("/sse", http.Header{"Accept": {"text/event-stream"}})
+	}
+	assert.Equal(t, 4, calls)
+
+	// event streams not asked for are detected by their content type
+	assert.Equal(t, "data: 1\n\n", serve("/events", nil))
+	assert.Equal(t, "data: 1\n\n", serve("/events", nil))
+	assert.Equal(t, 6, calls)
+}
+
+func TestHandlerStreamingWaiters(t *testing.T) {
+	release := make(chan struct{})
+	var calls int32
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			time.Sleep(30 * time.Millisecond)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %d\n\n", n)
+		w.(http.Flusher).Flush()
+		if n == 1 {
+			<-release
+		}
+	})
+	h := stampede.Handler(16, time.Minute)(app)
+
+	serve := func(done chan<- string) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+		done <- w.Body.String()
+	}
+	first, second := make(chan string, 1), make(chan string, 1)
+	go serve(first)
+	time.Sleep(10 * time.Millisecond)
+	go serve(second)
+
+	// the request waiting on the stream is released as soon as it is detected
+	select {
+	case body := <-second:
+		assert.Equal(t, "data: 2\n\n", body)
+	case <-time.After(time.Second):
+		t.Fatal("waiter blocked by the stream")
+	}
+	close(release)
+	assert.Equal(t, "data: 1\n\n", <-first)
+}
+
+func TestHandlerRange(t *testing.T) {
+	calls := 0
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.Empty(t, r.Header.Get("Range"))
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("0123456789"))
+	})
+	h := stampede.Handler(16, time.Minute)(app)
+
+	get := func(rng string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/file", nil)
+		if rng != "" {
+			r.Header.Set("Range", rng)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("bytes=2-4")
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "234", w.Body.String())
+	assert.Equal(t, "bytes 2-4/10", w.Header().Get("Content-Range"))
+
+	w = get("bytes=-3")
+	assert.Equal(t, http.StatusPartialContent, w.Code)
+	assert.Equal(t, "789", w.Body.String())
+
+	w = get("")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0123456789", w.Body.String())
+	assert.Equal(t, 1, calls)
+}
+
+func TestHandlerAge(t *testing.T) {
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	h := stampede.Handler(16, time.Hour)(app)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Empty(t, w.Header().Get("Age"))
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, "0", w.Header().Get("Age"))
+	assert.Empty(t, w.Header().Get("Warning"))
+
+	expires, err := http.ParseTime(w.Header().Get("Expires"))
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expires, 2*time.Second)
+}
+
+func TestHandlerStale(t *testing.T) {
+	var calls int32
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Write([]byte(strconv.Itoa(int(n))))
+	})
+	metrics := stampede.NewRouteMetrics(func(r *http.Request) string { return r.URL.Path })
+	h := stampede.NewHandler(16, 50*time.Millisecond, stampede.WithRouteMetrics(metrics), stampede.WithServeStale())(app)
+
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		return w
+	}
+
+	assert.Equal(t, "1", get().Body.String())
+	time.Sleep(60 * time.Millisecond)
+
+	// stale responses are served right away, and refreshed in the background
+	w := get()
+	assert.Equal(t, "1", w.Body.String())
+	assert.Equal(t, `110 - "Response is Stale"`, w.Header().Get("Warning"))
+	assert.Eventually(t, func() bool {
+		return get().Body.String() == "2"
+	}, time.Second, 5*time.Millisecond)
+	assert.Empty(t, get().Header().Get("Warning"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, int64(1), metrics.Routes()["/"].Stale)
+}
+
+func TestHandlerFreshOnly(t *testing.T) {
+	var calls int32
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Write([]byte(strconv.Itoa(int(n))))
+	})
+
+	// without WithServeStale, stale responses are fetched again
+	for _, h := range []http.Handler{
+		stampede.Handler(16, 50*time.Millisecond)(app),
+		stampede.HandlerWithKeyAndCb(16, 50*time.Millisecond, stampede.DefaultKeyFunc, func(bool, http.ResponseWriter, *http.Request) error { return nil })(app),
+	} {
+		atomic.StoreInt32(&calls, 0)
+		get := func() string {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+			return w.Body.String()
+		}
+		assert.Equal(t, "1", get())
+		assert.Equal(t, "1", get())
+		time.Sleep(60 * time.Millisecond)
+		assert.Equal(t, "2", get())
+	}
+}
+
+func TestLifetime(t *testing.T) {
+	ctx := context.Background()
+	cache := stampede.NewCacheKV[string, int](8, time.Hour, time.Hour, stampede.WithLifetime(stampede.Lifetime{
+		Fresh: 10 * time.Millisecond,
+		Grace: time.Hour,
+	}))
+	assert.Equal(t, stampede.Lifetime{Fresh: 10 * time.Millisecond, Grace: time.Hour}, cache.Lifetime())
+
+	var calls int
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+	cache.Get(ctx, "a", fetch)
+	time.Sleep(20 * time.Millisecond)
+
+	// in the grace period the stale value is served
+	val, err := cache.Get(ctx, "a", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+
+	// a ttl shorter than freshFor leaves no grace period, values die once they are stale
+	cache.SetTTL(10*time.Millisecond, time.Millisecond)
+	assert.Equal(t, stampede.Lifetime{Fresh: 10 * time.Millisecond}, cache.Lifetime())
+	cache.Set(ctx, "b", fetch)
+	time.Sleep(20 * time.Millisecond)
+	val, err = cache.Get(ctx, "b", func() (int, error) { return -1, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, -1, val)
+}
@@ -0,0 +1,34 @@
+// Package stampedetest provides test helpers for code using stampede caches.
+package stampedetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+)
+
+// Cache is the part of a stampede.Cache used by the helpers.
+type Cache interface {
+	Stats() stampede.Stats
+	Close() error
+}
+
+// CloseAndVerify closes c and fails t if any background goroutine of c is still alive
+// shortly after. Use it at the end of tests to catch leaked refreshes.
+func CloseAndVerify(t testing.TB, c Cache) {
+	t.Helper()
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("stampedetest: close: %v", err)
+	}
+
+	// goroutines started while closing are not waited for by Close, give them a moment
+	deadline := time.Now().Add(time.Second)
+	for c.Stats().Background > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := c.Stats().Background; n > 0 {
+		t.Fatalf("stampedetest: %d background goroutines leaked after close", n)
+	}
+}
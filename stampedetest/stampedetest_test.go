@@ -0,0 +1,32 @@
+package stampedetest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/dadav/stampede/stampedetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloseAndVerify(t *testing.T) {
+	cache := stampede.NewCacheKV[string, string](8, 0, time.Minute)
+	ctx := context.Background()
+
+	_, err := cache.Get(ctx, "a", func() (string, error) { return "v", nil })
+	assert.NoError(t, err)
+
+	// serves the stale value and refreshes in the background
+	_, err = cache.Get(ctx, "a", func() (string, error) {
+		time.Sleep(50 * time.Millisecond)
+		return "v", nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cache.Stats().Background)
+
+	cache.Schedule("b", time.Hour, func() (string, error) { return "v", nil })
+
+	stampedetest.CloseAndVerify(t, cache)
+	assert.Equal(t, 0, cache.Stats().Background)
+}
@@ -0,0 +1,135 @@
+package stampede
+
+import "sync/atomic"
+
+// Stats is a snapshot of the state of a cache.
+type Stats struct {
+	// Name is the name of the cache, see WithName. Add keeps it only when both names are
+	// the same.
+	Name string
+
+	// Entries is the number of cached entries, Size their total size, see Sizer.
+	Entries int
+	Size    int64
+
+	// Background is the number of live background goroutines, e.g. stale-while-revalidate
+	// refreshes and scheduled refreshes. It is 0 after Close.
+	Background int
+
+	// ShadowHits and ShadowMisses count the gets that would have been hits and misses,
+	// see WithShadowMode.
+	ShadowHits   int64
+	ShadowMisses int64
+
+	// Classes breaks down the gets by key class, if the cache classifies its keys, see
+	// WithKeyClass.
+	Classes map[string]ClassStats
+
+	// Spent is the cost of all fetches, Avoided the cost saved by serving cached values
+	// and coalescing fetches, see Coster.
+	Spent   Cost
+	Avoided Cost
+
+	// DroppedEvents is the number of events not delivered to slow subscribers, see Events.
+	DroppedEvents int64
+
+	// Retrying is the number of keys waiting for another refresh after their background
+	// refresh failed, see WithRefreshRetry.
+	Retrying int
+
+	// RefreshTimeouts is the number of background refreshes that ran out of their budget,
+	// see WithRefreshBudget.
+	RefreshTimeouts int64
+
+	// RefreshQueued is the number of background refreshes waiting for a refresh worker,
+	// RefreshDropped the number of refreshes dropped because the queue was full, see
+	// WithRefreshWorkers.
+	RefreshQueued  int
+	RefreshDropped int64
+}
+
+// Add returns the sum of s and o, to aggregate the stats of several caches.
+func (s Stats) Add(o Stats) Stats {
+	if s.Name != o.Name {
+		s.Name = ""
+	}
+	s.Entries += o.Entries
+	s.Size += o.Size
+	s.Background += o.Background
+	s.ShadowHits += o.ShadowHits
+	s.ShadowMisses += o.ShadowMisses
+	s.Spent = s.Spent.Add(o.Spent)
+	s.Avoided = s.Avoided.Add(o.Avoided)
+	s.DroppedEvents += o.DroppedEvents
+	s.Retrying += o.Retrying
+	s.RefreshTimeouts += o.RefreshTimeouts
+	s.RefreshQueued += o.RefreshQueued
+	s.RefreshDropped += o.RefreshDropped
+	if len(o.Classes) > 0 {
+		classes := make(map[string]ClassStats, len(s.Classes)+len(o.Classes))
+		for class, cs := range s.Classes {
+			classes[class] = cs
+		}
+		for class, cs := range o.Classes {
+			classes[class] = classes[class].Add(cs)
+		}
+		s.Classes = classes
+	}
+	return s
+}
+
+// Stats returns a snapshot of the state of the cache.
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	stats := Stats{
+		Name: c.name,
+
+		Entries:    c.values.Len(),
+		Size:       c.size,
+		Background: int(atomic.LoadInt64(&c.background)),
+
+		ShadowHits:   atomic.LoadInt64(&c.shadowHits),
+		ShadowMisses: atomic.LoadInt64(&c.shadowMisses),
+
+		Classes: c.classStats(),
+
+		DroppedEvents: c.dropped,
+
+		Retrying:        c.retrying(),
+		RefreshTimeouts: atomic.LoadInt64(&c.refreshTimeouts),
+	}
+	stats.Spent, stats.Avoided = c.costs()
+	stats.RefreshQueued, stats.RefreshDropped = c.pool.queued()
+	return stats
+}
+
+// Len returns the number of cached entries.
+func (c *Cache[K, V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.values.Len()
+}
+
+// Purge removes all entries, all cached errors and all keys known to be missing from the
+// cache.
+func (c *Cache[K, V]) Purge() {
+	c.mu.Lock()
+	c.values.Purge()
+	for ck := range c.pinned {
+		c.unstash(ck)
+	}
+	c.mu.Unlock()
+
+	c.negativesMu.Lock()
+	c.negatives = nil
+	c.negativesMu.Unlock()
+
+	c.collapsedMu.Lock()
+	c.collapsed = nil
+	c.collapsedMu.Unlock()
+
+	if c.missing != nil {
+		c.missing.reset()
+	}
+}
@@ -0,0 +1,364 @@
+package stampede
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Store is an external, shared cache tier holding encoded values, e.g. Redis. Get
+// returns ErrNotFound for missing keys.
+//
+// On a miss of the in-memory cache the store is consulted before the origin, and
+// values fetched from the origin are written through to the store. Who owns the
+// freshness of store entries depends on WithStoreEnvelope: by default the store TTL
+// is authoritative, entries are written with a TTL of freshFor and a store hit is
+// always fresh. With envelopes, entries are kept for the full ttl and carry their
+// freshness with them, see Envelope.
+//
+// The store is read within the singleflight call of the key, so a value is read and
+// decoded once for all concurrent local callers, which then share the decoded value.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Codec encodes values for a Store. Codecs may implement ID() uint8 to be recorded in
+// envelopes, so that entries written with another codec are not decoded; ids up to 127
+// are reserved for the codecs of this package.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes values with encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (JSONCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (JSONCodec) ID() uint8                          { return 1 }
+
+// GobCodec encodes values with encoding/gob.
+type GobCodec struct{}
+
+func (GobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (GobCodec) ID() uint8 { return 2 }
+
+// Cloner is implemented by values that can copy themselves, see WithCopyOnRead.
+type Cloner[V any] interface {
+	Clone() V
+}
+
+func (c *Cache[K, V]) read(v V) V {
+	if !c.copyOnRead {
+		return v
+	}
+	if cl, ok := any(v).(Cloner[V]); ok {
+		return cl.Clone()
+	}
+	return v
+}
+
+// storeKey returns the key of an entry in the store.
+func (c *Cache[K, V]) storeKey(key K, ck cacheKey[K]) string {
+	var skey string
+	if c.keyHash != KeyHashNone {
+		skey = hex.EncodeToString([]byte(ck.digest))
+	} else if c.anyKeys {
+		skey = typedKeyString(key)
+	} else {
+		skey = keyString(key)
+	}
+	if c.schema != 0 {
+		skey = "v" + strconv.FormatUint(uint64(c.schema), 10) + ":" + skey
+	}
+	return skey
+}
+
+// load returns the value of key from the store, or fetches it from the origin and
+// writes it through to the store. Zero times mean the value is fresh as of now; store
+// hits with an envelope return the freshness recorded in the envelope.
+func (c *Cache[K, V]) load(ctx context.Context, key K, ck cacheKey[K], fn FetchFunc[V]) (v V, bestBefore, expiry time.Time, err error) {
+	if c.store == nil {
+		v, err = c.origin(ctx, key, fn)
+		return v, time.Time{}, time.Time{}, err
+	}
+
+	skey := c.storeKey(key, ck)
+	if wroteInSession(ctx) {
+		return c.fetchThrough(ctx, key, ck, skey, fn)
+	}
+	if c.storeEnvelope {
+		return c.loadEnvelope(ctx, key, ck, skey, fn)
+	}
+
+	if v, ok := c.stored(ctx, skey); ok {
+		return v, time.Time{}, time.Time{}, nil
+	}
+	if unlock := c.lock(ctx, skey); unlock != nil {
+		defer unlock()
+		// another instance may have fetched the value while we waited for the lock
+		if v, ok := c.stored(ctx, skey); ok {
+			return v, time.Time{}, time.Time{}, nil
+		}
+	}
+	if release, held := c.lease(ctx, skey); held {
+		// another instance is fetching the value, wait for it to land in the store
+		var sv V
+		if c.awaitLease(ctx, skey, func() (ok bool) { sv, ok = c.stored(ctx, skey); return ok }) {
+			return sv, time.Time{}, time.Time{}, nil
+		}
+	} else if release != nil {
+		defer release()
+	}
+	return c.fetchThrough(ctx, key, ck, skey, fn)
+}
+
+// fetchThrough fetches the value of key from the origin and writes it through to the
+// store.
+func (c *Cache[K, V]) fetchThrough(ctx context.Context, key K, ck cacheKey[K], skey string, fn FetchFunc[V]) (v V, bestBefore, expiry time.Time, err error) {
+	start := time.Now()
+	v, err = c.origin(ctx, key, fn)
+	if err != nil || metaFrom(ctx).noStore() {
+		return v, time.Time{}, time.Time{}, err
+	}
+	bestBefore, expiry = c.expiry(ctx, v)
+	if !c.publishedInStore(ck, start) {
+		c.save(ctx, skey, v, bestBefore, expiry)
+	}
+	return v, bestBefore, expiry, nil
+}
+
+// loadEnvelope is load for stores with envelopes. Fresh store entries are used as they
+// are, stale ones are refreshed from the origin, and served if the origin fails.
+func (c *Cache[K, V]) loadEnvelope(ctx context.Context, key K, ck cacheKey[K], skey string, fn FetchFunc[V]) (v V, bestBefore, expiry time.Time, err error) {
+	sv, stale, fresh := c.storedEnvelope(ctx, skey)
+	if fresh {
+		return sv, stale.BestBefore, stale.Expiry, nil
+	}
+	if unlock := c.lock(ctx, skey); unlock != nil {
+		defer unlock()
+		// another instance may have refreshed the value while we waited for the lock
+		if v, env, fresh := c.storedEnvelope(ctx, skey); fresh {
+			return v, env.BestBefore, env.Expiry, nil
+		} else if env != nil {
+			sv, stale = v, env
+		}
+	}
+	if release, held := c.lease(ctx, skey); held {
+		// another instance is refreshing the value, serve it stale meanwhile or wait for
+		// it to land in the store
+		if stale != nil {
+			return sv, stale.BestBefore, stale.Expiry, nil
+		}
+		var env *Envelope
+		var fresh bool
+		if c.awaitLease(ctx, skey, func() bool { sv, env, fresh = c.storedEnvelope(ctx, skey); return fresh }) {
+			return sv, env.BestBefore, env.Expiry, nil
+		}
+	} else if release != nil {
+		defer release()
+	}
+
+	start := time.Now()
+	v, err = c.origin(ctx, key, fn)
+	if err == Unchanged && stale != nil {
+		v, err = sv, nil
+	}
+	if err != nil {
+		if stale != nil {
+			return sv, stale.BestBefore, stale.Expiry, nil
+		}
+		return v, time.Time{}, time.Time{}, err
+	}
+
+	bestBefore, expiry = c.expiry(ctx, v)
+	if !metaFrom(ctx).noStore() && !c.publishedInStore(ck, start) {
+		c.save(ctx, skey, v, bestBefore, expiry)
+	}
+	return v, bestBefore, expiry, nil
+}
+
+// save writes v, fresh until bestBefore and expiring at expiry, to the store. Without
+// envelopes, it is kept in the store while fresh.
+func (c *Cache[K, V]) save(ctx context.Context, skey string, v V, bestBefore, expiry time.Time) {
+	if !c.storeEnvelope {
+		if ttl := time.Until(bestBefore); ttl > 0 {
+			if b, err := c.codec.Marshal(v); err == nil {
+				c.store.Set(ctx, skey, b, ttl)
+			}
+		}
+		return
+	}
+	if ttl := time.Until(expiry); ttl > 0 {
+		if payload, err := c.codec.Marshal(v); err == nil {
+			env := Envelope{BestBefore: bestBefore, Expiry: expiry, CodecID: codecID(c.codec), Payload: payload}
+			c.store.Set(ctx, skey, EncodeEnvelope(env), ttl)
+		}
+	}
+}
+
+// stored returns the value of skey in the store.
+func (c *Cache[K, V]) stored(ctx context.Context, skey string) (V, bool) {
+	var v V
+	b, err := c.store.Get(ctx, skey)
+	if err != nil {
+		return v, false
+	}
+	return v, c.codec.Unmarshal(b, &v) == nil
+}
+
+// storedEnvelope returns the envelope of skey in the store unless it is expired, and
+// whether it is fresh.
+func (c *Cache[K, V]) storedEnvelope(ctx context.Context, skey string) (v V, env *Envelope, fresh bool) {
+	b, err := c.store.Get(ctx, skey)
+	if err != nil {
+		return v, nil, false
+	}
+	e, err := DecodeEnvelope(b)
+	if err != nil || !e.Expiry.After(time.Now()) || e.CodecID != codecID(c.codec) {
+		return v, nil, false
+	}
+	if err := c.codec.Unmarshal(e.Payload, &v); err != nil {
+		return v, nil, false
+	}
+	return v, &e, e.BestBefore.After(time.Now())
+}
+
+// Envelope wraps an encoded value with its freshness, so that all instances sharing a
+// store agree on when the value stops being fresh and when it expires.
+type Envelope struct {
+	Version    uint8 // set to EnvelopeVersion by EncodeEnvelope
+	Flags      uint8 // reserved, 0
+	BestBefore time.Time
+	Expiry     time.Time
+	CodecID    uint8 // see Codec
+	Payload    []byte
+}
+
+// EnvelopeVersion is the version of the envelope format written by EncodeEnvelope.
+const EnvelopeVersion = 1
+
+var (
+	// ErrInvalidEnvelope is returned when decoding data that is not an envelope.
+	ErrInvalidEnvelope = errors.New("stampede: invalid envelope")
+
+	// ErrEnvelopeVersion is returned when decoding an envelope of an unknown version,
+	// e.g. written by a newer release in a mixed-version fleet.
+	ErrEnvelopeVersion = errors.New("stampede: unsupported envelope version")
+)
+
+var envelopeMagic = [2]byte{'S', 'T'}
+
+const envelopeHeaderSize = 2 + 1 + 1 + 8 + 8 + 1
+
+// EncodeEnvelope encodes e in the binary envelope format:
+//
+//	magic       2 bytes "ST"
+//	version     1 byte, EnvelopeVersion
+//	flags       1 byte
+//	best before 8 bytes, unix nanoseconds big endian
+//	expiry      8 bytes, unix nanoseconds big endian
+//	codec id    1 byte
+//	payload     remaining bytes
+func EncodeEnvelope(e Envelope) []byte {
+	b := make([]byte, envelopeHeaderSize, envelopeHeaderSize+len(e.Payload))
+	copy(b[0:2], envelopeMagic[:])
+	b[2] = EnvelopeVersion
+	b[3] = e.Flags
+	binary.BigEndian.PutUint64(b[4:12], uint64(e.BestBefore.UnixNano()))
+	binary.BigEndian.PutUint64(b[12:20], uint64(e.Expiry.UnixNano()))
+	b[20] = e.CodecID
+	return append(b, e.Payload...)
+}
+
+// DecodeEnvelope decodes an envelope encoded with EncodeEnvelope. The payload of the
+// returned envelope aliases b.
+func DecodeEnvelope(b []byte) (Envelope, error) {
+	if len(b) < envelopeHeaderSize || b[0] != envelopeMagic[0] || b[1] != envelopeMagic[1] {
+		return Envelope{}, ErrInvalidEnvelope
+	}
+	if b[2] != EnvelopeVersion {
+		return Envelope{}, ErrEnvelopeVersion
+	}
+	return Envelope{
+		Version:    b[2],
+		Flags:      b[3],
+		BestBefore: time.Unix(0, int64(binary.BigEndian.Uint64(b[4:12]))),
+		Expiry:     time.Unix(0, int64(binary.BigEndian.Uint64(b[12:20]))),
+		CodecID:    b[20],
+		Payload:    b[envelopeHeaderSize:],
+	}, nil
+}
+
+// codecID returns the id of codec, or 0 if it has none.
+func codecID(codec Codec) uint8 {
+	if c, ok := codec.(interface{ ID() uint8 }); ok {
+		return c.ID()
+	}
+	return 0
+}
+
+// MemoryStore is an in-process Store, for tests and single instance deployments.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value  []byte
+	expiry time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: map[string]memoryEntry{}}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || (!e.expiry.IsZero() && e.expiry.Before(time.Now())) {
+		delete(s.entries, key)
+		return nil, ErrNotFound
+	}
+	return e.value, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := memoryEntry{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		e.expiry = time.Now().Add(ttl)
+	}
+	s.entries[key] = e
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,178 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+// countingCodec counts the values decoded by a codec.
+type countingCodec struct {
+	stampede.Codec
+	decodes int64
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v any) error {
+	atomic.AddInt64(&c.decodes, 1)
+	time.Sleep(20 * time.Millisecond)
+	return c.Codec.Unmarshal(data, v)
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	store := stampede.NewMemoryStore()
+	codec := &countingCodec{Codec: stampede.JSONCodec{}}
+
+	var origin int64
+	fetch := func() ([]string, error) {
+		atomic.AddInt64(&origin, 1)
+		return []string{"a", "b"}, nil
+	}
+
+	// the first instance fetches from the origin and writes through to the store
+	c1 := stampede.NewCacheKV[string, []string](8, time.Minute, time.Minute, stampede.WithStore(store, codec))
+	val, err := c1.Get(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, val)
+
+	// the second instance reads the store, decoding once for all concurrent callers
+	c2 := stampede.NewCacheKV[string, []string](8, time.Minute, time.Minute, stampede.WithStore(store, codec))
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			val, err := c2.Get(ctx, "k", fetch)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"a", "b"}, val)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&origin))
+	assert.Equal(t, int64(1), atomic.LoadInt64(&codec.decodes))
+}
+
+type tags []string
+
+func (t tags) Clone() tags {
+	return append(tags(nil), t...)
+}
+
+func TestCopyOnRead(t *testing.T) {
+	ctx := context.Background()
+	cache := stampede.NewCacheKV[string, tags](8, time.Minute, time.Minute, stampede.WithCopyOnRead())
+
+	val, err := cache.Get(ctx, "k", func() (tags, error) { return tags{"a"}, nil })
+	assert.NoError(t, err)
+	val[0] = "mutated"
+
+	val, err = cache.Get(ctx, "k", func() (tags, error) { return nil, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, tags{"a"}, val)
+}
+
+func TestStoreEnvelope(t *testing.T) {
+	ctx := context.Background()
+	store := stampede.NewMemoryStore()
+	opts := []stampede.Option{stampede.WithStore(store, stampede.JSONCodec{}), stampede.WithStoreEnvelope()}
+
+	var origin int64
+	fetch := func() (string, error) {
+		return "v" + strconv.FormatInt(atomic.AddInt64(&origin, 1), 10), nil
+	}
+
+	c1 := stampede.NewCacheKV[string, string](8, 50*time.Millisecond, time.Minute, opts...)
+	_, err := c1.Get(ctx, "k", fetch)
+	assert.NoError(t, err)
+
+	// the second instance uses the fresh store entry
+	c2 := stampede.NewCacheKV[string, string](8, 50*time.Millisecond, time.Minute, opts...)
+	val, err := c2.GetFresh(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", val)
+
+	// once stale by the envelope, not by the time of the store read, it is refreshed
+	time.Sleep(60 * time.Millisecond)
+	c3 := stampede.NewCacheKV[string, string](8, 50*time.Millisecond, time.Minute, opts...)
+	val, err = c3.GetFresh(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", val)
+
+	// stale store entries are served while the origin fails
+	time.Sleep(60 * time.Millisecond)
+	c4 := stampede.NewCacheKV[string, string](8, 50*time.Millisecond, time.Minute, opts...)
+	val, err = c4.GetFresh(ctx, "k", func() (string, error) { return "", errors.New("origin down") })
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", val)
+}
+
+func TestEnvelope(t *testing.T) {
+	env := stampede.Envelope{
+		BestBefore: time.Unix(0, 1000),
+		Expiry:     time.Unix(0, 2000),
+		CodecID:    stampede.JSONCodec{}.ID(),
+		Payload:    []byte(`"v"`),
+	}
+	b := stampede.EncodeEnvelope(env)
+	assert.Equal(t, "ST", string(b[:2]))
+
+	got, err := stampede.DecodeEnvelope(b)
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(stampede.EnvelopeVersion), got.Version)
+	assert.True(t, env.BestBefore.Equal(got.BestBefore))
+	assert.True(t, env.Expiry.Equal(got.Expiry))
+	assert.Equal(t, env.CodecID, got.CodecID)
+	assert.Equal(t, env.Payload, got.Payload)
+
+	_, err = stampede.DecodeEnvelope([]byte("short"))
+	assert.ErrorIs(t, err, stampede.ErrInvalidEnvelope)
+	_, err = stampede.DecodeEnvelope(append([]byte("XX"), b[2:]...))
+	assert.ErrorIs(t, err, stampede.ErrInvalidEnvelope)
+
+	b[2] = stampede.EnvelopeVersion + 1
+	_, err = stampede.DecodeEnvelope(b)
+	assert.ErrorIs(t, err, stampede.ErrEnvelopeVersion)
+}
+
+func TestEnvelopeCodecMismatch(t *testing.T) {
+	ctx := context.Background()
+	store := stampede.NewMemoryStore()
+
+	gobCache := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithStore(store, stampede.GobCodec{}), stampede.WithStoreEnvelope())
+	_, err := gobCache.Get(ctx, "k", func() (string, error) { return "gob", nil })
+	assert.NoError(t, err)
+
+	// entries of another codec are misses instead of decoding garbage
+	jsonCache := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithStore(store, stampede.JSONCodec{}), stampede.WithStoreEnvelope())
+	val, err := jsonCache.Get(ctx, "k", func() (string, error) { return "json", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "json", val)
+}
+
+func TestSchemaVersion(t *testing.T) {
+	ctx := context.Background()
+	store := stampede.NewMemoryStore()
+
+	v1 := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithStore(store, stampede.JSONCodec{}), stampede.WithSchemaVersion(1))
+	_, err := v1.Get(ctx, "k", func() (string, error) { return "v1", nil })
+	assert.NoError(t, err)
+
+	v2 := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithStore(store, stampede.JSONCodec{}), stampede.WithSchemaVersion(2))
+	val, err := v2.Get(ctx, "k", func() (string, error) { return "v2", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", val)
+
+	// both versions stay readable during a rolling deployment
+	v1 = stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithStore(store, stampede.JSONCodec{}), stampede.WithSchemaVersion(1))
+	val, err = v1.Get(ctx, "k", func() (string, error) { return "origin", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", val)
+}
@@ -0,0 +1,70 @@
+package stampede
+
+// Tagger is implemented by values that belong to groups of entries invalidated
+// together, e.g. all pages showing a product, see InvalidateTag.
+type Tagger interface {
+	Tags() []string
+}
+
+func tagsOf(v any) []string {
+	if t, ok := v.(Tagger); ok {
+		return t.Tags()
+	}
+	return nil
+}
+
+// tag adds ck to the index of tags. c.mu must be held.
+func (c *Cache[K, V]) tag(ck cacheKey[K], tags []string) {
+	for _, tag := range tags {
+		if c.tags == nil {
+			c.tags = make(map[string]map[cacheKey[K]]struct{})
+		}
+		keys := c.tags[tag]
+		if keys == nil {
+			keys = make(map[cacheKey[K]]struct{})
+			c.tags[tag] = keys
+		}
+		keys[ck] = struct{}{}
+	}
+}
+
+// untag removes ck from the index of tags. c.mu must be held.
+func (c *Cache[K, V]) untag(ck cacheKey[K], tags []string) {
+	for _, tag := range tags {
+		keys := c.tags[tag]
+		delete(keys, ck)
+		if len(keys) == 0 {
+			delete(c.tags, tag)
+		}
+	}
+}
+
+// InvalidateTag removes all entries tagged with tag from the cache and the store, and
+// returns how many were removed. It takes time proportional to the number of entries
+// with the tag.
+func (c *Cache[K, V]) InvalidateTag(tag string) int {
+	c.mu.Lock()
+	tagged := c.tags[tag]
+	keys := make([]K, 0, len(tagged))
+	cks := make([]cacheKey[K], 0, len(tagged))
+	for ck := range tagged {
+		key := ck.key
+		val, ok := c.values.Peek(ck)
+		if val, ok = c.stashed(ck, val, ok); ok && val.hasKey {
+			key = val.key
+		}
+		keys = append(keys, key)
+		cks = append(cks, ck)
+	}
+	c.invalidating = true
+	for _, ck := range cks {
+		c.values.Remove(ck)
+		c.unstash(ck)
+	}
+	c.invalidating = false
+	c.emitInvalidated()
+	c.mu.Unlock()
+
+	c.forget(keys, cks)
+	return len(cks)
+}
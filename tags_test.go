@@ -0,0 +1,58 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+type page struct {
+	body     string
+	products []string
+}
+
+func (p page) Tags() []string { return p.products }
+
+func TestInvalidateTag(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, page](8, time.Minute, time.Minute)
+
+	set := func(key string, products ...string) {
+		c.Set(ctx, key, func() (page, error) { return page{body: key, products: products}, nil })
+	}
+	set("/", "p1", "p2")
+	set("/p1", "p1")
+	set("/p2", "p2")
+
+	assert.Equal(t, 2, c.InvalidateTag("p1"))
+	assert.Equal(t, []string{"/p2"}, c.Keys())
+
+	// retagged entries leave their old tags
+	set("/p2", "p3")
+	assert.Equal(t, 0, c.InvalidateTag("p2"))
+	assert.Equal(t, 1, c.InvalidateTag("p3"))
+	assert.Equal(t, 0, c.Len())
+}
+
+type storedPage struct {
+	Body     string
+	Products []string
+}
+
+func (p storedPage) Tags() []string { return p.Products }
+
+func TestInvalidateTagStore(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, storedPage](8, time.Minute, time.Minute, stampede.WithStore(stampede.NewMemoryStore(), stampede.JSONCodec{}))
+
+	c.Get(ctx, "/p1", func() (storedPage, error) { return storedPage{Body: "old", Products: []string{"p1"}}, nil })
+	assert.Equal(t, 1, c.InvalidateTag("p1"))
+
+	// the store copy is gone too, so the next get reaches the origin
+	v, err := c.Get(ctx, "/p1", func() (storedPage, error) { return storedPage{Body: "new", Products: []string{"p1"}}, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "new", v.Body)
+}
@@ -0,0 +1,68 @@
+// Package tmplcache caches rendered html/template output with stampede, so server
+// rendered pages get micro-caching with stale-while-revalidate semantics.
+package tmplcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"io"
+	"time"
+
+	"github.com/dadav/stampede"
+)
+
+// Cache caches the output of the templates of a template set.
+type Cache struct {
+	tmpl  *template.Template
+	cache *stampede.Cache[uint64, []byte]
+}
+
+// New returns a Cache for the templates of tmpl.
+func New(tmpl *template.Template, size int, freshFor, ttl time.Duration, opts ...stampede.Option) *Cache {
+	return &Cache{
+		tmpl:  tmpl,
+		cache: stampede.NewCacheKV[uint64, []byte](size, freshFor, ttl, opts...),
+	}
+}
+
+// ExecuteTemplate writes the output of the named template applied to data to w. The
+// output is cached by template name and a hash of the json encoding of data. Data that
+// can't be encoded as json is rendered uncached; data depending on unexported fields
+// should use ExecuteTemplateKey instead.
+func (c *Cache) ExecuteTemplate(ctx context.Context, w io.Writer, name string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return c.tmpl.ExecuteTemplate(w, name, data)
+	}
+	return c.execute(ctx, w, stampede.BytesToHash(byData, []byte(name), b), name, data)
+}
+
+// ExecuteTemplateKey is like ExecuteTemplate, but caches the output by template name
+// and the given key instead of by data.
+func (c *Cache) ExecuteTemplateKey(ctx context.Context, w io.Writer, name, key string, data any) error {
+	return c.execute(ctx, w, stampede.BytesToHash(byKey, []byte(name), []byte(key)), name, data)
+}
+
+// byData and byKey prefix the keys of ExecuteTemplate and ExecuteTemplateKey, so a key
+// given to ExecuteTemplateKey never matches the json encoding of some data.
+var (
+	byData = []byte{0}
+	byKey  = []byte{1}
+)
+
+func (c *Cache) execute(ctx context.Context, w io.Writer, key uint64, name string, data any) error {
+	out, err := c.cache.Get(ctx, key, func() ([]byte, error) {
+		var buf bytes.Buffer
+		if err := c.tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
@@ -0,0 +1,44 @@
+package tmplcache_test
+
+import (
+	"bytes"
+	"context"
+	"html/template"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede/tmplcache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteTemplate(t *testing.T) {
+	var renders int
+	tmpl := template.Must(template.New("").Funcs(template.FuncMap{
+		"count": func() int { renders++; return renders },
+	}).Parse(`{{define "hello"}}hello {{.Name}} {{count}}{{end}}`))
+
+	c := tmplcache.New(tmpl, 16, time.Minute, time.Minute)
+	ctx := context.Background()
+
+	render := func(data any) string {
+		var buf bytes.Buffer
+		assert.NoError(t, c.ExecuteTemplate(ctx, &buf, "hello", data))
+		return buf.String()
+	}
+
+	assert.Equal(t, "hello a 1", render(map[string]string{"Name": "a"}))
+	assert.Equal(t, "hello a 1", render(map[string]string{"Name": "a"}))
+	assert.Equal(t, "hello b 2", render(map[string]string{"Name": "b"}))
+
+	var buf bytes.Buffer
+	assert.Error(t, c.ExecuteTemplate(ctx, &buf, "missing", nil))
+
+	buf.Reset()
+	assert.NoError(t, c.ExecuteTemplateKey(ctx, &buf, "hello", "user:a", struct{ Name string }{"c"}))
+	assert.Equal(t, "hello c 3", buf.String())
+
+	// keys never match the json encoding of data
+	buf.Reset()
+	assert.NoError(t, c.ExecuteTemplateKey(ctx, &buf, "hello", `{"Name":"a"}`, struct{ Name string }{"d"}))
+	assert.Equal(t, "hello d 4", buf.String())
+}
@@ -0,0 +1,60 @@
+package stampede
+
+import "context"
+
+// Tracer traces coalesced fetches. Every origin fetch gets one fetch span, and every
+// caller that waits on an in-flight fetch instead of running its own gets a wait span
+// pointing to the fetch span, so the shared origin call is attributable to all of
+// the requests it served. See the otelstampede package for OpenTelemetry.
+type Tracer interface {
+	// StartFetch starts the span of an origin fetch. The returned context is passed to
+	// the fetch function, the returned function ends the span.
+	StartFetch(ctx context.Context) (context.Context, func(err error))
+
+	// StartWait starts the span of a caller with context ctx waiting on the in-flight
+	// fetch with context fetch. The returned function ends the span.
+	StartWait(ctx, fetch context.Context) func(err error)
+}
+
+func (c *Cache[K, V]) startFetch(ctx context.Context, ck cacheKey[K]) (context.Context, func(error)) {
+	if c.tracer == nil {
+		return ctx, noEnd
+	}
+
+	ctx, end := c.tracer.StartFetch(c.withName(ctx))
+
+	c.fetchesMu.Lock()
+	if c.fetches == nil {
+		c.fetches = map[cacheKey[K]]context.Context{}
+	}
+	c.fetches[ck] = ctx
+	c.fetchesMu.Unlock()
+
+	return ctx, func(err error) {
+		c.fetchesMu.Lock()
+		delete(c.fetches, ck)
+		c.fetchesMu.Unlock()
+		end(err)
+	}
+}
+
+// startWait starts a wait span if a fetch for ck is in flight. Callers joining right
+// before the fetch started its span are not traced as waiters.
+func (c *Cache[K, V]) startWait(ctx context.Context, ck cacheKey[K]) func(error) {
+	if c.tracer == nil {
+		return noEnd
+	}
+
+	c.fetchesMu.Lock()
+	fetch, ok := c.fetches[ck]
+	c.fetchesMu.Unlock()
+
+	if !ok {
+		return noEnd
+	}
+	return c.tracer.StartWait(c.withName(ctx), fetch)
+}
+
+// noEnd ends no span. It is declared outside of the generic methods, whose closures are
+// allocated.
+func noEnd(error) {}
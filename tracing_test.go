@@ -0,0 +1,71 @@
+package stampede_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+type spanKey struct{}
+
+type testTracer struct {
+	fetches int64
+
+	mu    sync.Mutex
+	links []int64
+}
+
+func (t *testTracer) StartFetch(ctx context.Context) (context.Context, func(error)) {
+	id := atomic.AddInt64(&t.fetches, 1)
+	return context.WithValue(ctx, spanKey{}, id), func(error) {}
+}
+
+func (t *testTracer) StartWait(ctx, fetch context.Context) func(error) {
+	t.mu.Lock()
+	t.links = append(t.links, fetch.Value(spanKey{}).(int64))
+	t.mu.Unlock()
+	return func(error) {}
+}
+
+func TestTracer(t *testing.T) {
+	tracer := &testTracer{}
+	cache := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithTracer(tracer))
+
+	ctx := context.Background()
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := cache.GetContext(ctx, "a", func(ctx context.Context) (string, error) {
+			assert.Equal(t, int64(1), ctx.Value(spanKey{}))
+			close(started)
+			<-release
+			return "v", nil
+		})
+		assert.NoError(t, err)
+	}()
+	<-started
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.Get(ctx, "a", func() (string, error) { return "other", nil })
+			assert.NoError(t, err)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), atomic.LoadInt64(&tracer.fetches))
+	assert.Equal(t, []int64{1, 1, 1, 1, 1}, tracer.links)
+}
@@ -0,0 +1,153 @@
+package stampede
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ClassStats counts the gets of a key class by outcome.
+type ClassStats struct {
+	Hits    int64 // fresh values
+	Stale   int64 // stale values, served or waited for a refresh of
+	Misses  int64 // keys not cached
+	Expired int64 // keys cached, but expired
+}
+
+// Add returns the sum of s and o.
+func (s ClassStats) Add(o ClassStats) ClassStats {
+	s.Hits += o.Hits
+	s.Stale += o.Stale
+	s.Misses += o.Misses
+	s.Expired += o.Expired
+	return s
+}
+
+// Total returns the number of gets.
+func (s ClassStats) Total() int64 {
+	return s.Hits + s.Stale + s.Misses + s.Expired
+}
+
+// HitRate returns the share of gets served fresh.
+func (s ClassStats) HitRate() float64 {
+	if s.Total() == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Total())
+}
+
+type outcome int
+
+const (
+	outcomeHit outcome = iota
+	outcomeStale
+	outcomeMiss
+	outcomeExpired
+)
+
+// record counts the outcome of a get of key, if the cache classifies its keys.
+func (c *Cache[K, V]) record(key K, o outcome) {
+	if c.keyClass == nil {
+		return
+	}
+	class := c.keyClass(key)
+
+	c.classesMu.Lock()
+	defer c.classesMu.Unlock()
+	if c.classes == nil {
+		c.classes = make(map[string]*ClassStats)
+	}
+	cs := c.classes[class]
+	if cs == nil {
+		cs = &ClassStats{}
+		c.classes[class] = cs
+	}
+	switch o {
+	case outcomeHit:
+		cs.Hits++
+	case outcomeStale:
+		cs.Stale++
+	case outcomeMiss:
+		cs.Misses++
+	case outcomeExpired:
+		cs.Expired++
+	}
+}
+
+func (c *Cache[K, V]) classStats() map[string]ClassStats {
+	c.classesMu.Lock()
+	defer c.classesMu.Unlock()
+	if len(c.classes) == 0 {
+		return nil
+	}
+	stats := make(map[string]ClassStats, len(c.classes))
+	for class, cs := range c.classes {
+		stats[class] = *cs
+	}
+	return stats
+}
+
+// Advice is the kind of a Suggestion.
+type Advice int
+
+const (
+	// AdviceLongerFreshFor means values are mostly served stale or refreshed before use.
+	AdviceLongerFreshFor Advice = iota
+	// AdviceLongerTTL means values mostly expire before they are got again.
+	AdviceLongerTTL
+	// AdviceNoCache means keys are rarely got twice, caching them only costs memory.
+	AdviceNoCache
+)
+
+func (a Advice) String() string {
+	switch a {
+	case AdviceLongerFreshFor:
+		return "longer freshFor"
+	case AdviceLongerTTL:
+		return "longer ttl"
+	case AdviceNoCache:
+		return "don't cache"
+	}
+	return fmt.Sprintf("Advice(%d)", int(a))
+}
+
+// Suggestion is a tuning suggestion for a key class.
+type Suggestion struct {
+	Class  string
+	Advice Advice
+	Reason string
+}
+
+// MinTuningSamples is the number of gets of a key class below which Tuning makes no
+// suggestions for it.
+const MinTuningSamples = 100
+
+// Tuning suggests policy changes from the hit rates per key class, sorted by class.
+func (s Stats) Tuning() []Suggestion {
+	classes := make([]string, 0, len(s.Classes))
+	for class := range s.Classes {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+
+	var suggestions []Suggestion
+	for _, class := range classes {
+		cs := s.Classes[class]
+		total := cs.Total()
+		if total < MinTuningSamples {
+			continue
+		}
+
+		switch {
+		case cs.Misses*10 > total*9:
+			suggestions = append(suggestions, Suggestion{class, AdviceNoCache,
+				fmt.Sprintf("%d of %d gets were misses of keys never cached", cs.Misses, total)})
+		case cs.Expired*2 > total:
+			suggestions = append(suggestions, Suggestion{class, AdviceLongerTTL,
+				fmt.Sprintf("%d of %d gets found the value expired", cs.Expired, total)})
+		case cs.Stale*2 > total:
+			suggestions = append(suggestions, Suggestion{class, AdviceLongerFreshFor,
+				fmt.Sprintf("%d of %d gets found the value stale", cs.Stale, total)})
+		}
+	}
+	return suggestions
+}
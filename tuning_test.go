@@ -0,0 +1,37 @@
+package stampede_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTuning(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](1000, time.Minute, time.Minute, stampede.WithKeyClass(func(key any) string {
+		class, _, _ := strings.Cut(key.(string), ":")
+		return class
+	}))
+	fetch := func() (int, error) { return 1, nil }
+
+	for i := 0; i < 200; i++ {
+		c.Get(ctx, "user:1", fetch)
+		c.Get(ctx, "search:"+strconv.Itoa(i), fetch)
+	}
+
+	stats := c.Stats()
+	assert.Equal(t, stampede.ClassStats{Hits: 199, Misses: 1}, stats.Classes["user"])
+	assert.Equal(t, stampede.ClassStats{Misses: 200}, stats.Classes["search"])
+	assert.InDelta(t, 0.995, stats.Classes["user"].HitRate(), 0.001)
+
+	suggestions := stats.Tuning()
+	if assert.Len(t, suggestions, 1) {
+		assert.Equal(t, "search", suggestions[0].Class)
+		assert.Equal(t, stampede.AdviceNoCache, suggestions[0].Advice)
+	}
+}
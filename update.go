@@ -0,0 +1,78 @@
+package stampede
+
+import (
+	"context"
+	"sync"
+)
+
+type entryLock struct {
+	sync.Mutex
+	refs int
+}
+
+// Update replaces the cached value of key with fn applied to it, and makes the entry
+// fresh again, e.g. to increment a counter or append to a cached list. Updates of the
+// same key are serialized, without blocking other keys. fn must not modify a value that
+// callers may still be reading, but return a modified copy. Only the in-memory entry is
+// updated, not the store, and it keeps the tags, version and source of the entry, unless
+// the new value carries tags of its own. Update reports whether key was cached and not
+// expired.
+func (c *Cache[K, V]) Update(key K, fn func(v V) V) bool {
+	key = c.normalizeKey(key)
+	ck := c.cacheKey(key)
+
+	unlock := c.lockEntry(ck)
+	defer unlock()
+
+	c.mu.RLock()
+	old, ok := c.values.Peek(ck)
+	old, ok = c.stashed(ck, old, ok)
+	c.mu.RUnlock()
+	if !ok || old.IsExpired() {
+		return false
+	}
+
+	v := fn(old.v)
+	bestBefore, expiry := c.expiry(context.Background(), v)
+	entry := c.entry(key, ck, v, bestBefore, expiry)
+	if old.ext != nil {
+		e := entry.extend()
+		if e.tags == nil {
+			e.tags = old.ext.tags
+		}
+		e.version = old.ext.version
+	}
+	entry.source = old.source
+	entry.setCost(old.cost())
+
+	c.mu.Lock()
+	c.add(ck, entry)
+	c.notify(ck, v)
+	c.mu.Unlock()
+	return true
+}
+
+// lockEntry locks the entry of ck for Update.
+func (c *Cache[K, V]) lockEntry(ck cacheKey[K]) (unlock func()) {
+	c.entryLocksMu.Lock()
+	l := c.entryLocks[ck]
+	if l == nil {
+		if c.entryLocks == nil {
+			c.entryLocks = make(map[cacheKey[K]]*entryLock)
+		}
+		l = &entryLock{}
+		c.entryLocks[ck] = l
+	}
+	l.refs++
+	c.entryLocksMu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		c.entryLocksMu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(c.entryLocks, ck)
+		}
+		c.entryLocksMu.Unlock()
+	}
+}
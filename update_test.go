@@ -0,0 +1,65 @@
+package stampede_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute)
+
+	assert.False(t, c.Update("counter", func(v int) int { return v + 1 }))
+
+	c.Get(ctx, "counter", func() (int, error) { return 0, nil })
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, c.Update("counter", func(v int) int { return v + 1 }))
+		}()
+	}
+	wg.Wait()
+
+	val, ok := c.Peek("counter")
+	assert.True(t, ok)
+	assert.Equal(t, 100, val)
+}
+
+func TestUpdateFreshness(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Millisecond, time.Minute)
+	c.Get(ctx, "k", func() (int, error) { return 1, nil })
+	time.Sleep(5 * time.Millisecond)
+
+	c.SetTTL(time.Minute, time.Minute)
+	c.Update("k", func(v int) int { return v + 1 })
+	val, err := c.GetFresh(ctx, "k", func() (int, error) { return 0, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 2, val)
+
+	// expired entries are missing
+	c.SetTTL(time.Millisecond, time.Millisecond)
+	c.Set(ctx, "k", func() (int, error) { return 1, nil })
+	time.Sleep(5 * time.Millisecond)
+	assert.False(t, c.Update("k", func(v int) int { return v + 1 }))
+}
+
+func TestUpdateTags(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute)
+	c.GetContext(ctx, "k", stampede.ValueFunc(func(context.Context) (stampede.Value[int], error) {
+		return stampede.Value[int]{V: 1, Tags: []string{"product:1"}}, nil
+	}))
+
+	assert.True(t, c.Update("k", func(v int) int { return v + 1 }))
+	assert.Equal(t, 1, c.InvalidateTag("product:1"))
+	_, ok := c.Peek("k")
+	assert.False(t, ok)
+}
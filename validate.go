@@ -0,0 +1,50 @@
+package stampede
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidConfig is returned by Validate.
+var ErrInvalidConfig = errors.New("stampede: invalid config")
+
+// Validate checks the arguments of NewCacheKV, which would otherwise be normalized.
+func Validate(size int, freshFor, ttl time.Duration, opts ...Option) error {
+	o := newOptions(opts)
+	if o.lifetime != nil {
+		freshFor, ttl = o.lifetime.Fresh, o.lifetime.TTL()
+		if o.lifetime.Grace < 0 {
+			return fmt.Errorf("%w: negative grace period %v", ErrInvalidConfig, o.lifetime.Grace)
+		}
+	}
+
+	switch {
+	case size < 1:
+		return fmt.Errorf("%w: size %d, must be at least 1", ErrInvalidConfig, size)
+	case freshFor < 0:
+		return fmt.Errorf("%w: negative freshFor %v", ErrInvalidConfig, freshFor)
+	case ttl < freshFor:
+		return fmt.Errorf("%w: ttl %v shorter than freshFor %v", ErrInvalidConfig, ttl, freshFor)
+	case o.maxTTL > 0 && o.minTTL > o.maxTTL:
+		return fmt.Errorf("%w: min ttl %v above max ttl %v", ErrInvalidConfig, o.minTTL, o.maxTTL)
+	case o.hardLimit > 0 && o.softLimit > o.hardLimit:
+		return fmt.Errorf("%w: soft limit %d above hard limit %d", ErrInvalidConfig, o.softLimit, o.hardLimit)
+	case o.store != nil && o.codec == nil:
+		return fmt.Errorf("%w: store without codec", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// MustNewCache is like NewCache, but panics if Validate fails.
+func MustNewCache(size int, freshFor, ttl time.Duration, opts ...Option) *Cache[any, any] {
+	return MustNewCacheKV[any, any](size, freshFor, ttl, opts...)
+}
+
+// MustNewCacheKV is like NewCacheKV, but panics if Validate fails.
+func MustNewCacheKV[K comparable, V any](size int, freshFor, ttl time.Duration, opts ...Option) *Cache[K, V] {
+	if err := Validate(size, freshFor, ttl, opts...); err != nil {
+		panic(err)
+	}
+	return NewCacheKV[K, V](size, freshFor, ttl, opts...)
+}
@@ -0,0 +1,32 @@
+package stampede_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, stampede.Validate(8, time.Second, time.Minute))
+	assert.NoError(t, stampede.Validate(8, 0, 0))
+
+	for _, err := range []error{
+		stampede.Validate(0, time.Second, time.Minute),
+		stampede.Validate(8, -time.Second, time.Minute),
+		stampede.Validate(8, time.Minute, time.Second),
+		stampede.Validate(8, 0, 0, stampede.WithLifetime(stampede.Lifetime{Fresh: time.Second, Grace: -time.Minute})),
+		stampede.Validate(8, 0, 0, stampede.WithTTLBounds(time.Minute, time.Second)),
+		stampede.Validate(8, 0, 0, stampede.WithWatermarks(10, 5)),
+	} {
+		assert.ErrorIs(t, err, stampede.ErrInvalidConfig)
+	}
+
+	assert.Panics(t, func() { stampede.MustNewCache(8, time.Minute, time.Second) })
+	assert.NotPanics(t, func() { stampede.MustNewCacheKV[string, int](8, time.Second, time.Minute) })
+
+	// NewCacheKV normalizes instead
+	c := stampede.NewCacheKV[string, int](0, -time.Second, -time.Second)
+	assert.Equal(t, stampede.Lifetime{}, c.Lifetime())
+}
@@ -0,0 +1,140 @@
+package stampede
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Value is a fetched value with the metadata of its cache entry, returned by the
+// function given to ValueFunc. Zero fields keep the defaults of the cache and of the
+// interfaces V implements, like TTLer and Sizer.
+type Value[V any] struct {
+	V V
+
+	// FreshFor and TTL override the lifetime of the entry. Without TTL, the entry keeps
+	// the grace period of the cache.
+	FreshFor time.Duration
+	TTL      time.Duration
+
+	Tags []string // see Tagger
+	Size int64    // see Sizer
+
+	// NoStore returns V to the callers waiting for it without caching it, neither in
+	// memory nor in the store.
+	NoStore bool
+}
+
+// ValueFunc adapts a function returning a Value with metadata to a FetchFunc.
+func ValueFunc[V any](fn func(ctx context.Context) (Value[V], error)) FetchFunc[V] {
+	return func(ctx context.Context) (V, error) {
+		v, err := fn(ctx)
+		if m := metaFrom(ctx); m != nil && err == nil {
+			m.FreshFor, m.TTL = v.FreshFor, v.TTL
+			m.Tags, m.Size = v.Tags, v.Size
+			m.NoStore = m.NoStore || v.NoStore
+		}
+		return v.V, err
+	}
+}
+
+// DeltaFunc adapts a function computing a value from the previous value of the key to a
+// FetchFunc, so refreshes of large aggregates can apply incremental updates instead of
+// rebuilding them. previous is the cached value, even a stale or expired one, and ok is
+// false if there is none.
+func DeltaFunc[V any](fn func(ctx context.Context, previous V, ok bool) (V, error)) FetchFunc[V] {
+	return func(ctx context.Context) (V, error) {
+		var previous V
+		m := metaFrom(ctx)
+		ok := m != nil && m.hasPrevious
+		if ok {
+			previous, ok = m.previous.(V)
+		}
+		return fn(ctx, previous, ok)
+	}
+}
+
+// NoStore marks the value being fetched with ctx, the context passed to a FetchFunc,
+// as not to be cached, e.g. a partial result during origin trouble. The value is still
+// returned to the callers waiting for it. It does nothing for other contexts.
+func NoStore(ctx context.Context) {
+	if m := metaFrom(ctx); m != nil {
+		m.NoStore = true
+	}
+}
+
+// Unchanged is returned by a FetchFunc, instead of a value, when the value didn't change
+// since it was last fetched, e.g. after a conditional request with an ETag. The cached
+// entry is kept and fresh again, without being replaced or sending EventSet. If there
+// is no entry, Unchanged is returned to the callers.
+var Unchanged = errors.New("stampede: unchanged")
+
+// unchanged renews the freshness of the entry of ck, see Unchanged.
+func (c *Cache[K, V]) unchanged(ctx context.Context, ck cacheKey[K]) (V, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	val, ok := c.values.Peek(ck)
+	if val, ok = c.stashed(ck, val, ok); !ok {
+		return val.v, Unchanged
+	}
+	bestBefore, expiry := c.expiry(ctx, val.v)
+	val.bestBefore, val.expiry = bestBefore.UnixNano(), expiry.UnixNano()
+	if p := c.pinned[ck]; p != nil {
+		*p = val
+	} else {
+		c.values.Add(ck, val)
+	}
+	return val.v, nil
+}
+
+// entryMeta receives the metadata of a Value while it is fetched.
+type entryMeta struct {
+	FreshFor time.Duration
+	TTL      time.Duration
+	Tags     []string
+	Size     int64
+	NoStore  bool
+
+	previous    any // the cached value, see DeltaFunc
+	hasPrevious bool
+
+	source Source
+}
+
+type metaKey struct{}
+
+func withMeta(ctx context.Context) (context.Context, *entryMeta) {
+	m := &entryMeta{}
+	return context.WithValue(ctx, metaKey{}, m), m
+}
+
+// withPrevious records the cached value of ck for DeltaFunc.
+func (c *Cache[K, V]) withPrevious(m *entryMeta, ck cacheKey[K]) {
+	c.mu.RLock()
+	val, ok := c.values.Peek(ck)
+	val, ok = c.stashed(ck, val, ok)
+	c.mu.RUnlock()
+	if ok {
+		m.previous, m.hasPrevious = val.v, true
+	}
+}
+
+func metaFrom(ctx context.Context) *entryMeta {
+	m, _ := ctx.Value(metaKey{}).(*entryMeta)
+	return m
+}
+
+func (m *entryMeta) noStore() bool {
+	return m != nil && m.NoStore
+}
+
+// apply overrides the size and tags of v with m.
+func (c *Cache[K, V]) apply(v *value[K, V], m *entryMeta) {
+	if m.Size > 0 {
+		v.size = m.Size
+	}
+	if m.Tags != nil {
+		v.setTags(c.internTags(m.Tags))
+	}
+}
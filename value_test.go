@@ -0,0 +1,116 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueFunc(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithWatermarks(100, 100))
+
+	val, err := c.GetContext(ctx, "short", stampede.ValueFunc(func(ctx context.Context) (stampede.Value[string], error) {
+		return stampede.Value[string]{V: "a", FreshFor: time.Millisecond, TTL: time.Millisecond, Tags: []string{"t"}, Size: 42}, nil
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "a", val)
+	assert.Equal(t, int64(42), c.Size())
+
+	time.Sleep(5 * time.Millisecond)
+	val, err = c.GetContext(ctx, "short", func(ctx context.Context) (string, error) { return "b", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "b", val)
+
+	// values not to store are returned, but not cached
+	store := stampede.NewMemoryStore()
+	c = stampede.NewCacheKV[string, string](8, time.Minute, time.Minute, stampede.WithStore(store, stampede.JSONCodec{}))
+	val, err = c.GetContext(ctx, "degraded", stampede.ValueFunc(func(ctx context.Context) (stampede.Value[string], error) {
+		return stampede.Value[string]{V: "partial", NoStore: true}, nil
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "partial", val)
+	assert.Equal(t, 0, c.Len())
+	_, err = store.Get(ctx, "degraded")
+	assert.ErrorIs(t, err, stampede.ErrNotFound)
+}
+
+func TestNoStore(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, string](8, time.Minute, time.Minute)
+
+	calls := 0
+	fetch := func(ctx context.Context) (string, error) {
+		calls++
+		if calls == 1 {
+			stampede.NoStore(ctx)
+			return "degraded", nil
+		}
+		return "full", nil
+	}
+
+	val, err := c.GetContext(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "degraded", val)
+
+	val, err = c.GetContext(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "full", val)
+
+	val, err = c.GetContext(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, "full", val)
+	assert.Equal(t, 2, calls)
+
+	// outside of fetches it does nothing
+	stampede.NoStore(ctx)
+}
+
+func TestUnchanged(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, string](8, time.Millisecond, time.Millisecond)
+	events := c.Events()
+
+	val, err := c.Get(ctx, "k", func() (string, error) { return "a", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "a", val)
+	<-events
+
+	time.Sleep(5 * time.Millisecond)
+	c.SetTTL(time.Minute, time.Minute)
+	val, err = c.Get(ctx, "k", func() (string, error) { return "", stampede.Unchanged })
+	assert.NoError(t, err)
+	assert.Equal(t, "a", val)
+	assert.Len(t, events, 0)
+
+	// the entry is fresh again
+	val, err = c.GetFresh(ctx, "k", func() (string, error) { return "b", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, "a", val)
+
+	_, err = c.Get(ctx, "missing", func() (string, error) { return "", stampede.Unchanged })
+	assert.Equal(t, stampede.Unchanged, err)
+}
+
+func TestDeltaFunc(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, []int](8, time.Minute, time.Minute)
+	appendOne := stampede.DeltaFunc(func(ctx context.Context, previous []int, ok bool) ([]int, error) {
+		if !ok {
+			return []int{0}, nil
+		}
+		return append(append([]int(nil), previous...), len(previous)), nil
+	})
+
+	val, _, err := c.SetContext(ctx, "k", appendOne)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0}, val)
+
+	c.SetContext(ctx, "k", appendOne)
+	val, _, err = c.SetContext(ctx, "k", appendOne)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, val)
+}
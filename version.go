@@ -0,0 +1,23 @@
+package stampede
+
+import "context"
+
+// version returns the current version of key, see WithVersionCheck. A version fetched
+// before the value may be older than the value, but never newer, so a write racing the
+// fetch only causes another fetch.
+func (c *Cache[K, V]) version(ctx context.Context, key K) string {
+	if c.versionFn == nil {
+		return ""
+	}
+	v, _ := c.versionFn(ctx, key)
+	return v
+}
+
+// verify reports whether val, the entry of key, has the current version of key.
+func (c *Cache[K, V]) verify(ctx context.Context, key K, val value[K, V]) bool {
+	if c.versionFn == nil {
+		return true
+	}
+	v, err := c.versionFn(ctx, key)
+	return err == nil && v == val.version()
+}
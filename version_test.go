@@ -0,0 +1,39 @@
+package stampede_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersionCheck(t *testing.T) {
+	ctx := context.Background()
+	versions := map[any]int{}
+	check := func(ctx context.Context, key any) (string, error) {
+		return strconv.Itoa(versions[key]), nil
+	}
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithVersionCheck(check))
+
+	var calls int
+	fetch := func() (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	val, _ := c.Get(ctx, "k", fetch)
+	assert.Equal(t, 1, val)
+	val, _ = c.Get(ctx, "k", fetch)
+	assert.Equal(t, 1, val)
+
+	versions["k"]++
+	val, err := c.Get(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, val)
+	val, _ = c.GetFreshWithin(ctx, "k", time.Second, fetch)
+	assert.Equal(t, 2, val)
+	assert.Equal(t, 2, calls)
+}
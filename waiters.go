@@ -0,0 +1,39 @@
+package stampede
+
+import "errors"
+
+// ErrTooManyWaiters is returned when a key already has the maximum number of callers
+// waiting on its fetch, see WithMaxWaiters.
+var ErrTooManyWaiters = errors.New("stampede: too many waiters")
+
+// addWaiter registers a caller of do for ck, and reports false if the key is at the
+// cap. The first caller runs the fetch and doesn't count as a waiter.
+func (c *Cache[K, V]) addWaiter(ck cacheKey[K]) bool {
+	c.waitersMu.Lock()
+	defer c.waitersMu.Unlock()
+	if c.waiters[ck] > c.maxWaiters {
+		return false
+	}
+	if c.waiters == nil {
+		c.waiters = make(map[cacheKey[K]]int)
+	}
+	c.waiters[ck]++
+	return true
+}
+
+func (c *Cache[K, V]) removeWaiter(ck cacheKey[K]) {
+	c.waitersMu.Lock()
+	defer c.waitersMu.Unlock()
+	if c.waiters[ck]--; c.waiters[ck] == 0 {
+		delete(c.waiters, ck)
+	}
+}
+
+// rejectWaiter returns the stale value of ck for a caller over the cap, if any.
+func (c *Cache[K, V]) rejectWaiter(ck cacheKey[K]) (V, bool, error) {
+	if val, ok := c.lookup(ck); ok && !val.IsExpired() {
+		return val.Value(), false, nil
+	}
+	var zero V
+	return zero, false, c.named(ErrTooManyWaiters)
+}
@@ -0,0 +1,53 @@
+package stampede_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxWaiters(t *testing.T) {
+	ctx := context.Background()
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute, stampede.WithMaxWaiters(1))
+
+	started, release := make(chan struct{}), make(chan struct{})
+	fetch := func() (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.Get(ctx, "k", fetch)
+	}()
+	<-started
+
+	// one caller may wait on the fetch, the others are rejected right away
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Get(ctx, "k", fetch)
+			errs <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, stampede.ErrTooManyWaiters, <-errs)
+	}
+
+	close(release)
+	wg.Wait()
+	assert.NoError(t, <-errs)
+
+	val, err := c.Get(ctx, "k", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, val)
+}
@@ -0,0 +1,98 @@
+package stampede
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// WarmGroup primes the keys of a cache with bounded concurrency, similar to an
+// errgroup.Group. By default the first error cancels the context of the group and is
+// returned by Wait; with CollectAll every key is tried and all errors are returned.
+type WarmGroup[K comparable, V any] struct {
+	cache  *Cache[K, V]
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	sem        chan struct{}
+	collectAll bool
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	errs []error
+}
+
+// WarmGroup returns a WarmGroup for the cache, deriving its context from ctx.
+func (c *Cache[K, V]) WarmGroup(ctx context.Context) *WarmGroup[K, V] {
+	ctx, cancel := context.WithCancel(ctx)
+	return &WarmGroup[K, V]{cache: c, ctx: ctx, cancel: cancel}
+}
+
+// SetLimit limits the number of concurrent fetches to n. A negative n removes the
+// limit. It must not be called after Go.
+func (g *WarmGroup[K, V]) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	g.sem = make(chan struct{}, n)
+}
+
+// CollectAll makes the group try every key, and return all errors from Wait. It must
+// not be called after Go.
+func (g *WarmGroup[K, V]) CollectAll() {
+	g.collectAll = true
+}
+
+// Go primes key with fn, unless it is fresh already. It blocks while the concurrency
+// limit is reached.
+func (g *WarmGroup[K, V]) Go(key K, fn FetchFunc[V]) {
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		case <-g.ctx.Done():
+			g.fail(key, g.ctx.Err())
+			return
+		}
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.sem != nil {
+			defer func() { <-g.sem }()
+		}
+
+		if err := g.ctx.Err(); err != nil {
+			g.fail(key, err)
+			return
+		}
+		if _, err := g.cache.GetFreshContext(g.ctx, key, fn); err != nil {
+			g.fail(key, err)
+		}
+	}()
+}
+
+func (g *WarmGroup[K, V]) fail(key K, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.collectAll && len(g.errs) > 0 {
+		return
+	}
+	g.errs = append(g.errs, g.cache.errorf("warm %v: %w", key, err))
+	if !g.collectAll {
+		g.cancel()
+	}
+}
+
+// Wait waits for all fetches to return, and returns the first error, or all errors
+// joined with CollectAll.
+func (g *WarmGroup[K, V]) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return errors.Join(g.errs...)
+}
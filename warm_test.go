@@ -0,0 +1,68 @@
+package stampede_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmGroup(t *testing.T) {
+	cache := stampede.NewCacheKV[int, int](64, time.Minute, time.Minute)
+
+	var running, maxRunning int64
+	fetch := func(n int) stampede.FetchFunc[int] {
+		return func(ctx context.Context) (int, error) {
+			cur := atomic.AddInt64(&running, 1)
+			defer atomic.AddInt64(&running, -1)
+			for {
+				prev := atomic.LoadInt64(&maxRunning)
+				if cur <= prev || atomic.CompareAndSwapInt64(&maxRunning, prev, cur) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return n, nil
+		}
+	}
+
+	g := cache.WarmGroup(context.Background())
+	g.SetLimit(3)
+	for i := 0; i < 10; i++ {
+		g.Go(i, fetch(i))
+	}
+	assert.NoError(t, g.Wait())
+	assert.Equal(t, 10, cache.Len())
+	assert.LessOrEqual(t, atomic.LoadInt64(&maxRunning), int64(3))
+}
+
+func TestWarmGroupErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	failing := func(ctx context.Context) (int, error) { return 0, errBoom }
+
+	cache := stampede.NewCacheKV[int, int](64, time.Minute, time.Minute)
+	g := cache.WarmGroup(context.Background())
+	g.SetLimit(1)
+	g.Go(1, failing)
+	g.Go(2, failing)
+	g.Go(3, func(ctx context.Context) (int, error) { return 3, nil })
+	err := g.Wait()
+	assert.ErrorIs(t, err, errBoom)
+	assert.Equal(t, "stampede: warm 1: boom", err.Error())
+
+	g = cache.WarmGroup(context.Background())
+	g.CollectAll()
+	g.Go(4, failing)
+	g.Go(5, failing)
+	g.Go(6, func(ctx context.Context) (int, error) { return 6, nil })
+	err = g.Wait()
+	assert.ErrorIs(t, err, errBoom)
+	assert.Contains(t, err.Error(), "warm 4")
+	assert.Contains(t, err.Error(), "warm 5")
+	_, ok := cache.Peek(6)
+	assert.True(t, ok)
+}
@@ -0,0 +1,80 @@
+package stampede
+
+import "context"
+
+// Watch returns a channel receiving the value of key every time it is fetched, starting
+// with the cached value if there is one, e.g. to react to changes of configuration
+// without polling Get. Receivers lagging behind only get the latest value. The channel
+// is closed once ctx is done or the cache is closed.
+func (c *Cache[K, V]) Watch(ctx context.Context, key K) <-chan V {
+	ch := make(chan V, 1)
+	ck := c.cacheKey(c.normalizeKey(key))
+
+	// register under closeMu, but release it before goBackground takes it again, as a
+	// waiting Close would block the second RLock
+	if !c.addWatcher(ck, ch) {
+		close(ch)
+		return ch
+	}
+
+	c.goBackground(func() {
+		select {
+		case <-ctx.Done():
+		case <-c.ctx.Done():
+		}
+		c.unwatch(ck, ch)
+	})
+	return ch
+}
+
+// addWatcher registers ch as watcher of ck, with the cached value if there is one, and
+// reports whether the cache is still open.
+func (c *Cache[K, V]) addWatcher(ck cacheKey[K], ch chan V) bool {
+	c.closeMu.RLock()
+	defer c.closeMu.RUnlock()
+	if c.closed {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	val, ok := c.values.Peek(ck)
+	if val, ok := c.stashed(ck, val, ok); ok {
+		ch <- c.read(val.v)
+	}
+	if c.watchers == nil {
+		c.watchers = make(map[cacheKey[K]][]chan V)
+	}
+	c.watchers[ck] = append(c.watchers[ck], ch)
+	return true
+}
+
+func (c *Cache[K, V]) unwatch(ck cacheKey[K], ch chan V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	watchers := c.watchers[ck]
+	for i, w := range watchers {
+		if w == ch {
+			watchers = append(watchers[:i], watchers[i+1:]...)
+			break
+		}
+	}
+	if len(watchers) == 0 {
+		delete(c.watchers, ck)
+	} else {
+		c.watchers[ck] = watchers
+	}
+	close(ch)
+}
+
+// notify sends v, just fetched for ck, to its watchers, replacing values they haven't
+// received yet. c.mu must be held.
+func (c *Cache[K, V]) notify(ck cacheKey[K], v V) {
+	for _, ch := range c.watchers[ck] {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- c.read(v)
+	}
+}
@@ -0,0 +1,57 @@
+package stampede_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dadav/stampede"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute)
+	c.Get(ctx, "flags", func() (int, error) { return 1, nil })
+
+	ch := c.Watch(ctx, "flags")
+	assert.Equal(t, 1, <-ch)
+
+	c.Set(ctx, "flags", func() (int, error) { return 2, nil })
+	c.Set(ctx, "flags", func() (int, error) { return 3, nil })
+	c.Set(ctx, "other", func() (int, error) { return 4, nil })
+	// lagging receivers only get the latest value
+	assert.Equal(t, 3, <-ch)
+
+	cancel()
+	_, ok := <-ch
+	assert.False(t, ok)
+
+	ch = c.Watch(context.Background(), "other")
+	assert.Equal(t, 4, <-ch)
+	c.Close()
+	_, ok = <-ch
+	assert.False(t, ok)
+}
+
+func TestWatchClose(t *testing.T) {
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		c := stampede.NewCacheKV[string, int](8, time.Minute, time.Minute)
+		watched := make(chan (<-chan int))
+		go func() { watched <- c.Watch(ctx, "flags") }()
+		closed := make(chan error)
+		go func() { closed <- c.Close() }()
+
+		select {
+		case ch := <-watched:
+			assert.NoError(t, <-closed)
+			assert.Eventually(t, func() bool {
+				_, ok := <-ch
+				return !ok
+			}, time.Second, time.Millisecond)
+		case <-time.After(time.Second):
+			t.Fatal("Watch and Close deadlocked")
+		}
+	}
+}